@@ -0,0 +1,190 @@
+// aggregator.go: Error deduplication and occurrence counting for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxExpiredAggregates bounds the number of groups whose elapsed windows an Aggregator
+// keeps until Flush.
+const maxExpiredAggregates = 1024
+
+// AggregateEntry reports the occurrences of one group of errors in an Aggregator.
+type AggregateEntry struct {
+	Fingerprint string
+	Code        ErrorCode
+	Message     string
+	Count       int
+	FirstSeen   time.Time
+	LastSeen    time.Time
+}
+
+// Aggregator deduplicates errors by Fingerprint over a time window and counts their
+// occurrences, so that a flood of identical errors can be logged once with a count instead
+// of once per occurrence. It is safe for concurrent use.
+type Aggregator struct {
+	mu      sync.Mutex
+	window  time.Duration
+	groups  map[string]*AggregateEntry
+	expired map[string]*AggregateEntry // counts of elapsed windows, kept until Flush
+}
+
+// NewAggregator returns an Aggregator whose groups last for window after their first
+// occurrence. Once the window has elapsed, the next occurrence starts a new group; the
+// counts of the elapsed window are kept and reported by Snapshot and Flush, so no
+// occurrence is lost between two flushes. To bound memory when Flush is not called, the
+// elapsed windows of at most 1024 groups are kept: the counts of further groups are added
+// to a single entry with an empty Fingerprint and Code. Windows are measured with the
+// clock set with SetClock.
+//
+// Example:
+//
+//	agg := errors.NewAggregator(time.Minute)
+//	if agg.Add(err) {
+//		log.Error("request failed", "error", err) // first occurrence in the window
+//	}
+//
+//	// Periodically:
+//	for _, entry := range agg.Flush() {
+//		log.Warn("error summary", "code", entry.Code, "count", entry.Count)
+//	}
+func NewAggregator(window time.Duration) *Aggregator {
+	return &Aggregator{
+		window:  window,
+		groups:  make(map[string]*AggregateEntry),
+		expired: make(map[string]*AggregateEntry),
+	}
+}
+
+// Add records an occurrence of err and reports whether it is the first of its group in
+// the current window, i.e. whether it should be logged. Errors are grouped by the
+// Fingerprint of the first *Error in the chain; errors without one are grouped by their
+// message with digit sequences masked. Add ignores nil errors and returns false.
+func (a *Aggregator) Add(err error) bool {
+	if err == nil {
+		return false
+	}
+	key, code, message := aggregateKey(err)
+	now := clockNow()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry, ok := a.groups[key]
+	if ok && now.Sub(entry.FirstSeen) < a.window {
+		entry.Count++
+		entry.LastSeen = now
+		return false
+	}
+	if ok {
+		a.retire(entry)
+	}
+	a.groups[key] = &AggregateEntry{
+		Fingerprint: key,
+		Code:        code,
+		Message:     message,
+		Count:       1,
+		FirstSeen:   now,
+		LastSeen:    now,
+	}
+	return true
+}
+
+// Snapshot returns the occurrences recorded since the last Flush, one entry per group and
+// most frequent first, without resetting the Aggregator. The entry of a group whose window
+// has elapsed one or more times adds up the counts of every window.
+func (a *Aggregator) Snapshot() []AggregateEntry {
+	now := clockNow()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key, entry := range a.groups {
+		if now.Sub(entry.FirstSeen) >= a.window {
+			a.retire(entry)
+			delete(a.groups, key)
+		}
+	}
+	return mergeAggregates(a.expired, a.groups)
+}
+
+// Flush returns the occurrences recorded since the last Flush like Snapshot, and resets
+// the Aggregator.
+func (a *Aggregator) Flush() []AggregateEntry {
+	a.mu.Lock()
+	groups, expired := a.groups, a.expired
+	a.groups = make(map[string]*AggregateEntry)
+	a.expired = make(map[string]*AggregateEntry)
+	a.mu.Unlock()
+
+	return mergeAggregates(expired, groups)
+}
+
+// retire adds the counts of a group whose window has elapsed to the expired groups, or to
+// the overflow entry once maxExpiredAggregates groups are kept. The caller holds a.mu.
+func (a *Aggregator) retire(entry *AggregateEntry) {
+	key := entry.Fingerprint
+	if _, ok := a.expired[key]; !ok && len(a.expired) >= maxExpiredAggregates {
+		key = ""
+	}
+	if total, ok := a.expired[key]; ok {
+		total.Count += entry.Count
+		if entry.FirstSeen.Before(total.FirstSeen) {
+			total.FirstSeen = entry.FirstSeen
+		}
+		if entry.LastSeen.After(total.LastSeen) {
+			total.LastSeen = entry.LastSeen
+		}
+		return
+	}
+	total := *entry
+	if key == "" {
+		total.Fingerprint, total.Code, total.Message = "", "", "other errors"
+	}
+	a.expired[key] = &total
+}
+
+// mergeAggregates returns one entry per fingerprint of expired and groups, adding up the
+// counts of a group present in both, sorted by sortAggregateEntries.
+func mergeAggregates(expired, groups map[string]*AggregateEntry) []AggregateEntry {
+	entries := make([]AggregateEntry, 0, len(expired)+len(groups))
+	for key, entry := range expired {
+		merged := *entry
+		if live, ok := groups[key]; ok {
+			merged.Count += live.Count
+			merged.LastSeen = live.LastSeen
+		}
+		entries = append(entries, merged)
+	}
+	for key, entry := range groups {
+		if _, ok := expired[key]; !ok {
+			entries = append(entries, *entry)
+		}
+	}
+	sortAggregateEntries(entries)
+	return entries
+}
+
+// aggregateKey returns the grouping key, code and message of err.
+func aggregateKey(err error) (string, ErrorCode, string) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Fingerprint(), e.Code, e.Message
+	}
+	return messageTemplate(err.Error()), "", err.Error()
+}
+
+// sortAggregateEntries orders entries by decreasing count, then by fingerprint.
+func sortAggregateEntries(entries []AggregateEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Fingerprint < entries[j].Fingerprint
+	})
+}
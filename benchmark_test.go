@@ -9,6 +9,7 @@ package errors
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
@@ -25,6 +26,13 @@ func BenchmarkNew(b *testing.B) {
 	}
 }
 
+func BenchmarkNewWithStackOption(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = New(BenchmarkErrorCode, "Benchmark error message", WithStack())
+	}
+}
+
 func BenchmarkNewWithField(b *testing.B) {
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
@@ -53,6 +61,15 @@ func BenchmarkWrap(b *testing.B) {
 	}
 }
 
+func BenchmarkRetryDelay(b *testing.B) {
+	err := Wrap(New(BenchmarkErrorCode, "Rate limited").WithRetryAfter(time.Second), BenchmarkErrorCode, "Wrapped")
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = RetryDelay(err)
+	}
+}
+
 // Benchmark stacktrace operations
 func BenchmarkCaptureStacktrace(b *testing.B) {
 	b.ReportAllocs()
@@ -61,8 +78,33 @@ func BenchmarkCaptureStacktrace(b *testing.B) {
 	}
 }
 
+// Benchmark callsite capture against full stack capture
+func BenchmarkNewAtCallsite(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = NewAtCallsite(BenchmarkErrorCode, "Benchmark error message")
+	}
+}
+
+func BenchmarkNewWithStacktrace(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		err := New(BenchmarkErrorCode, "Benchmark error message")
+		err.Stack = CaptureStacktrace(1)
+	}
+}
+
+func BenchmarkNewWithResolvedStacktrace(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		err := New(BenchmarkErrorCode, "Benchmark error message")
+		err.Stack = CaptureStacktrace(1)
+		_ = err.Stack.String()
+	}
+}
+
 func BenchmarkStacktraceString(b *testing.B) {
-	stack := CaptureStacktrace(1)
+	stack := CaptureStacktrace(0)
 	b.ResetTimer()
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
@@ -85,6 +127,20 @@ func BenchmarkMarshalJSON(b *testing.B) {
 	}
 }
 
+func BenchmarkEncodeJSON(b *testing.B) {
+	err := New(BenchmarkErrorCode, "JSON benchmark error").
+		WithUserMessage("User friendly message").
+		WithContext("key", "value").
+		AsRetryable().
+		WithCriticalSeverity()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = err.EncodeJSON(io.Discard)
+	}
+}
+
 func BenchmarkMarshalJSONWithStack(b *testing.B) {
 	originalErr := fmt.Errorf("original error")
 	err := Wrap(originalErr, BenchmarkErrorCode, "Error with stack").
@@ -174,3 +230,22 @@ func BenchmarkTimeCachedTime(b *testing.B) {
 		_ = timecache.CachedTime()
 	}
 }
+
+// Benchmark caller capture with the WithCaller option
+func BenchmarkNewWithCaller(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = New(BenchmarkErrorCode, "Benchmark error message", WithCaller())
+	}
+}
+
+// Benchmark a three-layer wrap chain, where only the innermost wrapper captures a stack
+func BenchmarkWrapChain(b *testing.B) {
+	base := fmt.Errorf("base error")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		err := Wrap(base, BenchmarkErrorCode, "layer 1")
+		err = Wrap(err, BenchmarkErrorCode, "layer 2")
+		_ = Wrap(err, BenchmarkErrorCode, "layer 3")
+	}
+}
@@ -0,0 +1,103 @@
+// builder.go: Immutable error builder for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+// Builder builds errors without mutating shared values: every With* method returns a new
+// Builder and leaves the receiver unchanged, so a Builder stored in a package-level variable
+// can be customized per request from many goroutines. Create one with NewBuilder or
+// BuilderFrom; the zero value is not usable.
+//
+// Example:
+//
+//	var errQuota = errors.NewBuilder("QUOTA_EXCEEDED", "Quota exceeded").
+//		WithUserMessage("You have reached your plan limit").
+//		WithSeverity(errors.SeverityWarning)
+//
+//	return errQuota.WithContext("tenant_id", tenantID).Build()
+type Builder struct {
+	proto *Error
+}
+
+// NewBuilder returns a builder for errors with the given code and message.
+// If code is empty or whitespace-only, DefaultErrorCode will be used instead.
+func NewBuilder(code ErrorCode, message string) Builder {
+	return Builder{proto: &Error{
+		Code:     checkCode(code),
+		Message:  message,
+		Severity: SeverityError,
+		Context:  make(map[string]interface{}),
+	}}
+}
+
+// BuilderFrom returns a builder starting from a copy of e, typically a shared error value
+// that must be enriched per call without being modified. When e is a sentinel (see
+// NewSentinel), the built errors still match it in errors.Is.
+func BuilderFrom(e *Error) Builder {
+	return Builder{proto: e.Clone()}
+}
+
+// with returns a new builder whose error is a copy of b's, modified by fn.
+func (b Builder) with(fn func(*Error)) Builder {
+	c := b.proto.Clone()
+	fn(c)
+	return Builder{proto: c}
+}
+
+// WithContext returns a builder that adds a context entry.
+func (b Builder) WithContext(key string, value interface{}) Builder {
+	return b.with(func(e *Error) { e.WithContext(key, value) })
+}
+
+// WithField returns a builder that sets the field name and value.
+func (b Builder) WithField(field, value string) Builder {
+	return b.with(func(e *Error) { e.Field, e.Value = field, value })
+}
+
+// WithUserMessage returns a builder that sets the user-friendly message.
+func (b Builder) WithUserMessage(msg string) Builder {
+	return b.with(func(e *Error) { e.UserMsg = msg })
+}
+
+// WithSeverity returns a builder that sets the severity.
+func (b Builder) WithSeverity(severity Severity) Builder {
+	return b.with(func(e *Error) { e.Severity = severity })
+}
+
+// WithCategory returns a builder that sets the category.
+func (b Builder) WithCategory(category string) Builder {
+	return b.with(func(e *Error) { e.Category = category })
+}
+
+// WithOp returns a builder that sets the operation.
+func (b Builder) WithOp(op string) Builder {
+	return b.with(func(e *Error) { e.Op = op })
+}
+
+// WithHTTPStatus returns a builder that sets the HTTP status code.
+func (b Builder) WithHTTPStatus(code int) Builder {
+	return b.with(func(e *Error) { e.HTTPStatusCode = code })
+}
+
+// WithCause returns a builder that sets the cause.
+func (b Builder) WithCause(cause error) Builder {
+	return b.with(func(e *Error) { e.Cause = cause })
+}
+
+// AsRetryable returns a builder that marks errors as retryable.
+func (b Builder) AsRetryable() Builder {
+	return b.with(func(e *Error) { e.Retryable = true })
+}
+
+// Build returns a new error from the builder, timestamped now. Options such as WithStack()
+// are applied as in New, and the stack trace, if any, starts at the caller of Build.
+// The returned error is independent of the builder and of other built errors.
+func (b Builder) Build(opts ...Option) *Error {
+	e := b.proto.Clone()
+	e.Timestamp = now()
+	e.finish(opts, false, 1)
+	return e
+}
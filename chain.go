@@ -0,0 +1,275 @@
+// chain.go: Error chain traversal for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"errors"
+	"iter"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	// DefaultMaxChainDepth is the default number of errors visited while traversing a chain.
+	DefaultMaxChainDepth = 1024
+
+	// ErrCodeChainCycle is the code of the error returned by CheckChain for a chain whose
+	// Unwrap methods loop back to an error already visited.
+	ErrCodeChainCycle ErrorCode = "ERROR_CHAIN_CYCLE"
+
+	// ErrCodeChainTooDeep is the code of the error returned by CheckChain for a chain longer
+	// than GetMaxChainDepth.
+	ErrCodeChainTooDeep ErrorCode = "ERROR_CHAIN_TOO_DEEP"
+)
+
+// maxChainDepth is set by SetMaxChainDepth; 0 selects DefaultMaxChainDepth.
+var maxChainDepth atomic.Int32
+
+// SetMaxChainDepth sets how many errors the chain helpers, such as HasCode, RootCause,
+// Chain and HTTPStatus, visit at most before stopping. Together with the cycle detection
+// of the traversal it guarantees that a custom Unwrap returning a cycle or an endless chain
+// cannot hang the program: the helpers answer from the errors visited so far. A depth of 0
+// or less restores DefaultMaxChainDepth.
+func SetMaxChainDepth(depth int) {
+	if depth < 0 {
+		depth = 0
+	}
+	maxChainDepth.Store(int32(depth))
+}
+
+// GetMaxChainDepth returns the depth set with SetMaxChainDepth.
+func GetMaxChainDepth() int {
+	if depth := maxChainDepth.Load(); depth > 0 {
+		return int(depth)
+	}
+	return DefaultMaxChainDepth
+}
+
+// chainWalk holds the state of a traversal by walkErrors.
+type chainWalk struct {
+	visited   int
+	linear    bool // follow only Unwrap() error, like errors.Unwrap
+	cycle     bool // a cycle was detected and cut
+	truncated bool // the traversal stopped at GetMaxChainDepth
+}
+
+// WalkChain calls fn for each error in the chain of err, starting with err itself and
+// stopping as soon as fn returns false. Both unwrap shapes are followed: Unwrap() error
+// and Unwrap() []error, as returned by errors.Join, whose branches are visited depth-first
+// in order. An *Error reached twice, through a cycle or a shared branch, is visited once.
+// It is the traversal primitive shared by HasCode, RootCause, Chain and FindCode.
+//
+// Example:
+//
+//	errors.WalkChain(err, func(e error) bool {
+//		log.Printf("%T: %v", e, e)
+//		return true
+//	})
+func WalkChain(err error, fn func(error) bool) {
+	var seen []*Error
+	var w chainWalk
+	walkErrors(err, func(cur error) bool {
+		if e, ok := cur.(*Error); ok {
+			for _, s := range seen {
+				if s == e {
+					return true
+				}
+			}
+			seen = append(seen, e)
+		}
+		return fn(cur)
+	}, &w)
+}
+
+// walkErrors is the allocation-free core of WalkChain. It does not deduplicate nodes, but
+// detects cycles along each linear segment of the chain with Brent's algorithm and stops
+// the segment when one is found, after visiting some nodes of the cycle a second time,
+// which is harmless for searches. It returns false once the traversal must stop, either
+// because fn returned false or because GetMaxChainDepth errors have been visited.
+func walkErrors(err error, fn func(error) bool, w *chainWalk) bool {
+	limit := GetMaxChainDepth()
+	var tortoise error
+	power, steps := 1, 0
+	for err != nil {
+		w.visited++
+		if w.visited > limit {
+			w.truncated = true
+			return false
+		}
+		if !fn(err) {
+			return false
+		}
+		if steps == power {
+			tortoise = err
+			power *= 2
+			steps = 0
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() []error }:
+			if w.linear {
+				return true
+			}
+			for _, branch := range u.Unwrap() {
+				if !walkErrors(branch, fn, w) {
+					return false
+				}
+			}
+			return true
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+		default:
+			return true
+		}
+		steps++
+		if sameError(err, tortoise) {
+			w.cycle = true
+			return true
+		}
+	}
+	return true
+}
+
+// sameError reports whether a and b are the same error value, without the run-time panic
+// of comparing interfaces holding an uncomparable type.
+func sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if ea, ok := a.(*Error); ok {
+		eb, ok := b.(*Error)
+		return ok && ea == eb
+	}
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
+// unwrapChain returns an iterator over err and the errors returned by its successive
+// Unwrap() error methods, like a loop over errors.Unwrap, bounded like walkErrors.
+func unwrapChain(err error) iter.Seq[error] {
+	return func(yield func(error) bool) {
+		w := chainWalk{linear: true}
+		walkErrors(err, yield, &w)
+	}
+}
+
+// CheckChain reports whether the chain of err can be traversed completely. It returns nil
+// for a well-formed chain, an error with code ErrCodeChainCycle if an Unwrap method loops
+// back to an error already visited, and an error with code ErrCodeChainTooDeep if the chain
+// has more than GetMaxChainDepth errors. The chain helpers never hang on such chains, but
+// their answers only cover the errors visited, so CheckChain lets callers detect and report
+// misbehaving custom error types.
+//
+// Example:
+//
+//	if cerr := errors.CheckChain(err); cerr != nil {
+//		logger.Warn("malformed error chain", "error", cerr)
+//	}
+func CheckChain(err error) error {
+	var w chainWalk
+	walkErrors(err, func(error) bool { return true }, &w)
+	switch {
+	case w.cycle:
+		return New(ErrCodeChainCycle, "Error chain contains a cycle").
+			WithContext("visited", w.visited)
+	case w.truncated:
+		return New(ErrCodeChainTooDeep, "Error chain exceeds "+strconv.Itoa(GetMaxChainDepth())+" errors").
+			WithContext("max_depth", GetMaxChainDepth())
+	}
+	return nil
+}
+
+// Chain returns every *Error in the chain of err, from outermost to innermost, skipping
+// errors of other types. Aggregates implementing Unwrap() []error, such as the result of
+// errors.Join, are traversed depth-first in order. Each *Error is reported once, even if
+// the chain contains a cycle. Chain returns nil if err is nil or contains no *Error.
+//
+// Example:
+//
+//	for _, e := range errors.Chain(err) {
+//		log.Printf("%s: %s", e.Code, e.Message)
+//	}
+func Chain(err error) []*Error {
+	var chain []*Error
+	WalkChain(err, func(cur error) bool {
+		if e, ok := cur.(*Error); ok {
+			chain = append(chain, e)
+		}
+		return true
+	})
+	return chain
+}
+
+// FindCode returns the first *Error in the chain of err with the given code, in the same
+// order as Chain. The boolean is false if there is none.
+//
+// Example:
+//
+//	if dbErr, ok := errors.FindCode(err, ErrCodeDatabase); ok {
+//		log.Printf("query: %v", dbErr.Context["query"])
+//	}
+func FindCode(err error, code ErrorCode) (*Error, bool) {
+	var found *Error
+	var w chainWalk
+	walkErrors(err, func(cur error) bool {
+		if e, ok := cur.(*Error); ok && e.Code == code {
+			found = e
+			return false
+		}
+		return true
+	}, &w)
+	return found, found != nil
+}
+
+// Ops returns the operations recorded with WithOp along the chain of err, outermost
+// first, as a lightweight trace of the logical call path. Consecutive duplicates, as when
+// a helper and its caller record the same operation, are collapsed.
+//
+// Example:
+//
+//	log.Printf("%s: %v", strings.Join(errors.Ops(err), ": "), err)
+//	// api.CreateOrder: billing.ChargeCard: stripe.CreateCharge: [CHARGE_FAILED]: ...
+func Ops(err error) []string {
+	var ops []string
+	WalkChain(err, func(cur error) bool {
+		if e, ok := cur.(*Error); ok && e.Op != "" && (len(ops) == 0 || ops[len(ops)-1] != e.Op) {
+			ops = append(ops, e.Op)
+		}
+		return true
+	})
+	return ops
+}
+
+// JoinErrors creates an *Error whose cause joins errs like errors.Join, so that errors.Is,
+// errors.As, HasCode and Chain search every branch. Nil errors are discarded, and JoinErrors
+// returns nil if errs contains no non-nil error. The stack trace is captured like Wrap.
+//
+// Example:
+//
+//	return errors.JoinErrors("CLEANUP_FAILED", "Failed to release resources",
+//		conn.Close(), file.Close(), lock.Release())
+func JoinErrors(code ErrorCode, message string, errs ...error) *Error {
+	joined := errors.Join(errs...)
+	if joined == nil {
+		return nil
+	}
+	return wrapWithOptions(joined, WrapOptions{Code: code, Message: message}, 1)
+}
+
+// Causes returns the direct causes of the error: the branches joined by JoinErrors or any
+// other cause implementing Unwrap() []error, or the single Cause otherwise.
+// It returns nil if the error has no cause.
+func (e *Error) Causes() []error {
+	switch c := e.Cause.(type) {
+	case nil:
+		return nil
+	case interface{ Unwrap() []error }:
+		return c.Unwrap()
+	default:
+		return []error{c}
+	}
+}
@@ -0,0 +1,67 @@
+// checkpoint.go: Transactional error state for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+// Checkpoint snapshots the current state of the error and returns the error for chaining.
+// The snapshot is a deep copy of the mutable fields, so later changes made through
+// WithContext() and the other builder methods do not leak into it.
+// Taking a new checkpoint replaces the previous one.
+//
+// Example:
+//
+//	err.Checkpoint()
+//	if handleErr := tryRecover(err.WithContext("attempt", 1)); handleErr != nil {
+//		err.Restore() // back to the original error
+//	}
+func (e *Error) Checkpoint() *Error {
+	e.checkpoint = e.snapshot()
+	return e
+}
+
+// Restore reverts the error to the state saved by the last Checkpoint() call and returns
+// the error for chaining. The checkpoint is kept, so Restore can be called repeatedly.
+// If no checkpoint exists the error is returned unchanged.
+func (e *Error) Restore() *Error {
+	cp := e.checkpoint
+	if cp == nil {
+		return e
+	}
+	*e = *cp.snapshot()
+	e.checkpoint = cp
+	return e
+}
+
+// HasCheckpoint reports whether a checkpoint has been taken and not cleared.
+func (e *Error) HasCheckpoint() bool {
+	return e.checkpoint != nil
+}
+
+// ClearCheckpoint discards the saved checkpoint and returns the error for chaining.
+func (e *Error) ClearCheckpoint() *Error {
+	e.checkpoint = nil
+	return e
+}
+
+// snapshot returns a copy of the error whose mutable fields are not shared with e.
+// The checkpoint itself is not carried over to the copy.
+func (e *Error) snapshot() *Error {
+	cp := *e
+	cp.checkpoint = nil
+	if e.Context != nil {
+		cp.Context = make(map[string]interface{}, len(e.Context))
+		for k, v := range e.Context {
+			cp.Context[k] = v
+		}
+	}
+	if e.FieldErrors != nil {
+		cp.FieldErrors = append([]FieldError(nil), e.FieldErrors...)
+	}
+	if e.Events != nil {
+		cp.Events = append([]TimelineEvent(nil), e.Events...)
+	}
+	return &cp
+}
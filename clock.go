@@ -0,0 +1,91 @@
+// clock.go: Pluggable time source for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock supplies the Timestamp of new errors.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts an ordinary function to the Clock interface.
+type ClockFunc func() time.Time
+
+// Now returns f().
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock reads time.Now. It is the default clock, unless the library is built with
+// the timecache tag.
+var SystemClock Clock = ClockFunc(time.Now)
+
+// defaultClock is the clock restored by SetClock(nil). Builds with the timecache tag
+// replace it with the cached clock of go-timecache (see clock_timecache.go).
+var defaultClock = SystemClock
+
+// clockHolder boxes a Clock so it can be stored in an atomic.Pointer.
+type clockHolder struct {
+	clock Clock
+}
+
+var (
+	currentClock      atomic.Pointer[clockHolder]
+	timestampsEnabled atomic.Bool
+)
+
+func init() {
+	timestampsEnabled.Store(true)
+}
+
+// SetClock sets the clock used to timestamp new errors. Passing nil restores the default
+// clock: SystemClock, or the go-timecache clock when built with -tags timecache.
+// SetClock is also the hook for other time sources, e.g. a cached clock without the build tag:
+//
+//	errors.SetClock(errors.ClockFunc(timecache.CachedTime))
+//
+// Example:
+//
+//	// Freeze time in tests
+//	fixed := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+//	errors.SetClock(errors.ClockFunc(func() time.Time { return fixed }))
+//	defer errors.SetClock(nil)
+func SetClock(c Clock) {
+	if c == nil {
+		currentClock.Store(nil)
+		return
+	}
+	currentClock.Store(&clockHolder{clock: c})
+}
+
+// SetTimestamps enables or disables the timestamping of new errors. When disabled, errors
+// are created with a zero Timestamp and the clock is not consulted, which saves its cost on
+// hot paths. Timestamps are enabled by default.
+func SetTimestamps(enabled bool) {
+	timestampsEnabled.Store(enabled)
+}
+
+// now returns the Timestamp for a new error.
+func now() time.Time {
+	if !timestampsEnabled.Load() {
+		return time.Time{}
+	}
+	return clockNow()
+}
+
+// clockNow reads the clock set with SetClock, regardless of SetTimestamps. It is used by
+// the components that measure time windows, such as Aggregator.
+func clockNow() time.Time {
+	if h := currentClock.Load(); h != nil {
+		return h.clock.Now()
+	}
+	return defaultClock.Now()
+}
@@ -0,0 +1,19 @@
+// clock_timecache.go: go-timecache default clock for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build timecache
+
+package errors
+
+import (
+	"github.com/agilira/go-timecache"
+)
+
+// With the timecache build tag, new errors are timestamped with the cached time of
+// go-timecache: cheaper than time.Now, but only as precise as the cache refresh interval.
+func init() {
+	defaultClock = ClockFunc(timecache.CachedTime)
+}
@@ -0,0 +1,154 @@
+// codes.go: Standard error code presets for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+// Package codes provides a curated set of error codes modelled on the canonical gRPC
+// status codes, each mapped to its HTTP status, gRPC code, default severity, retry hint
+// and user message, so that services share one mapping instead of reinventing it. The
+// gRPC codes are plain numbers equal to those of google.golang.org/grpc/codes, which the
+// package does not import.
+//
+// Example:
+//
+//	func init() {
+//		_ = codes.Register(errors.DefaultRegistry) // errors.HTTPStatus now knows the presets
+//	}
+//
+//	return errors.New(codes.NotFound, "user not found")
+package codes
+
+import (
+	"net/http"
+
+	"github.com/agilira/go-errors"
+)
+
+// Error codes of the presets, named after the canonical gRPC codes. Codes that the errors
+// package defines too, for its constructors such as errors.NotFound, are the same values.
+const (
+	Canceled           errors.ErrorCode = errors.ErrCodeCanceled
+	Unknown            errors.ErrorCode = "UNKNOWN"
+	InvalidArgument    errors.ErrorCode = errors.ErrCodeInvalidArgument
+	DeadlineExceeded   errors.ErrorCode = "DEADLINE_EXCEEDED"
+	NotFound           errors.ErrorCode = errors.ErrCodeNotFound
+	AlreadyExists      errors.ErrorCode = errors.ErrCodeAlreadyExists
+	PermissionDenied   errors.ErrorCode = errors.ErrCodePermissionDenied
+	ResourceExhausted  errors.ErrorCode = errors.ErrCodeResourceExhausted
+	FailedPrecondition errors.ErrorCode = "FAILED_PRECONDITION"
+	Aborted            errors.ErrorCode = "ABORTED"
+	OutOfRange         errors.ErrorCode = "OUT_OF_RANGE"
+	Unimplemented      errors.ErrorCode = "UNIMPLEMENTED"
+	Internal           errors.ErrorCode = errors.ErrCodeInternal
+	Unavailable        errors.ErrorCode = errors.ErrCodeUnavailable
+	DataLoss           errors.ErrorCode = "DATA_LOSS"
+	Unauthenticated    errors.ErrorCode = errors.ErrCodeUnauthenticated
+)
+
+// GRPCUnknown is the gRPC code returned by GRPCCode for errors without a preset code.
+const GRPCUnknown uint32 = 2
+
+// Preset is the mapping of one preset code.
+type Preset struct {
+	Code        errors.ErrorCode
+	HTTPStatus  int
+	GRPCCode    uint32
+	Severity    errors.Severity
+	Retryable   bool
+	UserMessage string
+}
+
+// presets lists the presets in gRPC code order. The HTTP statuses follow the mapping of
+// grpc-gateway.
+var presets = []Preset{
+	{Canceled, 499, 1, errors.SeverityInfo, false, "The request was canceled."},
+	{Unknown, http.StatusInternalServerError, 2, errors.SeverityError, false, "An unexpected error occurred."},
+	{InvalidArgument, http.StatusBadRequest, 3, errors.SeverityWarning, false, "The request is invalid."},
+	{DeadlineExceeded, http.StatusGatewayTimeout, 4, errors.SeverityError, true, "The request timed out. Please try again."},
+	{NotFound, http.StatusNotFound, 5, errors.SeverityWarning, false, "The requested resource was not found."},
+	{AlreadyExists, http.StatusConflict, 6, errors.SeverityWarning, false, "The resource already exists."},
+	{PermissionDenied, http.StatusForbidden, 7, errors.SeverityWarning, false, "You do not have permission to perform this action."},
+	{ResourceExhausted, http.StatusTooManyRequests, 8, errors.SeverityWarning, true, "Too many requests. Please try again later."},
+	{FailedPrecondition, http.StatusBadRequest, 9, errors.SeverityWarning, false, "The request cannot be performed in the current state."},
+	{Aborted, http.StatusConflict, 10, errors.SeverityWarning, true, "The request conflicted with another one. Please try again."},
+	{OutOfRange, http.StatusBadRequest, 11, errors.SeverityWarning, false, "A value is out of range."},
+	{Unimplemented, http.StatusNotImplemented, 12, errors.SeverityError, false, "This operation is not supported."},
+	{Internal, http.StatusInternalServerError, 13, errors.SeverityError, false, "An internal error occurred."},
+	{Unavailable, http.StatusServiceUnavailable, 14, errors.SeverityError, true, "The service is temporarily unavailable. Please try again."},
+	{DataLoss, http.StatusInternalServerError, 15, errors.SeverityCritical, false, "An internal error occurred."},
+	{Unauthenticated, http.StatusUnauthorized, 16, errors.SeverityWarning, false, "Authentication is required."},
+}
+
+// presetsByCode indexes presets by code.
+var presetsByCode = func() map[errors.ErrorCode]Preset {
+	m := make(map[errors.ErrorCode]Preset, len(presets))
+	for _, p := range presets {
+		m[p.Code] = p
+	}
+	return m
+}()
+
+// Presets returns every preset in gRPC code order.
+func Presets() []Preset {
+	return append([]Preset(nil), presets...)
+}
+
+// Lookup returns the preset of code.
+func Lookup(code errors.ErrorCode) (Preset, bool) {
+	p, ok := presetsByCode[code]
+	return p, ok
+}
+
+// Register records every preset in r, so that r.New fills in their defaults and, for
+// errors.DefaultRegistry, errors.HTTPStatus returns their HTTP status. It stops at the
+// first code already registered and returns the error of r.Register.
+func Register(r *errors.ErrorRegistry) error {
+	for _, p := range presets {
+		if err := r.Register(p.Code, errors.ErrorMeta{
+			DefaultUserMessage: p.UserMessage,
+			DefaultSeverity:    p.Severity,
+			DefaultRetryable:   p.Retryable,
+			DefaultHTTPStatus:  p.HTTPStatus,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HTTPStatus returns the HTTP status of the first *errors.Error in the chain of err with
+// a preset code, and falls back to errors.HTTPStatus otherwise.
+func HTTPStatus(err error) int {
+	if p, ok := find(err); ok {
+		return p.HTTPStatus
+	}
+	return errors.HTTPStatus(err)
+}
+
+// GRPCCode returns the gRPC code of the first *errors.Error in the chain of err with a
+// preset code: 0 (OK) for a nil error and GRPCUnknown if no preset code is found. The
+// result converts to google.golang.org/grpc/codes.Code.
+//
+// Example:
+//
+//	return status.Error(grpccodes.Code(codes.GRPCCode(err)), err.Error())
+func GRPCCode(err error) uint32 {
+	if err == nil {
+		return 0
+	}
+	if p, ok := find(err); ok {
+		return p.GRPCCode
+	}
+	return GRPCUnknown
+}
+
+// find returns the preset of the first *errors.Error in the chain of err with a preset code.
+func find(err error) (Preset, bool) {
+	for _, e := range errors.Chain(err) {
+		if p, ok := presetsByCode[e.Code]; ok {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
@@ -0,0 +1,66 @@
+// codes_test.go: Tests for the codes package of the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package codes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/agilira/go-errors"
+)
+
+func TestPresets(t *testing.T) {
+	all := Presets()
+	if len(all) != 16 {
+		t.Fatalf("Expected 16 presets, got %d", len(all))
+	}
+	for i, p := range all {
+		if p.GRPCCode != uint32(i+1) {
+			t.Errorf("Expected %s to have gRPC code %d, got %d", p.Code, i+1, p.GRPCCode)
+		}
+		if p.HTTPStatus == 0 || p.UserMessage == "" || p.Severity == "" {
+			t.Errorf("Expected complete preset for %s, got %+v", p.Code, p)
+		}
+	}
+	if p, ok := Lookup(NotFound); !ok || p.HTTPStatus != http.StatusNotFound || p.GRPCCode != 5 {
+		t.Errorf("Unexpected NotFound preset %+v", p)
+	}
+	if _, ok := Lookup("CUSTOM"); ok {
+		t.Error("Expected no preset for a custom code")
+	}
+}
+
+func TestStatuses(t *testing.T) {
+	err := fmt.Errorf("handler: %w", errors.Wrap(errors.New(Unavailable, "db down"), "LOAD_FAILED", "Load failed"))
+	if got := HTTPStatus(err); got != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", got)
+	}
+	if got := GRPCCode(err); got != 14 {
+		t.Errorf("Expected gRPC code 14, got %d", got)
+	}
+	if GRPCCode(nil) != 0 || GRPCCode(errors.New("CUSTOM", "x")) != GRPCUnknown {
+		t.Error("Expected OK for nil and Unknown for custom codes")
+	}
+	if got := HTTPStatus(errors.New("CUSTOM", "x")); got != http.StatusInternalServerError {
+		t.Errorf("Expected fallback to errors.HTTPStatus, got %d", got)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := errors.NewRegistry()
+	if err := Register(r); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	e := r.New(ResourceExhausted, "quota exceeded")
+	if !e.Retryable || e.HTTPStatusCode != http.StatusTooManyRequests || e.UserMessage() != "Too many requests. Please try again later." {
+		t.Errorf("Expected preset defaults, got %+v", e)
+	}
+	if err := Register(r); !errors.HasCode(err, errors.ErrCodeAlreadyRegistered) {
+		t.Errorf("Expected duplicate registration to fail, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+// collector.go: Concurrent error collection for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"sync"
+)
+
+// WorkerKey is the context key under which Collector.CollectWorker records the worker index.
+const WorkerKey = "worker"
+
+// Collector gathers the errors reported by many goroutines, such as the workers of a
+// fan-out pool, into a single *MultiError. It is safe for concurrent use.
+type Collector struct {
+	mu   sync.Mutex
+	errs []*Error
+}
+
+// NewCollector returns an empty Collector.
+//
+// Example:
+//
+//	c := errors.NewCollector()
+//	var wg sync.WaitGroup
+//	for w := 0; w < workers; w++ {
+//		wg.Add(1)
+//		go func(w int) {
+//			defer wg.Done()
+//			for job := range jobs {
+//				c.CollectWorker(w, process(job))
+//			}
+//		}(w)
+//	}
+//	wg.Wait()
+//	return c.Err()
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Collect records err. Nil errors are ignored, so the result of a call can be passed
+// directly. An *Error is recorded as is; any other error is wrapped without a stack
+// trace under DefaultErrorCode, as in Append.
+func (c *Collector) Collect(err error) {
+	if err == nil {
+		return
+	}
+	e, ok := err.(*Error)
+	if !ok || e == nil {
+		e = WrapNoStack(err, DefaultErrorCode, err.Error())
+	}
+	c.add(e)
+}
+
+// CollectWorker records err like Collect, wrapped with the index of the reporting worker in
+// its context under WorkerKey. The code and message of err are kept.
+func (c *Collector) CollectWorker(worker int, err error) {
+	if err == nil {
+		return
+	}
+	c.add(annotateFailure(err, map[string]interface{}{WorkerKey: worker}))
+}
+
+// add appends e under the lock.
+func (c *Collector) add(e *Error) {
+	c.mu.Lock()
+	c.errs = append(c.errs, e)
+	c.mu.Unlock()
+}
+
+// Errors returns a copy of the recorded errors, in the order they were collected.
+func (c *Collector) Errors() []*Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	errs := make([]*Error, len(c.errs))
+	copy(errs, c.errs)
+	return errs
+}
+
+// Len returns the number of recorded errors.
+func (c *Collector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.errs)
+}
+
+// Err returns the recorded errors as a *MultiError, in the order they were collected, or
+// nil if none was recorded.
+func (c *Collector) Err() error {
+	errs := c.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	return &MultiError{errs: errs}
+}
@@ -0,0 +1,211 @@
+// convention.go: Pluggable error code conventions for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// CodeConventionError is the error code of the *Error returned by code convention validators
+// when an ErrorCode does not follow the configured naming convention.
+const CodeConventionError ErrorCode = "CODE_CONVENTION_ERROR"
+
+// InvalidCodePolicy controls what constructors do with a code rejected by the convention
+// installed with SetCodeConvention.
+type InvalidCodePolicy int
+
+const (
+	// InvalidCodeUseDefault replaces the rejected code with DefaultErrorCode (default).
+	InvalidCodeUseDefault InvalidCodePolicy = iota
+	// InvalidCodePanic makes constructors panic with the convention error.
+	InvalidCodePanic
+)
+
+// maxUpperSnakeCaseLen is the maximum length accepted by UpperSnakeCaseConvention.
+const maxUpperSnakeCaseLen = 64
+
+type codeConvention struct {
+	validate func(ErrorCode) error
+	policy   InvalidCodePolicy
+}
+
+// builtinCodes are the codes used by the package's own constructors and helpers. They are
+// exempt from the installed convention, which describes the caller's codes, so that the
+// built-in helpers keep working under any convention and InvalidCodePolicy.
+var builtinCodes = map[ErrorCode]bool{
+	DefaultErrorCode:             true,
+	CodeConventionError:          true,
+	ErrCodeAlreadyRegistered:     true,
+	ErrCodeChainCycle:            true,
+	ErrCodeChainTooDeep:          true,
+	ErrCodeDB:                    true,
+	ErrCodeDBConnection:          true,
+	ErrCodeDBConstraintViolation: true,
+	ErrCodeDBDeadlock:            true,
+	ErrCodeDBForeignKeyViolation: true,
+	ErrCodeDBNoRows:              true,
+	ErrCodeDBUniqueViolation:     true,
+	ErrCodeEventChannel:          true,
+	ErrCodeHTTPResponse:          true,
+	ErrCodeInvalidErrorMap:       true,
+	ErrCodeInvariant:             true,
+	ErrCodeNotImplemented:        true,
+	ErrCodePanic:                 true,
+	ErrCodeValidationFailed:      true,
+	ErrCodeCanceled:              true,
+	ErrCodeConnectionRefused:     true,
+	ErrCodeConnectionReset:       true,
+	ErrCodeDNS:                   true,
+	ErrCodeNetwork:               true,
+	ErrCodeTimeout:               true,
+	ErrCodeAlreadyExists:         true,
+	ErrCodeInternal:              true,
+	ErrCodeInvalidArgument:       true,
+	ErrCodeNotFound:              true,
+	ErrCodePermissionDenied:      true,
+	ErrCodeResourceExhausted:     true,
+	ErrCodeUnauthenticated:       true,
+	ErrCodeUnavailable:           true,
+}
+
+// convention holds the active code convention. A nil pointer means no convention is
+// installed, which keeps the constructors' hot path to a single atomic load.
+var convention atomic.Pointer[codeConvention]
+
+// SetCodeConvention installs a validator that every constructor applies to its code, in
+// addition to the built-in empty/whitespace check. Passing nil removes the convention.
+// DefaultErrorCode and the package's ErrCode* constants are exempt, so the built-in
+// helpers such as NotFound and Combine never fail the convention.
+// Use NewChecked to receive the validator error directly, or SetInvalidCodePolicy to
+// choose between falling back to DefaultErrorCode and panicking.
+//
+// Example:
+//
+//	errors.SetCodeConvention(errors.UpperSnakeCaseConvention())
+func SetCodeConvention(validator func(ErrorCode) error) {
+	for {
+		old := convention.Load()
+		next := &codeConvention{validate: validator}
+		if old != nil {
+			next.policy = old.policy
+		}
+		if convention.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// SetInvalidCodePolicy sets how constructors handle codes rejected by the installed convention.
+// It has no effect until a convention is installed with SetCodeConvention.
+func SetInvalidCodePolicy(policy InvalidCodePolicy) {
+	for {
+		old := convention.Load()
+		next := &codeConvention{policy: policy}
+		if old != nil {
+			next.validate = old.validate
+		}
+		if convention.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// ValidateCode checks code against the built-in rules and the installed convention.
+// It returns nil for a valid code and a *Error with code CodeConventionError otherwise.
+func ValidateCode(code ErrorCode) error {
+	if !validateErrorCode(code) {
+		return newConventionError(code, "error code must not be empty")
+	}
+	if c := convention.Load(); c != nil && c.validate != nil {
+		return c.validate(code)
+	}
+	return nil
+}
+
+// NewChecked works like New but reports a code rejected by the installed convention as a
+// secondary return value instead of applying the InvalidCodePolicy.
+// The returned *Error always uses DefaultErrorCode when the code is rejected.
+func NewChecked(code ErrorCode, message string) (*Error, error) {
+	if verr := ValidateCode(code); verr != nil {
+		return New(DefaultErrorCode, message), verr
+	}
+	return New(code, message), nil
+}
+
+// UpperSnakeCaseConvention returns a validator requiring codes such as "VALIDATION_ERROR":
+// an uppercase letter followed by up to 63 uppercase letters, digits or underscores.
+func UpperSnakeCaseConvention() func(ErrorCode) error {
+	return func(code ErrorCode) error {
+		if !isUpperSnakeCase(string(code)) {
+			return newConventionError(code, "error code must be UPPER_SNAKE_CASE (^[A-Z][A-Z0-9_]{0,63}$)")
+		}
+		return nil
+	}
+}
+
+// DotSeparatedConvention returns a validator requiring codes in the "NAMESPACE.CODE" format,
+// where CODE follows UpperSnakeCaseConvention. If namespace is empty any non-empty
+// namespace is accepted.
+func DotSeparatedConvention(namespace string) func(ErrorCode) error {
+	return func(code ErrorCode) error {
+		ns, name, ok := strings.Cut(string(code), ".")
+		if !ok || ns == "" || (namespace != "" && ns != namespace) || !isUpperSnakeCase(name) {
+			want := namespace
+			if want == "" {
+				want = "NAMESPACE"
+			}
+			return newConventionError(code, fmt.Sprintf("error code must have the form %s.CODE", want))
+		}
+		return nil
+	}
+}
+
+// checkCode returns the code a constructor should use, applying the built-in rules,
+// the installed convention and the InvalidCodePolicy.
+func checkCode(code ErrorCode) ErrorCode {
+	if !validateErrorCode(code) {
+		return DefaultErrorCode
+	}
+	c := convention.Load()
+	if c == nil || c.validate == nil || builtinCodes[code] {
+		return code
+	}
+	if err := c.validate(code); err != nil {
+		if c.policy == InvalidCodePanic {
+			panic(err)
+		}
+		return DefaultErrorCode
+	}
+	return code
+}
+
+func isUpperSnakeCase(s string) bool {
+	if len(s) == 0 || len(s) > maxUpperSnakeCaseLen || s[0] < 'A' || s[0] > 'Z' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// newConventionError builds the error literal directly: going through New would validate
+// CodeConventionError against the very convention that is rejecting a code.
+func newConventionError(code ErrorCode, reason string) *Error {
+	return &Error{
+		Code:      CodeConventionError,
+		Message:   fmt.Sprintf("invalid error code %q: %s", code, reason),
+		Timestamp: now(),
+		Severity:  SeverityError,
+		Context:   map[string]interface{}{"code": string(code)},
+	}
+}
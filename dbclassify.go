@@ -0,0 +1,168 @@
+// dbclassify.go: Database error classification for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Codes assigned by ClassifyDB.
+const (
+	ErrCodeDBNoRows              ErrorCode = "DB_NO_ROWS"
+	ErrCodeDBUniqueViolation     ErrorCode = "DB_UNIQUE_VIOLATION"
+	ErrCodeDBForeignKeyViolation ErrorCode = "DB_FOREIGN_KEY_VIOLATION"
+	ErrCodeDBConstraintViolation ErrorCode = "DB_CONSTRAINT_VIOLATION"
+	ErrCodeDBDeadlock            ErrorCode = "DB_DEADLOCK"
+	ErrCodeDBConnection          ErrorCode = "DB_CONNECTION_ERROR"
+	ErrCodeDB                    ErrorCode = "DB_ERROR"
+)
+
+// SQLStateKey is the context key under which ClassifyDB records the SQLSTATE of the
+// driver error, when the driver exposes one.
+const SQLStateKey = "sqlstate"
+
+// DBErrorKind is the class of a database error recognized by ClassifyDB.
+type DBErrorKind int
+
+const (
+	// DBUnknown is any database error that matches no other kind.
+	DBUnknown DBErrorKind = iota
+	// DBNoRows is a query that returned no row, such as sql.ErrNoRows.
+	DBNoRows
+	// DBUniqueViolation is a duplicate key (SQLSTATE 23505).
+	DBUniqueViolation
+	// DBForeignKeyViolation is a missing or still referenced row (SQLSTATE 23503).
+	DBForeignKeyViolation
+	// DBConstraintViolation is any other integrity constraint violation (SQLSTATE class 23).
+	DBConstraintViolation
+	// DBDeadlock is a deadlock or serialization failure (SQLSTATE 40P01 and 40001).
+	DBDeadlock
+	// DBConnection is a lost or refused connection (SQLSTATE class 08, driver.ErrBadConn).
+	DBConnection
+)
+
+// dbKindInfo is the error produced by ClassifyDB for a DBErrorKind.
+type dbKindInfo struct {
+	code       ErrorCode
+	message    string
+	severity   Severity
+	retryable  bool
+	httpStatus int
+}
+
+var dbKinds = map[DBErrorKind]dbKindInfo{
+	DBUnknown:             {ErrCodeDB, "Database error", SeverityError, false, 0},
+	DBNoRows:              {ErrCodeDBNoRows, "Record not found", SeverityWarning, false, http.StatusNotFound},
+	DBUniqueViolation:     {ErrCodeDBUniqueViolation, "Unique constraint violation", SeverityWarning, false, http.StatusConflict},
+	DBForeignKeyViolation: {ErrCodeDBForeignKeyViolation, "Foreign key constraint violation", SeverityWarning, false, http.StatusConflict},
+	DBConstraintViolation: {ErrCodeDBConstraintViolation, "Constraint violation", SeverityWarning, false, http.StatusConflict},
+	DBDeadlock:            {ErrCodeDBDeadlock, "Transaction deadlock or serialization failure", SeverityError, true, http.StatusServiceUnavailable},
+	DBConnection:          {ErrCodeDBConnection, "Database connection failure", SeverityCritical, true, http.StatusServiceUnavailable},
+}
+
+// DBMatcher recognizes the errors of a database driver. It returns the kind of err, or
+// DBUnknown if it does not recognize it.
+type DBMatcher func(err error) DBErrorKind
+
+var (
+	dbMatchersMu sync.RWMutex
+	dbMatchers   []DBMatcher
+)
+
+// RegisterDBMatcher adds m to the matchers consulted by ClassifyDB. Registered matchers run
+// in registration order, before the built-in rules; the first one returning a kind other
+// than DBUnknown wins. Use it for drivers whose errors do not expose a SQLSTATE method.
+//
+// Example:
+//
+//	errors.RegisterDBMatcher(func(err error) errors.DBErrorKind {
+//		var me *mysql.MySQLError
+//		if stderrors.As(err, &me) && me.Number == 1062 {
+//			return errors.DBUniqueViolation
+//		}
+//		return errors.DBUnknown
+//	})
+func RegisterDBMatcher(m DBMatcher) {
+	if m == nil {
+		return
+	}
+	dbMatchersMu.Lock()
+	defer dbMatchersMu.Unlock()
+	dbMatchers = append(dbMatchers, m)
+}
+
+// ClassifyDB wraps a database error into an *Error whose code, severity, retryable flag and
+// HTTP status reflect its kind: sql.ErrNoRows, constraint violations, deadlocks and
+// connection failures are recognized out of the box, the latter two as retryable. Drivers
+// are recognized through a SQLState() string method, which pgx and lib/pq implement, and
+// through the matchers added with RegisterDBMatcher. The SQLSTATE, when known, is recorded
+// in the context under SQLStateKey. ClassifyDB returns nil if err is nil.
+//
+// Example:
+//
+//	if err := row.Scan(&u.ID, &u.Email); err != nil {
+//		return nil, errors.ClassifyDB(err) // DB_NO_ROWS, 404 for sql.ErrNoRows
+//	}
+func ClassifyDB(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	state := sqlState(err)
+	info := dbKinds[dbKindOf(err, state)]
+	e := wrapWithOptions(err, WrapOptions{Code: info.code, Message: info.message, Severity: info.severity}, 1)
+	e.Retryable = info.retryable
+	e.HTTPStatusCode = info.httpStatus
+	if state != "" {
+		e.WithContext(SQLStateKey, state)
+	}
+	return e
+}
+
+// dbKindOf returns the kind of err, consulting the registered matchers first.
+func dbKindOf(err error, state string) DBErrorKind {
+	dbMatchersMu.RLock()
+	matchers := dbMatchers
+	dbMatchersMu.RUnlock()
+	for _, m := range matchers {
+		if kind := m(err); kind != DBUnknown {
+			return kind
+		}
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return DBNoRows
+	case errors.Is(err, sql.ErrConnDone), errors.Is(err, driver.ErrBadConn):
+		return DBConnection
+	case state == "23505":
+		return DBUniqueViolation
+	case state == "23503":
+		return DBForeignKeyViolation
+	case strings.HasPrefix(state, "23"):
+		return DBConstraintViolation
+	case state == "40P01", state == "40001":
+		return DBDeadlock
+	case strings.HasPrefix(state, "08"):
+		return DBConnection
+	}
+	return DBUnknown
+}
+
+// sqlState returns the SQLSTATE of the first error in the chain of err implementing
+// SQLState() string, or an empty string.
+func sqlState(err error) string {
+	var s interface{ SQLState() string }
+	if errors.As(err, &s) {
+		return s.SQLState()
+	}
+	return ""
+}
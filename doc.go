@@ -15,7 +15,7 @@
 // • JSON Serialization: Built-in JSON marshaling for API responses and logging
 // • Retry Logic: Built-in support for retryable errors
 // • Interface-Based: Type-safe error handling through well-defined interfaces
-// • Zero Dependencies: Uses only Go standard library
+// • Zero Dependencies: Uses only Go standard library (go-timecache is opt-in with -tags timecache)
 // • High Performance: Minimal overhead with efficient memory usage
 //
 // # Quick Start
@@ -90,7 +90,7 @@
 //		if err != nil {
 //			apiErr, ok := err.(*errors.Error)
 //			if ok {
-//				http.Error(w, apiErr.UserMessage(), getStatusCode(apiErr))
+//				http.Error(w, apiErr.UserMessage(), errors.HTTPStatus(apiErr))
 //				log.Error("API Error", "error", apiErr.Error(), "code", apiErr.ErrorCode())
 //			} else {
 //				http.Error(w, "Internal server error", 500)
@@ -0,0 +1,231 @@
+// errctx.go: context.Context propagation for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Context keys recorded by WrapCtx about the state of the context.Context.
+const (
+	ContextErrKey        = "ctx_err"            // ctx.Err() text, when ctx is canceled or its deadline exceeded
+	DeadlineRemainingKey = "deadline_remaining" // time left before the deadline of ctx, negative once passed
+)
+
+// errorContextKey and errorsContextKey are unexported to avoid collisions with other packages.
+type (
+	errorContextKey  struct{}
+	errorsContextKey struct{}
+	traceContextKey  struct{}
+)
+
+// IntoContext returns a copy of ctx carrying err, so that middleware, deferred cleanup
+// and logging hooks can retrieve it with FromContext.
+//
+// Example:
+//
+//	ctx = errors.IntoContext(ctx, err)
+//	// later, in a deferred logging hook
+//	if err, ok := errors.FromContext(ctx); ok {
+//		log.Error("request failed", "err", err)
+//	}
+func IntoContext(ctx context.Context, err *Error) context.Context {
+	return context.WithValue(ctx, errorContextKey{}, err)
+}
+
+// FromContext returns the error stored in ctx by IntoContext.
+// The boolean is false if no error was stored.
+func FromContext(ctx context.Context) (*Error, bool) {
+	err, ok := ctx.Value(errorContextKey{}).(*Error)
+	return err, ok && err != nil
+}
+
+// AppendToContext returns a copy of ctx whose error list, retrieved with AllFromContext,
+// is the list of ctx followed by err. The list of ctx itself is not modified, so sibling
+// contexts derived from the same parent do not see each other's errors.
+func AppendToContext(ctx context.Context, err *Error) context.Context {
+	prev, _ := ctx.Value(errorsContextKey{}).([]*Error)
+	next := make([]*Error, len(prev), len(prev)+1)
+	copy(next, prev)
+	next = append(next, err)
+	return context.WithValue(ctx, errorsContextKey{}, next)
+}
+
+// AllFromContext returns the errors accumulated in ctx by AppendToContext, in order.
+// The returned slice is a copy and can be modified without affecting ctx.
+func AllFromContext(ctx context.Context) []*Error {
+	errs, _ := ctx.Value(errorsContextKey{}).([]*Error)
+	if len(errs) == 0 {
+		return nil
+	}
+	out := make([]*Error, len(errs))
+	copy(out, errs)
+	return out
+}
+
+// ContextExtractor returns request-scoped metadata, such as trace or request IDs, to copy
+// from a context.Context into an error's Context. It may return nil.
+type ContextExtractor func(ctx context.Context) map[string]interface{}
+
+var (
+	contextExtractorsMu sync.RWMutex
+	contextExtractors   []ContextExtractor
+)
+
+// RegisterContextExtractor adds fn to the extractors run by WithRequestContext. Extractors
+// run in registration order. It is safe for concurrent use, but is meant to be called at
+// startup, typically by the package that stores the metadata in the context.
+//
+// Example:
+//
+//	func init() {
+//		errors.RegisterContextExtractor(func(ctx context.Context) map[string]interface{} {
+//			if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+//				return map[string]interface{}{"request_id": id}
+//			}
+//			return nil
+//		})
+//	}
+func RegisterContextExtractor(fn ContextExtractor) {
+	if fn == nil {
+		return
+	}
+	contextExtractorsMu.Lock()
+	defer contextExtractorsMu.Unlock()
+	contextExtractors = append(contextExtractors, fn)
+}
+
+// WithRequestContext returns err enriched with the metadata returned by the registered
+// extractors for ctx; keys the error already has are kept, so values set at the error site
+// win. err itself is never modified, since handlers may return shared errors: an *Error is
+// cloned, a sentinel (see NewSentinel) is wrapped like WrapPreserve so that errors.Is still
+// matches it, and any other error is wrapped keeping the code of the first *Error in its
+// chain. It returns nil if err is nil.
+//
+// Example:
+//
+//	if err := svc.Handle(ctx, req); err != nil {
+//		return errors.WithRequestContext(ctx, err)
+//	}
+func WithRequestContext(ctx context.Context, err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	switch inner, ok := err.(*Error); {
+	case ok && !inner.sentinel:
+		e = inner.Clone()
+	case ok:
+		e = wrapWithOptions(err, WrapOptions{Message: inner.Message, PreserveCode: true, Inherit: true}, 1)
+	default:
+		e = wrapWithOptions(err, WrapOptions{Message: err.Error(), PreserveCode: true}, 1)
+	}
+	addRequestContext(ctx, e)
+	return e
+}
+
+// addRequestContext adds the metadata of the registered extractors for ctx to e, keeping
+// the keys e already has.
+func addRequestContext(ctx context.Context, e *Error) {
+	contextExtractorsMu.RLock()
+	extractors := contextExtractors
+	contextExtractorsMu.RUnlock()
+
+	for _, extract := range extractors {
+		for k, v := range extract(ctx) {
+			if _, exists := e.Context[k]; !exists {
+				e.WithContext(k, v)
+			}
+		}
+	}
+}
+
+// TraceExtractor returns the trace and span IDs of the operation carried by ctx, or empty
+// strings if there is none.
+type TraceExtractor func(ctx context.Context) (traceID, spanID string)
+
+var (
+	traceExtractorMu sync.RWMutex
+	traceExtractor   TraceExtractor = traceFromContext
+)
+
+// traceIDs is the value stored by ContextWithTrace.
+type traceIDs struct {
+	traceID, spanID string
+}
+
+// ContextWithTrace returns a copy of ctx carrying the trace and span IDs read by WrapCtx
+// with the default TraceExtractor. Services without a tracing SDK can set them from
+// incoming headers such as traceparent or X-Request-ID.
+func ContextWithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, traceIDs{traceID: traceID, spanID: spanID})
+}
+
+// traceFromContext is the default TraceExtractor, reading the IDs set by ContextWithTrace.
+func traceFromContext(ctx context.Context) (string, string) {
+	ids, _ := ctx.Value(traceContextKey{}).(traceIDs)
+	return ids.traceID, ids.spanID
+}
+
+// SetTraceExtractor replaces the function used by WrapCtx to read trace and span IDs,
+// typically to read them from a tracing SDK. Pass nil to restore the default, which reads
+// the IDs set by ContextWithTrace.
+//
+// Example:
+//
+//	errors.SetTraceExtractor(func(ctx context.Context) (string, string) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return "", ""
+//		}
+//		return sc.TraceID().String(), sc.SpanID().String()
+//	})
+func SetTraceExtractor(fn TraceExtractor) {
+	if fn == nil {
+		fn = traceFromContext
+	}
+	traceExtractorMu.Lock()
+	defer traceExtractorMu.Unlock()
+	traceExtractor = fn
+}
+
+// WrapCtx wraps err like Wrap and records the trace and span IDs of ctx, read by the
+// TraceExtractor, along with the metadata of the registered context extractors (see
+// WithRequestContext). It also records the state of ctx, to help diagnose timeout
+// cascades: the text of ctx.Err() under ContextErrKey once ctx is done, and the time left
+// before its deadline under DeadlineRemainingKey. The error is marked retryable when ctx
+// exceeded its deadline or err is a context.DeadlineExceeded. All of it is recorded before
+// the creation hooks run, and options passed to WrapCtx override it.
+//
+// Example:
+//
+//	if err := repo.Save(ctx, order); err != nil {
+//		return errors.WrapCtx(ctx, err, "ORDER_SAVE_FAILED", "Failed to save order")
+//	}
+func WrapCtx(ctx context.Context, err error, code ErrorCode, message string, opts ...Option) *Error {
+	traceExtractorMu.RLock()
+	extract := traceExtractor
+	traceExtractorMu.RUnlock()
+
+	contextual := func(e *Error) {
+		e.TraceID, e.SpanID = extract(ctx)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			e.WithContext(ContextErrKey, ctxErr.Error())
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			e.WithContext(DeadlineRemainingKey, time.Until(deadline))
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+			e.Retryable = true
+		}
+		addRequestContext(ctx, e)
+	}
+	return wrapWithOptions(err, WrapOptions{Code: code, Message: message}, 1, append([]Option{contextual}, opts...)...)
+}
@@ -0,0 +1,180 @@
+// cbor.go: CBOR encoding for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errorcodec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// CBOR major types.
+const (
+	cborUint   = 0
+	cborNegint = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// appendCBOR appends the CBOR encoding of the normalized value v to b, with lengths and
+// integers in their shortest form as required for deterministic encoding.
+func appendCBOR(b []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case nil:
+		return append(b, 0xf6)
+	case bool:
+		if x {
+			return append(b, 0xf5)
+		}
+		return append(b, 0xf4)
+	case int64:
+		if x < 0 {
+			return appendCBORHead(b, cborNegint, uint64(^x))
+		}
+		return appendCBORHead(b, cborUint, uint64(x))
+	case uint64:
+		return appendCBORHead(b, cborUint, x)
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xfb), math.Float64bits(x))
+	case string:
+		b = appendCBORHead(b, cborText, uint64(len(x)))
+		return append(b, x...)
+	case []interface{}:
+		b = appendCBORHead(b, cborArray, uint64(len(x)))
+		for _, item := range x {
+			b = appendCBOR(b, item)
+		}
+		return b
+	case map[string]interface{}:
+		b = appendCBORHead(b, cborMap, uint64(len(x)))
+		for _, k := range sortedKeys(x) {
+			b = appendCBOR(b, k)
+			b = appendCBOR(b, x[k])
+		}
+		return b
+	}
+	panic(fmt.Sprintf("errorcodec: unnormalized %T", v))
+}
+
+// appendCBORHead appends the initial byte of an item of major type major with argument n.
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), n)
+}
+
+// cbor decodes the next CBOR item. Byte strings are decoded as strings, tags are skipped
+// in favour of the item they enclose, and indefinite-length items are rejected.
+func (d *decoder) cbor() (interface{}, error) {
+	head, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	major, info := head[0]>>5, head[0]&0x1f
+	if major == cborSimple {
+		return d.cborSimple(info)
+	}
+
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info <= 27:
+		if n, err = d.uint(1 << (info - 24)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("errorcodec: unsupported CBOR additional information %d", info)
+	}
+
+	switch major {
+	case cborUint:
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, nil
+	case cborNegint:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("errorcodec: CBOR negative integer overflows int64")
+		}
+		return -1 - int64(n), nil
+	case cborBytes, cborText:
+		b, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case cborArray:
+		return d.decodeArray(n, d.cbor)
+	case cborMap:
+		return d.decodeMap(n, d.cbor)
+	}
+	// cborTag
+	if err := d.enter(1, 1); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+	return d.cbor()
+}
+
+// cborSimple decodes a simple value or floating-point number.
+func (d *decoder) cborSimple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		n, err := d.uint(2)
+		return halfToFloat64(uint16(n)), err
+	case 26:
+		n, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 27:
+		n, err := d.uint(8)
+		return math.Float64frombits(n), err
+	}
+	return nil, fmt.Errorf("errorcodec: unsupported CBOR simple value %d", info)
+}
+
+// halfToFloat64 converts an IEEE 754 half-precision number.
+func halfToFloat64(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
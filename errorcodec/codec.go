@@ -0,0 +1,270 @@
+// codec.go: Binary encodings for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+// Package errorcodec encodes errors.Error values in compact binary formats, MessagePack
+// and CBOR, for services whose transports are too constrained for JSON. The document is
+// the one of errors.Error.ToMap, so it carries the same keys as the JSON representation
+// and is decoded with errors.FromMap. The encoders are implemented in the package and
+// have no dependencies.
+//
+// Example:
+//
+//	payload, err := errorcodec.Marshal(appErr, errorcodec.MsgPack)
+//	...
+//	remote, err := errorcodec.Unmarshal(payload, errorcodec.MsgPack)
+package errorcodec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/agilira/go-errors"
+)
+
+// Format selects a binary encoding.
+type Format int
+
+const (
+	// MsgPack is the MessagePack format (https://msgpack.org).
+	MsgPack Format = iota
+	// CBOR is the Concise Binary Object Representation (RFC 8949).
+	CBOR
+)
+
+// String returns the name of the format.
+func (f Format) String() string {
+	switch f {
+	case MsgPack:
+		return "msgpack"
+	case CBOR:
+		return "cbor"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// maxNesting bounds the nesting of decoded documents, so that hostile input cannot
+// exhaust the stack.
+const maxNesting = 100
+
+// Marshal encodes e in format. Map keys are written in sorted order, so the encoding is
+// deterministic. Context values other than strings, booleans, numbers, maps and slices
+// are converted through their JSON representation.
+func Marshal(e *errors.Error, format Format) ([]byte, error) {
+	if e == nil {
+		return nil, fmt.Errorf("errorcodec: cannot marshal nil error")
+	}
+	v, err := normalize(e.ToMap())
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case MsgPack:
+		return appendMsgpack(nil, v), nil
+	case CBOR:
+		return appendCBOR(nil, v), nil
+	}
+	return nil, fmt.Errorf("errorcodec: unknown format %v", format)
+}
+
+// Unmarshal decodes an error encoded by Marshal in format.
+func Unmarshal(data []byte, format Format) (*errors.Error, error) {
+	d := &decoder{data: data}
+	var v interface{}
+	var err error
+	switch format {
+	case MsgPack:
+		v, err = d.msgpack()
+	case CBOR:
+		v, err = d.cbor()
+	default:
+		return nil, fmt.Errorf("errorcodec: unknown format %v", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(d.data) > 0 {
+		return nil, fmt.Errorf("errorcodec: %d trailing bytes after %v document", len(d.data), format)
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("errorcodec: %v document is a %T, not a map", format, v)
+	}
+	return errors.FromMap(m)
+}
+
+// Encode writes e to w in format.
+func Encode(w io.Writer, e *errors.Error, format Format) error {
+	data, err := Marshal(e, format)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Decode reads r to its end and decodes the error it holds in format.
+func Decode(r io.Reader, format Format) (*errors.Error, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(data, format)
+}
+
+// normalize converts v to the types handled by the encoders: nil, bool, int64, uint64,
+// float64, string, []interface{} and map[string]interface{}.
+func normalize(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case nil, bool, string, int64, uint64, float64:
+		return x, nil
+	case int:
+		return int64(x), nil
+	case int8:
+		return int64(x), nil
+	case int16:
+		return int64(x), nil
+	case int32:
+		return int64(x), nil
+	case uint:
+		return uint64(x), nil
+	case uint8:
+		return uint64(x), nil
+	case uint16:
+		return uint64(x), nil
+	case uint32:
+		return uint64(x), nil
+	case float32:
+		return float64(x), nil
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, item := range x {
+			n, err := normalize(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = n
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			n, err := normalize(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = n
+		}
+		return out, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("errorcodec: cannot encode %T: %w", v, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return normalizeJSON(generic)
+}
+
+// normalizeJSON converts the json.Number values of a decoded JSON document.
+func normalizeJSON(v interface{}) (interface{}, error) {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		return n.Float64()
+	}
+	return normalize(v)
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// decoder reads a document from data, which it consumes as it goes.
+type decoder struct {
+	data  []byte
+	depth int
+}
+
+// next returns the next n bytes.
+func (d *decoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+// enter records one more level of nesting and checks that each of count items can still
+// be present in the remaining input, so that a forged length does not allocate much.
+func (d *decoder) enter(count, itemSize uint64) error {
+	d.depth++
+	if d.depth > maxNesting {
+		return fmt.Errorf("errorcodec: document nested deeper than %d levels", maxNesting)
+	}
+	if count > uint64(len(d.data))/itemSize {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// leave records the end of a nested array or map.
+func (d *decoder) leave() {
+	d.depth--
+}
+
+// decodeMap decodes the count entries of a map with item decoding each key and value.
+func (d *decoder) decodeMap(count uint64, item func() (interface{}, error)) (interface{}, error) {
+	if err := d.enter(count, 2); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+	m := make(map[string]interface{}, count)
+	for i := uint64(0); i < count; i++ {
+		k, err := item()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("errorcodec: map key is a %T, not a string", k)
+		}
+		if m[key], err = item(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// decodeArray decodes the count items of an array with item.
+func (d *decoder) decodeArray(count uint64, item func() (interface{}, error)) (interface{}, error) {
+	if err := d.enter(count, 1); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+	a := make([]interface{}, count)
+	for i := range a {
+		var err error
+		if a[i], err = item(); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
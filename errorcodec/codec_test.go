@@ -0,0 +1,130 @@
+// codec_test.go: Tests for the errorcodec package of the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errorcodec
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agilira/go-errors"
+)
+
+func TestRoundTrip(t *testing.T) {
+	inner := errors.Wrap(stderrors.New("connection reset"), "DB_ERROR", "Query failed")
+	orig := errors.Wrap(inner, "SERVICE_ERROR", "Load failed").
+		WithUserMessage("Please retry").
+		WithContext("attempt", 3).
+		WithContext("ratio", 0.5).
+		WithContext("tags", []string{"a", "b"}).
+		WithContext("offset", -40000).
+		AsRetryable().
+		WithRetryAfter(1500 * time.Millisecond)
+
+	for _, format := range []Format{MsgPack, CBOR} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, orig, format); err != nil {
+			t.Fatalf("%v: Encode failed: %v", format, err)
+		}
+		if jsonData, _ := json.Marshal(orig); buf.Len() >= len(jsonData) {
+			t.Errorf("%v: Expected a document smaller than JSON, got %d >= %d bytes", format, buf.Len(), len(jsonData))
+		}
+		decoded, err := Decode(&buf, format)
+		if err != nil {
+			t.Fatalf("%v: Decode failed: %v", format, err)
+		}
+		if decoded.Code != orig.Code || decoded.Message != orig.Message || decoded.UserMsg != "Please retry" ||
+			!decoded.Retryable || decoded.RetryAfter != 1500*time.Millisecond || !decoded.Timestamp.Equal(orig.Timestamp) {
+			t.Errorf("%v: Expected fields to round-trip, got %+v", format, decoded)
+		}
+		want := map[string]interface{}{
+			"attempt": int64(3),
+			"ratio":   0.5,
+			"tags":    []interface{}{"a", "b"},
+			"offset":  int64(-40000),
+		}
+		if !reflect.DeepEqual(decoded.Context, want) {
+			t.Errorf("%v: Expected context %v, got %v", format, want, decoded.Context)
+		}
+		if ce, ok := decoded.Cause.(*errors.Error); !ok || ce.Code != "DB_ERROR" || ce.Cause.Error() != "connection reset" {
+			t.Errorf("%v: Expected cause chain to round-trip, got %v", format, decoded.Cause)
+		}
+	}
+}
+
+func TestEncodings(t *testing.T) {
+	v := map[string]interface{}{"a": int64(-1), "b": []interface{}{true, nil, uint64(math.MaxUint64)}, "c": strings.Repeat("x", 40)}
+	msgpack := appendMsgpack(nil, v)
+	wantMsgpack := append([]byte{0x83, 0xa1, 'a', 0xff, 0xa1, 'b', 0x93, 0xc3, 0xc0, 0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xa1, 'c', 0xd9, 40},
+		strings.Repeat("x", 40)...)
+	if !bytes.Equal(msgpack, wantMsgpack) {
+		t.Errorf("Expected MessagePack % x, got % x", wantMsgpack, msgpack)
+	}
+	cbor := appendCBOR(nil, v)
+	wantCBOR := append([]byte{0xa3, 0x61, 'a', 0x20, 0x61, 'b', 0x83, 0xf5, 0xf6, 0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x61, 'c', 0x78, 40},
+		strings.Repeat("x", 40)...)
+	if !bytes.Equal(cbor, wantCBOR) {
+		t.Errorf("Expected CBOR % x, got % x", wantCBOR, cbor)
+	}
+
+	for name, tc := range map[string]struct {
+		data   []byte
+		format Format
+		want   interface{}
+	}{
+		"cbor half":       {[]byte{0xf9, 0x3c, 0x00}, CBOR, 1.0},
+		"cbor tag":        {[]byte{0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0}, CBOR, int64(1363896240)},
+		"cbor bytes":      {[]byte{0x42, 'h', 'i'}, CBOR, "hi"},
+		"cbor negint":     {[]byte{0x39, 0x01, 0xf3}, CBOR, int64(-500)},
+		"msgpack float32": {[]byte{0xca, 0x3f, 0xc0, 0x00, 0x00}, MsgPack, 1.5},
+		"msgpack int16":   {[]byte{0xd1, 0xfe, 0x0c}, MsgPack, int64(-500)},
+		"msgpack bin8":    {[]byte{0xc4, 0x02, 'h', 'i'}, MsgPack, "hi"},
+		"msgpack str16":   {[]byte{0xda, 0x00, 0x02, 'h', 'i'}, MsgPack, "hi"},
+	} {
+		d := &decoder{data: tc.data}
+		var got interface{}
+		var err error
+		if tc.format == CBOR {
+			got, err = d.cbor()
+		} else {
+			got, err = d.msgpack()
+		}
+		if err != nil || got != tc.want || len(d.data) != 0 {
+			t.Errorf("%s: Expected %v, got %v (%v)", name, tc.want, got, err)
+		}
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	data, _ := Marshal(errors.New("NOT_FOUND", "Missing"), CBOR)
+	if _, err := Unmarshal(data[:len(data)-1], CBOR); err == nil {
+		t.Error("Expected truncated document to fail")
+	}
+	if _, err := Unmarshal(append(data, 0), CBOR); err == nil {
+		t.Error("Expected trailing bytes to fail")
+	}
+	if _, err := Unmarshal([]byte{0x01}, MsgPack); err == nil {
+		t.Error("Expected a non-map document to fail")
+	}
+	if _, err := Unmarshal([]byte{0xdd, 0xff, 0xff, 0xff, 0xff}, MsgPack); err == nil {
+		t.Error("Expected a forged array length to fail")
+	}
+	if _, err := Unmarshal(bytes.Repeat([]byte{0x91}, 200), MsgPack); err == nil {
+		t.Error("Expected deeply nested document to fail")
+	}
+	if _, err := Unmarshal([]byte{0x81, 0x01, 0x01}, MsgPack); err == nil {
+		t.Error("Expected a non-string map key to fail")
+	}
+	if _, err := Marshal(errors.New("X", "x"), Format(9)); err == nil {
+		t.Error("Expected unknown format to fail")
+	}
+}
@@ -0,0 +1,196 @@
+// msgpack.go: MessagePack encoding for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errorcodec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// appendMsgpack appends the MessagePack encoding of the normalized value v to b, using
+// the smallest representation of each integer, string, array and map.
+func appendMsgpack(b []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if x {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case int64:
+		return appendMsgpackInt(b, x)
+	case uint64:
+		if x <= math.MaxInt64 {
+			return appendMsgpackInt(b, int64(x))
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), x)
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(x))
+	case string:
+		b = appendMsgpackHead(b, uint64(len(x)), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		return append(b, x...)
+	case []interface{}:
+		b = appendMsgpackHead(b, uint64(len(x)), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range x {
+			b = appendMsgpack(b, item)
+		}
+		return b
+	case map[string]interface{}:
+		b = appendMsgpackHead(b, uint64(len(x)), 0x80, 16, 0, 0xde, 0xdf)
+		for _, k := range sortedKeys(x) {
+			b = appendMsgpack(b, k)
+			b = appendMsgpack(b, x[k])
+		}
+		return b
+	}
+	panic(fmt.Sprintf("errorcodec: unnormalized %T", v))
+}
+
+// appendMsgpackInt appends an integer as a fixint or the smallest sized integer.
+func appendMsgpackInt(b []byte, x int64) []byte {
+	switch {
+	case x >= 0 && x <= 0x7f, x >= -32 && x < 0:
+		return append(b, byte(x))
+	case x >= 0 && x <= math.MaxUint8:
+		return append(b, 0xcc, byte(x))
+	case x >= 0 && x <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(x))
+	case x >= 0 && x <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(x))
+	case x >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(x))
+	case x >= math.MinInt8:
+		return append(b, 0xd0, byte(x))
+	case x >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(x))
+	case x >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(x))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(x))
+}
+
+// appendMsgpackHead appends the header of a string, array or map of length n: the fixed
+// form fix|n below fixLimit, then the 8, 16 or 32-bit length forms. A zero code8 means
+// the type has no 8-bit form.
+func appendMsgpackHead(b []byte, n uint64, fix byte, fixLimit uint64, code8, code16, code32 byte) []byte {
+	switch {
+	case n < fixLimit:
+		return append(b, fix|byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		return append(b, code8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, code32), uint32(n))
+}
+
+// msgpack decodes the next MessagePack value. Binary data is decoded as a string, and
+// extension types are rejected.
+func (d *decoder) msgpack() (interface{}, error) {
+	head, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := head[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0:
+		return d.msgpackString(uint64(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return d.decodeArray(uint64(c&0x0f), d.msgpack)
+	case c&0xf0 == 0x80:
+		return d.decodeMap(uint64(c&0x0f), d.msgpack)
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xca:
+		n, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.uint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce:
+		n, err := d.uint(1 << (c - 0xcc))
+		return int64(n), err
+	case 0xcf:
+		n, err := d.uint(8)
+		if n <= math.MaxInt64 {
+			return int64(n), err
+		}
+		return n, err
+	case 0xd0:
+		n, err := d.uint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := d.uint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := d.uint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := d.uint(8)
+		return int64(n), err
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.msgpackString(n)
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.msgpackString(n)
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n, d.msgpack)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n, d.msgpack)
+	}
+	return nil, fmt.Errorf("errorcodec: unsupported MessagePack type 0x%02x", c)
+}
+
+// msgpackString decodes a string or binary payload of n bytes.
+func (d *decoder) msgpackString(n uint64) (interface{}, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes.
+func (d *decoder) uint(size int) (uint64, error) {
+	b, err := d.next(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
@@ -0,0 +1,150 @@
+// errorgroup.go: Error aggregation for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxStringErrors is the number of errors shown by ErrorGroup.String before truncating.
+const maxStringErrors = 3
+
+// ErrorGroup collects several errors into a single error value.
+// The zero value is an empty group ready to use.
+type ErrorGroup struct {
+	Errors []error
+}
+
+// NewErrorGroup creates a group containing the given non-nil errors.
+func NewErrorGroup(errs ...error) *ErrorGroup {
+	g := &ErrorGroup{}
+	for _, err := range errs {
+		g.Add(err)
+	}
+	return g
+}
+
+// Add appends err to the group and returns the group for chaining. Nil errors are ignored.
+func (g *ErrorGroup) Add(err error) *ErrorGroup {
+	if err != nil {
+		g.Errors = append(g.Errors, err)
+	}
+	return g
+}
+
+// Len returns the number of errors in the group.
+func (g *ErrorGroup) Len() int {
+	return len(g.Errors)
+}
+
+// ErrorOrNil returns the group as an error, or nil if it is empty.
+func (g *ErrorGroup) ErrorOrNil() error {
+	if g == nil || len(g.Errors) == 0 {
+		return nil
+	}
+	return g
+}
+
+// Error implements the error interface, joining the messages of all errors with "; ".
+func (g *ErrorGroup) Error() string {
+	msgs := make([]string, len(g.Errors))
+	for i, err := range g.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the grouped errors, so errors.Is and errors.As inspect each of them.
+func (g *ErrorGroup) Unwrap() []error {
+	return g.Errors
+}
+
+// String implements fmt.Stringer with a compact, log-friendly representation showing at most
+// three errors, e.g. "4 errors: [VALIDATION_ERROR: field required], [DATABASE_ERROR: timeout],
+// [NETWORK_ERROR: refused] and 1 more".
+func (g *ErrorGroup) String() string {
+	var b strings.Builder
+	b.WriteString(countErrors(len(g.Errors)))
+	for i, err := range g.Errors {
+		if i == maxStringErrors {
+			b.WriteString(" and ")
+			b.WriteString(strconv.Itoa(len(g.Errors) - maxStringErrors))
+			b.WriteString(" more")
+			break
+		}
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteByte('[')
+		if e, ok := err.(*Error); ok {
+			b.WriteString(string(e.Code))
+			b.WriteString(": ")
+			b.WriteString(e.Message)
+		} else {
+			b.WriteString(err.Error())
+		}
+		b.WriteByte(']')
+	}
+	return b.String()
+}
+
+// Summary returns the number of errors followed by a count per code, most frequent first,
+// e.g. "3 errors (2×VALIDATION_ERROR, 1×DATABASE_ERROR)".
+// Errors that are not *Error are counted under DefaultErrorCode.
+func (g *ErrorGroup) Summary() string {
+	if len(g.Errors) == 0 {
+		return countErrors(0)
+	}
+	counts := make(map[ErrorCode]int)
+	var order []ErrorCode
+	for _, err := range g.Errors {
+		code := DefaultErrorCode
+		if e, ok := err.(*Error); ok {
+			code = e.Code
+		}
+		if counts[code] == 0 {
+			order = append(order, code)
+		}
+		counts[code]++
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return counts[order[i]] > counts[order[j]]
+	})
+
+	parts := make([]string, len(order))
+	for i, code := range order {
+		parts[i] = strconv.Itoa(counts[code]) + "×" + string(code)
+	}
+	return countErrors(len(g.Errors)) + " (" + strings.Join(parts, ", ") + ")"
+}
+
+// Verbose returns a multi-line representation listing every error on its own numbered line.
+func (g *ErrorGroup) Verbose() string {
+	var b strings.Builder
+	b.WriteString(countErrors(len(g.Errors)))
+	if len(g.Errors) > 0 {
+		b.WriteByte(':')
+	}
+	for i, err := range g.Errors {
+		b.WriteString("\n  ")
+		b.WriteString(strconv.Itoa(i + 1))
+		b.WriteString(". ")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func countErrors(n int) string {
+	if n == 1 {
+		return "1 error"
+	}
+	return strconv.Itoa(n) + " errors"
+}
@@ -0,0 +1,75 @@
+// errorid.go: Support reference IDs for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrorIDPrefix is the prefix of the IDs generated by NewErrorID.
+const ErrorIDPrefix = "ERR-"
+
+// autoErrorID is set by SetAutoErrorID.
+var autoErrorID atomic.Bool
+
+// NewErrorID returns a short random reference such as "ERR-7F3A2C", drawn from
+// crypto/rand so that references cannot be guessed. With 24 random bits it identifies an
+// incident together with its timestamp, not across the whole history of a service.
+func NewErrorID() string {
+	var b [3]byte
+	_, _ = rand.Read(b[:])
+	return ErrorIDPrefix + strings.ToUpper(hex.EncodeToString(b[:]))
+}
+
+// SetAutoErrorID controls whether every new error gets an ErrorID on creation. A wrapper
+// reuses the ID of the first *Error in its chain that has one, so the whole chain shares
+// one reference. It is disabled by default.
+func SetAutoErrorID(enabled bool) {
+	autoErrorID.Store(enabled)
+}
+
+// WithErrorID sets a support reference generated by NewErrorID, unless the error already
+// has one, and returns the error for chaining. The ID is serialized as "error_id", added
+// to PublicError and appended to the user message, so that support teams can find the
+// server logs of the error a user reports.
+//
+// Example:
+//
+//	err := errors.Internal(dbErr, "Checkout failed").WithUserMessage("Payment could not be processed.").WithErrorID()
+//	err.UserMessage() // "Payment could not be processed. (reference: ERR-7F3A2C)"
+func (e *Error) WithErrorID() *Error {
+	if e.ErrorID == "" {
+		e.ErrorID = NewErrorID()
+	}
+	return e
+}
+
+// assignErrorID sets the ErrorID of a new error when SetAutoErrorID is enabled.
+func (e *Error) assignErrorID() {
+	if !autoErrorID.Load() || e.ErrorID != "" {
+		return
+	}
+	var inner *Error
+	if e.Cause != nil && errors.As(e.Cause, &inner) && inner.ErrorID != "" {
+		e.ErrorID = inner.ErrorID
+		return
+	}
+	e.ErrorID = NewErrorID()
+}
+
+// withErrorIDReference appends the reference of the error to a user message, unless the
+// message already contains it.
+func (e *Error) withErrorIDReference(msg string) string {
+	if e.ErrorID == "" || strings.Contains(msg, e.ErrorID) {
+		return msg
+	}
+	return msg + " (reference: " + e.ErrorID + ")"
+}
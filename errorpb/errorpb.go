@@ -0,0 +1,404 @@
+// errorpb.go: Protobuf conversion for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+// Package errorpb converts errors.Error values to and from the protobuf message defined
+// in error.proto, so that services talking over gRPC or Kafka with protobuf payloads can
+// carry structured errors natively. The package has no dependencies: Error implements the
+// protobuf wire format by hand with Marshal and Unmarshal, and is byte-compatible with
+// the code generated from error.proto in any language.
+//
+// Example:
+//
+//	payload, _ := errorpb.ToProto(err).Marshal()
+//	...
+//	var m errorpb.Error
+//	if err := m.Unmarshal(payload); err == nil {
+//		remote, _ := errorpb.FromProto(&m)
+//	}
+package errorpb
+
+import (
+	"encoding/binary"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/agilira/go-errors"
+)
+
+// Error is the Go form of the agilira.errors.v1.Error message. An Error with an empty
+// Code stands for a plain error cause and only carries its Message.
+type Error struct {
+	Code               string
+	Message            string
+	Severity           string
+	UserMessage        string
+	Category           string
+	Field              string
+	Value              string
+	Retryable          bool
+	RetryAfterNanos    int64
+	HTTPStatus         int32
+	TimestampUnixNanos int64
+	Context            map[string]string
+	TraceID            string
+	SpanID             string
+	Stack              string
+	Cause              *Error
+	Op                 string
+	ErrorID            string
+}
+
+// Field numbers of error.proto.
+const (
+	fieldCode = iota + 1
+	fieldMessage
+	fieldSeverity
+	fieldUserMessage
+	fieldCategory
+	fieldField
+	fieldValue
+	fieldRetryable
+	fieldRetryAfterNanos
+	fieldHTTPStatus
+	fieldTimestampUnixNanos
+	fieldContext
+	fieldTraceID
+	fieldSpanID
+	fieldStack
+	fieldCause
+	fieldOp
+	fieldErrorID
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// flatDepth is the depth at which unmarshal skips nested messages: it is used for map
+// entries and for the cause decoded at errors.GetMaxCauseDepth, which is kept as text.
+const flatDepth = math.MaxInt
+
+// errTruncated is returned by Unmarshal for a message that ends in the middle of a field.
+var errTruncated = stderrors.New("errorpb: truncated message")
+
+// ToProto converts e to its protobuf form. The values are those of e.ToMap: sensitive
+// values are redacted, the user message is rendered and the stack is kept as text.
+// Context values are formatted with fmt.Sprint, since the message carries them as
+// strings. Causes are converted recursively up to errors.GetMaxCauseDepth levels; a cause
+// that is not an *errors.Error keeps only its message. ToProto returns nil if e is nil.
+func ToProto(e *errors.Error) *Error {
+	return toProto(e, 0)
+}
+
+func toProto(e *errors.Error, depth int) *Error {
+	if e == nil {
+		return nil
+	}
+	m := e.ToMap()
+	pb := &Error{
+		Code:               string(e.Code),
+		Message:            stringValue(m, "message"),
+		Severity:           stringValue(m, "severity"),
+		UserMessage:        stringValue(m, "user_msg"),
+		Category:           stringValue(m, "category"),
+		Field:              stringValue(m, "field"),
+		Value:              stringValue(m, "value"),
+		Retryable:          e.Retryable,
+		RetryAfterNanos:    int64(e.RetryAfter),
+		HTTPStatus:         int32(e.HTTPStatusCode),
+		TimestampUnixNanos: e.Timestamp.UnixNano(),
+		TraceID:            stringValue(m, "trace_id"),
+		SpanID:             stringValue(m, "span_id"),
+		Stack:              stringValue(m, "stack"),
+		Op:                 stringValue(m, "op"),
+		ErrorID:            stringValue(m, "error_id"),
+	}
+	if e.Timestamp.IsZero() {
+		pb.TimestampUnixNanos = 0
+	}
+	if context, ok := m["context"].(map[string]interface{}); ok {
+		pb.Context = make(map[string]string, len(context))
+		for k, v := range context {
+			pb.Context[k] = fmt.Sprint(v)
+		}
+	}
+	if ce, ok := e.Cause.(*errors.Error); ok && depth < errors.GetMaxCauseDepth() {
+		pb.Cause = toProto(ce, depth+1)
+	} else if e.Cause != nil {
+		pb.Cause = &Error{Message: e.Cause.Error()}
+	}
+	return pb
+}
+
+// FromProto rebuilds an *errors.Error from its protobuf form with errors.FromMap, so it
+// follows the same rules: the code convention is not applied and the stack stays text.
+// Context values are strings. A cause without a code becomes a plain error. FromProto
+// returns an error with code errors.ErrCodeInvalidErrorMap if m has no code. Like
+// ToProto, it rebuilds causes up to errors.GetMaxCauseDepth levels and keeps a deeper
+// cause as a plain error with its text.
+func FromProto(m *Error) (*errors.Error, error) {
+	if m == nil {
+		return nil, errors.New(errors.ErrCodeInvalidErrorMap, "nil protobuf error")
+	}
+	return errors.FromMap(m.toMap(0))
+}
+
+// toMap returns m, nested depth levels deep, in the form accepted by errors.FromMap.
+func (m *Error) toMap(depth int) map[string]interface{} {
+	out := map[string]interface{}{
+		"code":        m.Code,
+		"retryable":   m.Retryable,
+		"retry_after": m.RetryAfterNanos,
+		"http_status": int64(m.HTTPStatus),
+	}
+	for key, value := range map[string]string{
+		"message":  m.Message,
+		"severity": m.Severity,
+		"user_msg": m.UserMessage,
+		"category": m.Category,
+		"field":    m.Field,
+		"value":    m.Value,
+		"trace_id": m.TraceID,
+		"span_id":  m.SpanID,
+		"stack":    m.Stack,
+		"op":       m.Op,
+		"error_id": m.ErrorID,
+	} {
+		if value != "" {
+			out[key] = value
+		}
+	}
+	if m.TimestampUnixNanos != 0 {
+		out["timestamp"] = time.Unix(0, m.TimestampUnixNanos)
+	}
+	if len(m.Context) > 0 {
+		context := make(map[string]interface{}, len(m.Context))
+		for k, v := range m.Context {
+			context[k] = v
+		}
+		out["context"] = context
+	}
+	if m.Cause != nil {
+		if m.Cause.Code == "" || depth >= errors.GetMaxCauseDepth() {
+			out["cause"] = m.Cause.text()
+		} else {
+			out["cause"] = m.Cause.toMap(depth + 1)
+		}
+	}
+	return out
+}
+
+// text returns the text of m as a plain cause: its message, preceded by its code like
+// errors.Error.Error if it has one.
+func (m *Error) text() string {
+	if m.Code == "" {
+		return m.Message
+	}
+	return "[" + m.Code + "]: " + m.Message
+}
+
+// Marshal encodes m in the protobuf wire format. Context entries are written in key
+// order, so the encoding is deterministic.
+func (m *Error) Marshal() ([]byte, error) {
+	return m.appendTo(nil), nil
+}
+
+func (m *Error) appendTo(b []byte) []byte {
+	b = appendString(b, fieldCode, m.Code)
+	b = appendString(b, fieldMessage, m.Message)
+	b = appendString(b, fieldSeverity, m.Severity)
+	b = appendString(b, fieldUserMessage, m.UserMessage)
+	b = appendString(b, fieldCategory, m.Category)
+	b = appendString(b, fieldField, m.Field)
+	b = appendString(b, fieldValue, m.Value)
+	if m.Retryable {
+		b = appendVarint(b, fieldRetryable, 1)
+	}
+	b = appendVarint(b, fieldRetryAfterNanos, uint64(m.RetryAfterNanos))
+	b = appendVarint(b, fieldHTTPStatus, uint64(int64(m.HTTPStatus)))
+	b = appendVarint(b, fieldTimestampUnixNanos, uint64(m.TimestampUnixNanos))
+	keys := make([]string, 0, len(m.Context))
+	for k := range m.Context {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = appendString(entry, 1, k)
+		entry = appendString(entry, 2, m.Context[k])
+		b = appendBytes(b, fieldContext, entry)
+	}
+	b = appendString(b, fieldTraceID, m.TraceID)
+	b = appendString(b, fieldSpanID, m.SpanID)
+	b = appendString(b, fieldStack, m.Stack)
+	if m.Cause != nil {
+		b = appendBytes(b, fieldCause, m.Cause.appendTo(nil))
+	}
+	b = appendString(b, fieldOp, m.Op)
+	b = appendString(b, fieldErrorID, m.ErrorID)
+	return b
+}
+
+// Unmarshal decodes a message in the protobuf wire format into m, replacing its content.
+// Unknown fields are skipped, as required for forward compatibility. Causes are decoded
+// up to errors.GetMaxCauseDepth levels, like ToProto encodes them; a deeper cause is kept
+// as a plain cause carrying its text, and its own causes are skipped.
+func (m *Error) Unmarshal(data []byte) error {
+	return m.unmarshal(data, 0)
+}
+
+// unmarshal decodes a message nested depth levels deep.
+func (m *Error) unmarshal(data []byte, depth int) error {
+	*m = Error{}
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		field, wire := tag>>3, tag&7
+
+		switch wire {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+			m.setVarint(field, v)
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || size > uint64(len(data)-n) {
+				return errTruncated
+			}
+			value := data[n : n+int(size)]
+			data = data[n+int(size):]
+			if err := m.setBytes(field, value, depth); err != nil {
+				return err
+			}
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+			data = data[4:]
+		default:
+			return fmt.Errorf("errorpb: unsupported wire type %d for field %d", wire, field)
+		}
+	}
+	return nil
+}
+
+func (m *Error) setVarint(field, v uint64) {
+	switch field {
+	case fieldRetryable:
+		m.Retryable = v != 0
+	case fieldRetryAfterNanos:
+		m.RetryAfterNanos = int64(v)
+	case fieldHTTPStatus:
+		m.HTTPStatus = int32(v)
+	case fieldTimestampUnixNanos:
+		m.TimestampUnixNanos = int64(v)
+	}
+}
+
+func (m *Error) setBytes(field uint64, value []byte, depth int) error {
+	stringFields := map[uint64]*string{
+		fieldCode:        &m.Code,
+		fieldMessage:     &m.Message,
+		fieldSeverity:    &m.Severity,
+		fieldUserMessage: &m.UserMessage,
+		fieldCategory:    &m.Category,
+		fieldField:       &m.Field,
+		fieldValue:       &m.Value,
+		fieldTraceID:     &m.TraceID,
+		fieldSpanID:      &m.SpanID,
+		fieldStack:       &m.Stack,
+		fieldOp:          &m.Op,
+		fieldErrorID:     &m.ErrorID,
+	}
+	if dst, ok := stringFields[field]; ok {
+		*dst = string(value)
+		return nil
+	}
+	if depth == flatDepth {
+		return nil
+	}
+	switch field {
+	case fieldContext:
+		var entry Error
+		key, val, err := entry.decodeMapEntry(value)
+		if err != nil {
+			return err
+		}
+		if m.Context == nil {
+			m.Context = make(map[string]string)
+		}
+		m.Context[key] = val
+	case fieldCause:
+		m.Cause = &Error{}
+		if depth < errors.GetMaxCauseDepth() {
+			return m.Cause.unmarshal(value, depth+1)
+		}
+		if err := m.Cause.unmarshal(value, flatDepth); err != nil {
+			return err
+		}
+		m.Cause = &Error{Message: m.Cause.text()}
+	}
+	return nil
+}
+
+// decodeMapEntry decodes a map<string, string> entry, reusing the string fields 1 and 2.
+func (m *Error) decodeMapEntry(data []byte) (key, value string, err error) {
+	if err := m.unmarshal(data, flatDepth); err != nil {
+		return "", "", err
+	}
+	return m.Code, m.Message, nil
+}
+
+func appendTag(b []byte, field, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wire))
+}
+
+func appendVarint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendBytes(b []byte, field int, value []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// stringValue returns the string stored under key in a map returned by ToMap.
+func stringValue(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
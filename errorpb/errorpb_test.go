@@ -0,0 +1,128 @@
+// errorpb_test.go: Tests for the errorpb package of the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errorpb
+
+import (
+	"bytes"
+	stderrors "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/agilira/go-errors"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	inner := errors.Wrap(stderrors.New("connection reset"), "DB_ERROR", "Query failed").
+		WithContext("table", "users")
+	orig := errors.Wrap(inner, "SERVICE_ERROR", "Load failed").
+		WithUserMessage("Please retry").
+		WithCriticalSeverity().
+		WithContext("attempt", 3).
+		WithHTTPStatus(http.StatusServiceUnavailable).
+		WithRetryAfter(2*time.Second).
+		WithTraceID("trace-1", "span-1").
+		WithOp("users.Load").
+		WithErrorID()
+
+	data, err := ToProto(orig).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var m Error
+	if err := m.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	decoded, err := FromProto(&m)
+	if err != nil {
+		t.Fatalf("FromProto failed: %v", err)
+	}
+
+	if decoded.Code != orig.Code || decoded.Message != orig.Message || decoded.UserMsg != "Please retry" {
+		t.Errorf("Expected code and messages to round-trip, got %+v", decoded)
+	}
+	if decoded.Severity != errors.SeverityCritical || !decoded.Retryable || decoded.RetryAfter != 2*time.Second ||
+		decoded.HTTPStatusCode != http.StatusServiceUnavailable || decoded.TraceID != "trace-1" || decoded.SpanID != "span-1" || decoded.Op != "users.Load" ||
+		decoded.ErrorID != orig.ErrorID {
+		t.Errorf("Expected metadata to round-trip, got %+v", decoded)
+	}
+	if !decoded.Timestamp.Equal(orig.Timestamp) {
+		t.Errorf("Expected timestamp %v, got %v", orig.Timestamp, decoded.Timestamp)
+	}
+	if decoded.Context["attempt"] != "3" {
+		t.Errorf("Expected context values as strings, got %v", decoded.Context)
+	}
+	decodedInner, ok := decoded.Cause.(*errors.Error)
+	if !ok || decodedInner.Code != "DB_ERROR" || decodedInner.Context["table"] != "users" {
+		t.Fatalf("Expected structured cause, got %#v", decoded.Cause)
+	}
+	if decodedInner.Cause == nil || decodedInner.Cause.Error() != "connection reset" {
+		t.Errorf("Expected plain root cause, got %v", decodedInner.Cause)
+	}
+	if len(decodedInner.StackFrames()) == 0 {
+		t.Error("Expected the stack text to round-trip")
+	}
+
+	again, _ := ToProto(orig).Marshal()
+	if !bytes.Equal(data, again) {
+		t.Error("Expected deterministic encoding")
+	}
+}
+
+func TestProtoWireFormat(t *testing.T) {
+	data, _ := (&Error{Code: "X", HTTPStatus: 404, Context: map[string]string{"k": "v"}}).Marshal()
+	want := []byte{
+		0x0a, 0x01, 'X', // 1: "X"
+		0x50, 0x94, 0x03, // 10: 404
+		0x62, 0x06, 0x0a, 0x01, 'k', 0x12, 0x01, 'v', // 12: {"k": "v"}
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("Expected % x, got % x", want, data)
+	}
+
+	// Unknown fields of every wire type are skipped.
+	withUnknown := append([]byte{0xa8, 0x01, 0x07, 0xb1, 0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0xbd, 0x01, 0, 0, 0, 0}, data...)
+	var m Error
+	if err := m.Unmarshal(withUnknown); err != nil || m.Code != "X" || m.HTTPStatus != 404 || m.Context["k"] != "v" {
+		t.Errorf("Expected unknown fields to be skipped, got %+v %v", m, err)
+	}
+	if err := m.Unmarshal(data[:len(data)-1]); err == nil {
+		t.Error("Expected truncated message to fail")
+	}
+	if _, err := FromProto(&Error{Message: "no code"}); !errors.HasCode(err, errors.ErrCodeInvalidErrorMap) {
+		t.Errorf("Expected missing code to be rejected, got %v", err)
+	}
+}
+
+func TestProtoCauseDepth(t *testing.T) {
+	deep := &Error{Code: "ROOT", Message: "root"}
+	for i := 0; i < 5000; i++ {
+		deep = &Error{Code: "NESTED", Message: "nested", Cause: deep}
+	}
+	payload, _ := deep.Marshal()
+
+	var m Error
+	if err := m.Unmarshal(payload); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	depth, cause := 0, m.Cause
+	for cause != nil && cause.Code != "" {
+		depth++
+		cause = cause.Cause
+	}
+	if depth != errors.GetMaxCauseDepth() || cause == nil || cause.Message != "[NESTED]: nested" || cause.Cause != nil {
+		t.Errorf("Expected %d decoded causes and a plain truncated cause, got %d and %+v", errors.GetMaxCauseDepth(), depth, cause)
+	}
+
+	remote, err := FromProto(deep)
+	if err != nil {
+		t.Fatalf("FromProto failed: %v", err)
+	}
+	if chain := errors.Chain(remote); len(chain) != errors.GetMaxCauseDepth()+1 {
+		t.Errorf("Expected FromProto to stop at the max cause depth, got %d errors", len(chain))
+	}
+}
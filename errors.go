@@ -8,21 +8,19 @@ package errors
 
 import (
 	"time"
-
-	"github.com/agilira/go-timecache"
 )
 
 // ErrorCode represents a custom error code that can be used to categorize and identify specific types of errors.
 // Error codes should be defined as constants in your application for consistency.
 type ErrorCode string
 
-// Predefined severity levels for consistent error classification.
-// These constants can be used with WithSeverity() method for type safety.
+// Predefined severity levels for consistent error classification. See Severity for their
+// ordering.
 const (
-	SeverityCritical = "critical" // System failures, data corruption, security breaches
-	SeverityError    = "error"    // Standard errors that prevent operation completion
-	SeverityWarning  = "warning"  // Issues that don't prevent operation but need attention
-	SeverityInfo     = "info"     // Informational messages for debugging/audit trails
+	SeverityCritical Severity = "critical" // System failures, data corruption, security breaches
+	SeverityError    Severity = "error"    // Standard errors that prevent operation completion
+	SeverityWarning  Severity = "warning"  // Issues that don't prevent operation but need attention
+	SeverityInfo     Severity = "info"     // Informational messages for debugging/audit trails
 )
 
 // DefaultErrorCode is used when an empty or invalid ErrorCode is provided to constructors.
@@ -31,39 +29,97 @@ const DefaultErrorCode ErrorCode = "UNKNOWN_ERROR"
 // Error represents a structured error with comprehensive context and metadata.
 // It includes error codes, messages, stack traces, user-friendly messages, and retry information.
 type Error struct {
-	Code      ErrorCode              `json:"code"`
-	Message   string                 `json:"message"`
-	Field     string                 `json:"field,omitempty"`
-	Value     string                 `json:"value,omitempty"`
-	Context   map[string]interface{} `json:"context,omitempty"`
-	Timestamp time.Time              `json:"timestamp"`
-	Cause     error                  `json:"cause,omitempty"`
-	Severity  string                 `json:"severity"`
-	Stack     *Stacktrace            `json:"stack,omitempty"`
-	UserMsg   string                 `json:"user_msg,omitempty"`
-	Retryable bool                   `json:"retryable,omitempty"`
+	Code           ErrorCode              `json:"code"`
+	Category       string                 `json:"category,omitempty"`
+	Op             string                 `json:"op,omitempty"`
+	Message        string                 `json:"message"`
+	Field          string                 `json:"field,omitempty"`
+	Value          string                 `json:"value,omitempty"`
+	FieldErrors    []FieldError           `json:"fields,omitempty"`
+	Context        map[string]interface{} `json:"context,omitempty"`
+	Timestamp      time.Time              `json:"timestamp"`
+	Cause          error                  `json:"cause,omitempty"`
+	Severity       Severity               `json:"severity"`
+	Stack          *Stacktrace            `json:"stack,omitempty"`
+	UserMsg        string                 `json:"user_msg,omitempty"`
+	UserMsgKey     string                 `json:"user_msg_key,omitempty"`
+	UserMsgArgs    []interface{}          `json:"user_msg_args,omitempty"`
+	Retryable      bool                   `json:"retryable,omitempty"`
+	RetryAfter     time.Duration          `json:"retry_after,omitempty"`
+	RetryPolicy    *RetryPolicy           `json:"retry_policy,omitempty"`
+	Callsite       string                 `json:"callsite,omitempty"`
+	Source         *StackFrame            `json:"source,omitempty"`
+	HTTPStatusCode int                    `json:"http_status,omitempty"`
+	TraceID        string                 `json:"trace_id,omitempty"`
+	SpanID         string                 `json:"span_id,omitempty"`
+	ErrorID        string                 `json:"error_id,omitempty"`
+	Events         []TimelineEvent        `json:"events,omitempty"`
+
+	checkpoint *Error  // last snapshot taken by Checkpoint, never serialized
+	rateLimit  float64 // per-second limit of the NewRateLimited factory that built the error
+
+	samplingRate    float64 // probability used by ShouldLog, see WithSamplingRate()
+	hasSamplingRate bool
+
+	eventName string // overrides the name derived by EventName()
+	rawStack  string // stack text decoded by UnmarshalJSON, re-emitted when Stack is nil
+	sentinel  bool   // created by NewSentinel, matched by identity in Is()
+	origin    *Error // sentinel this error was cloned from, matched by Is()
+
+	upstreamCode ErrorCode // code replaced by CodeTranslator.Translate, see UpstreamCode
+
+	fingerprint string // overrides the fingerprint computed by Fingerprint()
+
+	stackFormat    StackFormat // JSON stack layout set by WithStackFormat()
+	hasStackFormat bool
+
+	stackChoice stackChoice // set by WithStack/WithoutStack options, consumed by the constructor
+	withCaller  bool        // set by the WithCaller option, see Source
+	verbose     bool        // set by WithVerboseError(), see Error()
 }
 
 // New creates a new structured error with the given code and message.
 // The error will have a timestamp set to the current time and default severity of SeverityError.
 // If code is empty or whitespace-only, DefaultErrorCode will be used instead.
+// Options such as WithStack() are applied after construction.
 //
 // Example:
 //
 //	const ErrCodeValidation ErrorCode = "VALIDATION_ERROR"
 //	err := New(ErrCodeValidation, "Username is required")
 //	fmt.Println(err.Error()) // Output: [VALIDATION_ERROR]: Username is required
-func New(code ErrorCode, message string) *Error {
-	if !validateErrorCode(code) {
-		code = DefaultErrorCode
-	}
-	return &Error{
+func New(code ErrorCode, message string, opts ...Option) *Error {
+	code = checkCode(code)
+	e := &Error{
 		Code:      code,
 		Message:   message,
-		Timestamp: timecache.CachedTime(),
+		Timestamp: now(),
 		Severity:  SeverityError,
 		Context:   make(map[string]interface{}),
 	}
+	e.finish(opts, false, 1)
+	return e
+}
+
+// NewWithCaller creates a new error like New and records the function, file and line of
+// the caller in Source, like the WithCaller option.
+//
+// Example:
+//
+//	err := errors.NewWithCaller("CACHE_MISS", "Key not found")
+//	// {"code":"CACHE_MISS",...,"source":{"function":"main.lookup","file":"/app/cache.go","line":42,"package":"main"}}
+func NewWithCaller(code ErrorCode, message string, opts ...Option) *Error {
+	code = checkCode(code)
+	e := &Error{
+		Code:       code,
+		Message:    message,
+		Timestamp:  now(),
+		Severity:   SeverityError,
+		Context:    make(map[string]interface{}),
+		withCaller: true,
+	}
+	e.finish(opts, false, 1)
+	return e
 }
 
 // NewWithField creates a new structured error with the given code, message, field, and value.
@@ -75,33 +131,56 @@ func New(code ErrorCode, message string) *Error {
 //	err := NewWithField("VALIDATION_ERROR", "Invalid email format", "email", "invalid@")
 //	fmt.Printf("Field: %s, Value: %s\n", err.Field, err.Value)
 //	// Output: Field: email, Value: invalid@
-func NewWithField(code ErrorCode, message, field, value string) *Error {
-	if !validateErrorCode(code) {
-		code = DefaultErrorCode
-	}
-	return &Error{
+func NewWithField(code ErrorCode, message, field, value string, opts ...Option) *Error {
+	code = checkCode(code)
+	e := &Error{
 		Code:      code,
 		Message:   message,
 		Field:     field,
 		Value:     value,
-		Timestamp: timecache.CachedTime(),
+		Timestamp: now(),
 		Severity:  SeverityError,
 		Context:   make(map[string]interface{}),
 	}
+	e.finish(opts, false, 1)
+	return e
 }
 
 // NewWithContext creates a new structured error with the given code, message, and context map.
 // The context map allows you to attach additional metadata to the error for debugging purposes.
 // If code is empty or whitespace-only, DefaultErrorCode will be used instead.
-func NewWithContext(code ErrorCode, message string, context map[string]interface{}) *Error {
-	if !validateErrorCode(code) {
-		code = DefaultErrorCode
-	}
-	return &Error{
+func NewWithContext(code ErrorCode, message string, context map[string]interface{}, opts ...Option) *Error {
+	code = checkCode(code)
+	e := &Error{
 		Code:      code,
 		Message:   message,
-		Timestamp: timecache.CachedTime(),
+		Timestamp: now(),
 		Severity:  SeverityError,
 		Context:   context,
 	}
+	e.finish(opts, false, 1)
+	return e
+}
+
+// Clone returns a deep copy of the error that can be modified independently of the original.
+// The Context map and the stack trace frames are copied, while the Cause is shared since
+// causes are treated as immutable. The clone is not linked to the wrappers of the original
+// (see Parent) and does not inherit its checkpoint. The clone of a sentinel (see
+// NewSentinel) is not a sentinel itself, but errors.Is still matches it to the sentinel it
+// was cloned from.
+//
+// Example:
+//
+//	branch := base.Clone().WithContext("branch_id", id)
+func (e *Error) Clone() *Error {
+	c := e.snapshot()
+	if e.sentinel {
+		c.sentinel, c.origin = false, e
+	}
+	if e.Stack != nil {
+		frames := make([]uintptr, len(e.Stack.Frames))
+		copy(frames, e.Stack.Frames)
+		c.Stack = &Stacktrace{Frames: frames}
+	}
+	return c
 }
@@ -7,11 +7,27 @@
 package errors
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 )
 
 const (
@@ -172,17 +188,92 @@ func TestIsWithNonErrorTarget(t *testing.T) {
 	}
 }
 
+type testCauseError struct{ msg string }
+
+func (e *testCauseError) Error() string { return e.msg }
+
 func TestAsFunction(t *testing.T) {
-	orig := errors.New("original error")
+	orig := &testCauseError{msg: "original error"}
 	wrapped := Wrap(orig, TestCodeDatabase, "DB failed")
 
-	// Test that As can find the original error in the cause chain
-	var origTarget error
-	if !wrapped.As(&origTarget) {
-		t.Error("As should succeed for error target")
+	// The *Error itself satisfies error, so it is the first match
+	var errTarget error
+	if !wrapped.As(&errTarget) || errTarget != wrapped {
+		t.Error("As should set an error target to the *Error itself")
+	}
+
+	var structured *Error
+	if !wrapped.As(&structured) || structured != wrapped {
+		t.Error("As should set a **Error target to the *Error itself")
+	}
+
+	var coder ErrorCoder
+	if !wrapped.As(&coder) || coder.ErrorCode() != TestCodeDatabase {
+		t.Error("As should match interfaces implemented by *Error")
+	}
+
+	// Concrete types in the cause chain are still found
+	var causeTarget *testCauseError
+	if !wrapped.As(&causeTarget) || causeTarget != orig {
+		t.Error("As should set a concrete target to the original error")
+	}
+}
+
+func TestAsTargetCombinations(t *testing.T) {
+	orig := &testCauseError{msg: "original error"}
+	inner := Wrap(orig, TestCodeValidation, "inner")
+	outer := Wrap(fmt.Errorf("middle: %w", inner), TestCodeDatabase, "outer")
+
+	tests := []struct {
+		name   string
+		target func() (interface{}, func() interface{})
+		want   interface{}
+		match  bool
+	}{
+		{"**Error", func() (interface{}, func() interface{}) {
+			var v *Error
+			return &v, func() interface{} { return v }
+		}, outer, true},
+		{"error interface", func() (interface{}, func() interface{}) {
+			var v error
+			return &v, func() interface{} { return v }
+		}, outer, true},
+		{"Retryable interface", func() (interface{}, func() interface{}) {
+			var v Retryable
+			return &v, func() interface{} { return v }
+		}, outer, true},
+		{"concrete cause type", func() (interface{}, func() interface{}) {
+			var v *testCauseError
+			return &v, func() interface{} { return v }
+		}, orig, true},
+		{"unrelated interface", func() (interface{}, func() interface{}) {
+			var v interface{ Timeout() bool }
+			return &v, func() interface{} { return v }
+		}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, get := tt.target()
+			if got := outer.As(target); got != tt.match {
+				t.Fatalf("Expected As to return %v, got %v", tt.match, got)
+			}
+			if tt.match && get() != tt.want {
+				t.Errorf("Expected target %v, got %v", tt.want, get())
+			}
+			if got := errors.As(outer, target); got != tt.match {
+				t.Errorf("Expected errors.As to return %v, got %v", tt.match, got)
+			}
+		})
+	}
+
+	var notPointer *Error
+	if outer.As(notPointer) {
+		t.Error("As should return false for a non-pointer-to-pointer target")
 	}
-	if origTarget != orig {
-		t.Error("As should set target to the original error")
+	var nilTarget **Error
+	if outer.As(nilTarget) {
+		t.Error("As should return false for a nil **Error target")
 	}
 }
 
@@ -477,3 +568,4280 @@ func testRecursiveStackCapture(depth, target int) *Stacktrace {
 	}
 	return testRecursiveStackCapture(depth+1, target)
 }
+
+func TestCheckpointAndRestore(t *testing.T) {
+	err := New(TestCodeValidation, "Original").WithContext("step", 1)
+
+	if err.HasCheckpoint() {
+		t.Error("Expected no checkpoint on a fresh error")
+	}
+	if err.Restore() != err {
+		t.Error("Restore without checkpoint should return the same error")
+	}
+
+	err.Checkpoint()
+	if !err.HasCheckpoint() {
+		t.Fatal("Expected checkpoint to be set")
+	}
+
+	err.Message = "Changed"
+	err.WithContext("step", 2).WithContext("extra", true).WithCriticalSeverity()
+
+	err.Restore()
+	if err.Message != "Original" {
+		t.Errorf("Expected message 'Original', got '%s'", err.Message)
+	}
+	if err.Context["step"] != 1 {
+		t.Errorf("Expected context step 1, got '%v'", err.Context["step"])
+	}
+	if _, ok := err.Context["extra"]; ok {
+		t.Error("Expected context key added after checkpoint to be removed")
+	}
+	if err.Severity != SeverityError {
+		t.Errorf("Expected severity '%s', got '%s'", SeverityError, err.Severity)
+	}
+
+	// Restore keeps the checkpoint and does not alias it
+	err.WithContext("step", 3)
+	err.Restore()
+	if err.Context["step"] != 1 {
+		t.Errorf("Expected second restore to yield step 1, got '%v'", err.Context["step"])
+	}
+
+	err.ClearCheckpoint()
+	if err.HasCheckpoint() {
+		t.Error("Expected checkpoint to be cleared")
+	}
+}
+
+func TestNewRateLimited(t *testing.T) {
+	newErr := NewRateLimited(2)
+
+	first := newErr(TestCodeValidation, "first")
+	second := newErr(TestCodeValidation, "second")
+	third := newErr(TestCodeValidation, "third")
+
+	if first == nil || second == nil {
+		t.Fatal("Expected the burst to allow two errors")
+	}
+	if third != nil {
+		t.Error("Expected third error to be rate-limited")
+	}
+	if first.Code != TestCodeValidation || first.Message != "first" {
+		t.Errorf("Unexpected error fields: %s", first.Error())
+	}
+	if rate := SampledErrorRate(first); rate != 2 {
+		t.Errorf("Expected sampled rate 2, got %v", rate)
+	}
+	if rate := SampledErrorRate(New(TestCodeValidation, "plain")); rate != 0 {
+		t.Errorf("Expected sampled rate 0 for plain error, got %v", rate)
+	}
+	if rate := SampledErrorRate(errors.New("std")); rate != 0 {
+		t.Errorf("Expected sampled rate 0 for standard error, got %v", rate)
+	}
+
+	if NewRateLimited(0)(TestCodeValidation, "never") != nil {
+		t.Error("Expected zero limit to suppress every error")
+	}
+}
+
+func TestParentChildrenNavigation(t *testing.T) {
+	inner := New(TestCodeValidation, "inner")
+	if inner.Parent() != nil || inner.Children() != nil {
+		t.Error("Expected fresh error to have no parent or children")
+	}
+	if inner.Root() != inner {
+		t.Error("Expected Root of unwrapped error to be itself")
+	}
+
+	middle := Wrap(inner, TestCodeDatabase, "middle")
+	outer := Wrap(fmt.Errorf("context: %w", middle), "OUTER_ERROR", "outer")
+
+	if inner.Parent() != middle {
+		t.Error("Expected inner parent to be middle")
+	}
+	if middle.Parent() != outer {
+		t.Error("Expected middle parent to be outer (through fmt wrapper)")
+	}
+	if inner.Root() != outer {
+		t.Error("Expected Root to return outermost wrapper")
+	}
+
+	other := Wrap(inner, "OTHER_ERROR", "other branch")
+	children := inner.Children()
+	if len(children) != 2 || children[0] != middle || children[1] != other {
+		t.Errorf("Expected children [middle other], got %v", children)
+	}
+	if inner.Parent() != other {
+		t.Error("Expected parent to be the most recent wrapper")
+	}
+
+	data, _ := json.Marshal(inner)
+	if strings.Contains(string(data), "parent") || strings.Contains(string(data), "children") {
+		t.Error("Navigation links must not be serialized")
+	}
+
+	shared := New(TestCodeValidation, "shared")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_ = Wrap(shared, TestCodeDatabase, "concurrent")
+			}
+		}()
+	}
+	wg.Wait()
+	if n := len(shared.Children()); n > maxWrapChildren {
+		t.Errorf("Expected at most %d children, got %d", maxWrapChildren, n)
+	}
+}
+
+func TestWrapLinkLimit(t *testing.T) {
+	total := wrapLinkShards*maxWrapLinks + 4096
+	inners := make([]*Error, total)
+	wrappers := make([]*Error, total)
+	for i := range inners {
+		inners[i] = &Error{Code: TestCodeValidation, Message: "inner"}
+		wrappers[i] = WrapNoStack(inners[i], TestCodeDatabase, "outer")
+	}
+	dropped := 0
+	for i, inner := range inners {
+		switch inner.Parent() {
+		case wrappers[i]:
+		case nil:
+			dropped++
+			if inner.Root() != inner {
+				t.Fatal("Expected Root to return the error itself when its link was dropped")
+			}
+		default:
+			t.Fatal("Expected Parent to be the recorded wrapper or nil")
+		}
+	}
+	if dropped < total-wrapLinkShards*maxWrapLinks {
+		t.Errorf("Expected at least %d links to be dropped, got %d", total-wrapLinkShards*maxWrapLinks, dropped)
+	}
+
+	// Once the wrappers are collected, their entries make room for new links.
+	wrappers = nil
+	runtime.GC()
+	for _, inner := range inners[:wrapLinkShards] {
+		if outer := WrapNoStack(inner, TestCodeDatabase, "again"); inner.Parent() != outer {
+			t.Fatal("Expected a link to be recorded after the stale entries were pruned")
+		}
+	}
+	runtime.KeepAlive(inners)
+}
+
+func TestSample(t *testing.T) {
+	err := New(TestCodeValidation, "sampled")
+
+	if !err.Sample(AlwaysLog()) {
+		t.Error("Expected AlwaysLog rate to always sample")
+	}
+	if err.Sample(NeverLog()) {
+		t.Error("Expected NeverLog rate to never sample")
+	}
+	if LogOneIn(4) != 0.25 || LogOneIn(0) != 1.0 {
+		t.Errorf("Unexpected LogOneIn rates: %v, %v", LogOneIn(4), LogOneIn(0))
+	}
+
+	hits := 0
+	for i := 0; i < 10000; i++ {
+		if err.Sample(0.5) {
+			hits++
+		}
+	}
+	if hits < 4000 || hits > 6000 {
+		t.Errorf("Expected roughly half of samples to hit, got %d/10000", hits)
+	}
+}
+
+func TestShouldLog(t *testing.T) {
+	if ShouldLog(nil) {
+		t.Error("Expected nil error not to be logged")
+	}
+	if !ShouldLog(errors.New("std")) {
+		t.Error("Expected standard errors to always be logged")
+	}
+	if !ShouldLog(New(TestCodeValidation, "no rate")) {
+		t.Error("Expected errors without rate to always be logged")
+	}
+
+	never := New(TestCodeValidation, "never").WithSamplingRate(NeverLog())
+	if ShouldLog(Wrap(never, TestCodeDatabase, "wrapped")) {
+		t.Error("Expected ShouldLog to honor rate found in the chain")
+	}
+	if ShouldLog(never) {
+		t.Error("Expected NeverLog rate to suppress logging")
+	}
+}
+
+func TestCodeConventions(t *testing.T) {
+	upper := UpperSnakeCaseConvention()
+	dotted := DotSeparatedConvention("BILLING")
+	anyNS := DotSeparatedConvention("")
+
+	tests := []struct {
+		name      string
+		validator func(ErrorCode) error
+		code      ErrorCode
+		valid     bool
+	}{
+		{"upper valid", upper, "VALIDATION_ERROR", true},
+		{"upper digits", upper, "E42_FAILED", true},
+		{"upper lowercase", upper, "validation_error", false},
+		{"upper leading digit", upper, "1_ERROR", false},
+		{"upper too long", upper, ErrorCode(strings.Repeat("A", 65)), false},
+		{"dotted valid", dotted, "BILLING.CARD_DECLINED", true},
+		{"dotted wrong namespace", dotted, "USERS.NOT_FOUND", false},
+		{"dotted missing dot", dotted, "BILLING_CARD_DECLINED", false},
+		{"dotted bad name", dotted, "BILLING.declined", false},
+		{"any namespace", anyNS, "USERS.NOT_FOUND", true},
+		{"any namespace empty", anyNS, ".NOT_FOUND", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validator(tt.code)
+			if (err == nil) != tt.valid {
+				t.Fatalf("Expected valid=%v for %q, got %v", tt.valid, tt.code, err)
+			}
+			if err != nil && !HasCode(err, CodeConventionError) {
+				t.Errorf("Expected convention error code, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSetCodeConvention(t *testing.T) {
+	SetCodeConvention(DotSeparatedConvention("APP"))
+	defer SetCodeConvention(nil)
+	defer SetInvalidCodePolicy(InvalidCodeUseDefault)
+
+	if err := New("APP.NOT_FOUND", "ok"); err.Code != "APP.NOT_FOUND" {
+		t.Errorf("Expected valid code to be kept, got '%s'", err.Code)
+	}
+	if err := New("USER_NOT_FOUND", "fallback"); err.Code != DefaultErrorCode {
+		t.Errorf("Expected invalid code to fall back to default, got '%s'", err.Code)
+	}
+
+	err, verr := NewChecked("NOT_FOUND", "checked")
+	if verr == nil || !HasCode(verr, CodeConventionError) {
+		t.Errorf("Expected NewChecked to report convention error, got %v", verr)
+	}
+	if err.Code != DefaultErrorCode {
+		t.Errorf("Expected NewChecked to fall back to default code, got '%s'", err.Code)
+	}
+	if _, verr := NewChecked("APP.OK", "checked"); verr != nil {
+		t.Errorf("Expected no convention error, got %v", verr)
+	}
+	if ValidateCode("  ") == nil {
+		t.Error("Expected whitespace code to be rejected")
+	}
+
+	SetInvalidCodePolicy(InvalidCodePanic)
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("Expected panic for invalid code")
+		}
+		if e, ok := r.(*Error); !ok || e.Code != CodeConventionError {
+			t.Errorf("Expected panic with convention error, got %v", r)
+		}
+	}()
+	_ = Wrap(errors.New("cause"), "WRONG", "panics")
+}
+
+func TestBuiltinCodesExemptFromConvention(t *testing.T) {
+	SetCodeConvention(DotSeparatedConvention("APP"))
+	SetInvalidCodePolicy(InvalidCodePanic)
+	defer SetCodeConvention(nil)
+	defer SetInvalidCodePolicy(InvalidCodeUseDefault)
+
+	var m *MultiError
+	if !errors.As(Combine(io.EOF), &m) || m.Errors()[0].Code != DefaultErrorCode {
+		t.Errorf("Expected Combine to wrap with DefaultErrorCode, got %v", m)
+	}
+	if e := NotFound("missing"); e.Code != ErrCodeNotFound {
+		t.Errorf("Expected NotFound to keep its code, got %s", e.Code)
+	}
+	c := NewCollector()
+	c.Collect(io.EOF)
+	if c.Len() != 1 {
+		t.Error("Expected Collect to record a plain error")
+	}
+	loop := &Error{Code: "APP.LOOP", Message: "loop"}
+	loop.Cause = loop
+	if cerr := CheckChain(loop); !HasCode(cerr, ErrCodeChainCycle) {
+		t.Errorf("Expected CheckChain to report the cycle, got %v", cerr)
+	}
+	if e := NewFromPanic("boom", ""); e.Code != ErrCodePanic {
+		t.Errorf("Expected NewFromPanic to keep ErrCodePanic, got %s", e.Code)
+	}
+}
+
+func TestEventName(t *testing.T) {
+	tests := []struct {
+		code     ErrorCode
+		expected string
+	}{
+		{"VALIDATION_ERROR", "error.validation"},
+		{"DATABASE_ERROR", "error.database"},
+		{"USER_NOT_FOUND", "error.user_not_found"},
+		{"ERROR", "error"},
+	}
+	for _, tt := range tests {
+		if got := New(tt.code, "msg").EventName(); got != tt.expected {
+			t.Errorf("EventName(%s): expected '%s', got '%s'", tt.code, tt.expected, got)
+		}
+	}
+
+	err := New(TestCodeValidation, "msg").WithEventName("user.signup.rejected")
+	if err.EventName() != "user.signup.rejected" {
+		t.Errorf("Expected overridden event name, got '%s'", err.EventName())
+	}
+}
+
+func TestEventPayloadAndPublish(t *testing.T) {
+	err := New(TestCodeDatabase, "timeout").WithContext("trace_id", "abc123")
+
+	payload := err.ToEventPayload()
+	if payload["event"] != "error.database" || payload["code"] != "DATABASE_ERROR" {
+		t.Errorf("Unexpected payload: %v", payload)
+	}
+	if payload["timestamp"] != err.Timestamp.UnixMilli() {
+		t.Errorf("Expected Unix ms timestamp, got %v", payload["timestamp"])
+	}
+	if payload["trace_id"] != "abc123" {
+		t.Errorf("Expected trace_id in payload, got %v", payload["trace_id"])
+	}
+
+	ch := make(chan interface{}, 1)
+	if pubErr := err.PublishToChannel(ch); pubErr != nil {
+		t.Fatalf("Unexpected publish error: %v", pubErr)
+	}
+	if got := (<-ch).(map[string]interface{}); got["message"] != "timeout" {
+		t.Errorf("Unexpected published payload: %v", got)
+	}
+
+	full := make(chan interface{})
+	if pubErr := err.PublishToChannel(full); !HasCode(pubErr, ErrCodeEventChannel) {
+		t.Errorf("Expected channel error for full channel, got %v", pubErr)
+	}
+	if pubErr := err.PublishToChannel(nil); !HasCode(pubErr, ErrCodeEventChannel) {
+		t.Errorf("Expected channel error for nil channel, got %v", pubErr)
+	}
+}
+
+func TestWrapMethod(t *testing.T) {
+	inner := New(TestCodeValidation, "inner")
+	outer := inner.Wrap(TestCodeDatabase, "outer").WithContext("key", "val")
+
+	if outer.Cause != inner {
+		t.Error("Expected method Wrap to set the receiver as cause")
+	}
+	if outer.Code != TestCodeDatabase || outer.Context["key"] != "val" {
+		t.Errorf("Unexpected wrapped error: %s %v", outer.Error(), outer.Context)
+	}
+	if inner.Parent() != outer {
+		t.Error("Expected method Wrap to link the parent")
+	}
+	if outer.Stack == nil || !strings.Contains(outer.Stack.String(), "TestWrapMethod") {
+		t.Error("Expected stack trace to start at the caller")
+	}
+	if strings.Contains(outer.Stack.String(), "errors.wrapWithOptions") {
+		t.Error("Expected internal wrap frames to be skipped")
+	}
+}
+
+func recoverInto(target **Error, code ErrorCode) {
+	*target = NewFromPanic(recover(), code)
+}
+
+func panicWith(v interface{}, code ErrorCode) (err *Error) {
+	defer recoverInto(&err, code)
+	panic(v)
+}
+
+func TestNewFromPanic(t *testing.T) {
+	cause := errors.New("boom")
+	tests := []struct {
+		name      string
+		value     interface{}
+		code      ErrorCode
+		wantCode  ErrorCode
+		wantMsg   string
+		wantCause error
+	}{
+		{"error value", cause, "HANDLER_PANIC", "HANDLER_PANIC", "boom", cause},
+		{"string value", "bad state", "", ErrCodePanic, "bad state", nil},
+		{"other value", 42, "", ErrCodePanic, "42", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := panicWith(tt.value, tt.code)
+			if err == nil {
+				t.Fatal("Expected error from panic")
+			}
+			if err.Code != tt.wantCode || err.Message != tt.wantMsg || err.Cause != tt.wantCause {
+				t.Errorf("Unexpected error: code=%s msg=%s cause=%v", err.Code, err.Message, err.Cause)
+			}
+			if err.Severity != SeverityCritical {
+				t.Errorf("Expected critical severity, got '%s'", err.Severity)
+			}
+			if !IsPanic(Wrap(err, TestCodeDatabase, "wrapped")) {
+				t.Error("Expected IsPanic to find the panic in the chain")
+			}
+			if PanicValue(err) != tt.value {
+				t.Errorf("Expected panic value %v, got %v", tt.value, PanicValue(err))
+			}
+			frames := runtime.CallersFrames(err.Stack.Frames)
+			if first, _ := frames.Next(); !strings.HasSuffix(first.Function, "panicWith") {
+				t.Errorf("Expected stack to start at the panicking function, got %s", first.Function)
+			}
+		})
+	}
+
+	if NewFromPanic(nil, "") != nil {
+		t.Error("Expected nil for nil recovered value")
+	}
+	if IsPanic(New(TestCodeValidation, "plain")) || PanicValue(errors.New("std")) != nil {
+		t.Error("Expected non-panic errors not to be reported as panics")
+	}
+}
+
+func TestNotImplemented(t *testing.T) {
+	err := NewNotImplemented("ExportReport")
+	if err.Code != ErrCodeNotImplemented || err.Severity != SeverityWarning {
+		t.Errorf("Unexpected error: code=%s severity=%s", err.Code, err.Severity)
+	}
+	if err.Op != "ExportReport" || err.Context["operation"] != nil || HTTPStatus(err) != 501 {
+		t.Errorf("Unexpected op or status: %q %v %d", err.Op, err.Context, HTTPStatus(err))
+	}
+	if err.UserMessage() != "This feature is not yet available" {
+		t.Errorf("Unexpected user message: %s", err.UserMessage())
+	}
+	if ops := Ops(Wrap(err, TestCodeDatabase, "wrapped")); len(ops) != 1 || ops[0] != "ExportReport" {
+		t.Errorf("Expected the feature in Ops, got %v", ops)
+	}
+	if !IsNotImplemented(Wrap(err, TestCodeDatabase, "wrapped")) {
+		t.Error("Expected IsNotImplemented to find the code in the chain")
+	}
+	if IsNotImplemented(New(TestCodeValidation, "other")) {
+		t.Error("Expected IsNotImplemented to be false for other codes")
+	}
+
+	created := RegisterNotImplemented("Zeta", "Alpha")
+	if len(created) != 2 || created["Alpha"].Op != "Alpha" {
+		t.Errorf("Unexpected registered errors: %v", created)
+	}
+	features := NotImplementedFeatures()
+	if len(features) < 2 || features[0] != "Alpha" {
+		t.Errorf("Expected sorted inventory, got %v", features)
+	}
+
+	defer ClearHooks()
+	var seen []string
+	RegisterCreationHook(func(e *Error) {
+		if e.Code == ErrCodeNotImplemented {
+			seen = NotImplementedFeatures()
+		}
+	})
+	created = RegisterNotImplemented("Beta")
+	if seen == nil {
+		t.Error("Expected creation hooks to run and read the inventory without deadlocking")
+	}
+	created["Beta"].WithContext("caller", "a")
+	notImplementedMu.RLock()
+	recorded := notImplementedFeatures["Beta"]
+	notImplementedMu.RUnlock()
+	if recorded == created["Beta"] || recorded.Context["caller"] != nil {
+		t.Error("Expected callers to receive a copy of the recorded error")
+	}
+}
+
+func TestErrorGroupRepresentations(t *testing.T) {
+	g := NewErrorGroup(
+		New(TestCodeValidation, "field required"),
+		nil,
+		New(TestCodeDatabase, "timeout"),
+		New(TestCodeValidation, "too long"),
+		errors.New("plain"),
+	)
+
+	if g.Len() != 4 {
+		t.Fatalf("Expected nil errors to be skipped, got %d errors", g.Len())
+	}
+	if !errors.Is(g, &Error{Code: TestCodeDatabase}) {
+		t.Error("Expected errors.Is to inspect grouped errors")
+	}
+
+	wantString := "4 errors: [VALIDATION_ERROR: field required], [DATABASE_ERROR: timeout], [VALIDATION_ERROR: too long] and 1 more"
+	if got := g.String(); got != wantString {
+		t.Errorf("String:\nexpected %q\ngot      %q", wantString, got)
+	}
+	var _ fmt.Stringer = g
+
+	wantSummary := "4 errors (2×VALIDATION_ERROR, 1×DATABASE_ERROR, 1×UNKNOWN_ERROR)"
+	if got := g.Summary(); got != wantSummary {
+		t.Errorf("Summary:\nexpected %q\ngot      %q", wantSummary, got)
+	}
+
+	verbose := g.Verbose()
+	if !strings.HasPrefix(verbose, "4 errors:\n  1. [VALIDATION_ERROR]: field required") ||
+		!strings.HasSuffix(verbose, "\n  4. plain") {
+		t.Errorf("Unexpected verbose output: %q", verbose)
+	}
+
+	if g.Error() != "[VALIDATION_ERROR]: field required; [DATABASE_ERROR]: timeout; [VALIDATION_ERROR]: too long; plain" {
+		t.Errorf("Unexpected Error output: %q", g.Error())
+	}
+
+	empty := &ErrorGroup{}
+	if empty.ErrorOrNil() != nil || empty.String() != "0 errors" || empty.Summary() != "0 errors" {
+		t.Error("Unexpected empty group representation")
+	}
+	if one := NewErrorGroup(errors.New("x")); one.String() != "1 error: [x]" {
+		t.Errorf("Unexpected single error representation: %q", one.String())
+	}
+}
+
+func TestCallsite(t *testing.T) {
+	_, file, line, _ := runtime.Caller(0)
+	err := NewAtCallsite(TestCodeValidation, "with callsite")
+	if want := fmt.Sprintf("%s:%d", file, line+1); err.Callsite != want {
+		t.Errorf("Expected callsite '%s', got '%s'", want, err.Callsite)
+	}
+	if err.Stack != nil {
+		t.Error("Expected no stack trace to be captured")
+	}
+
+	err = New(TestCodeValidation, "later").WithCallsite()
+	if want := fmt.Sprintf("%s:%d", file, line+9); err.Callsite != want {
+		t.Errorf("Expected callsite '%s', got '%s'", want, err.Callsite)
+	}
+
+	data, _ := json.Marshal(err)
+	if !strings.Contains(string(data), `"callsite":"`) {
+		t.Errorf("Expected callsite in JSON, got %s", data)
+	}
+}
+
+func wrapOnBehalf(err error) *Error {
+	return WrapWithSkip(err, TestCodeDatabase, "helper", 1)
+}
+
+func firstFrameFunction(st *Stacktrace) string {
+	frame, _ := runtime.CallersFrames(st.Frames).Next()
+	return frame.Function
+}
+
+func TestWrapWithOptions(t *testing.T) {
+	inner := New(TestCodeValidation, "inner")
+	ctx := map[string]interface{}{"order_id": 7}
+
+	err := WrapWithOptions(inner, WrapOptions{
+		Message:      "outer",
+		PreserveCode: true,
+		Severity:     SeverityCritical,
+		Context:      ctx,
+	})
+	if err.Code != TestCodeValidation {
+		t.Errorf("Expected preserved code '%s', got '%s'", TestCodeValidation, err.Code)
+	}
+	if err.Severity != SeverityCritical || err.Context["order_id"] != 7 {
+		t.Errorf("Unexpected severity or context: %s %v", err.Severity, err.Context)
+	}
+	err.WithContext("extra", true)
+	if _, ok := ctx["extra"]; ok {
+		t.Error("Expected options context to be copied")
+	}
+	if !strings.HasSuffix(firstFrameFunction(err.Stack), "TestWrapWithOptions") {
+		t.Errorf("Expected stack to start at caller, got %s", firstFrameFunction(err.Stack))
+	}
+
+	zero := WrapWithOptions(errors.New("std"), WrapOptions{})
+	if zero.Code != DefaultErrorCode || zero.Severity != SeverityError || zero.Stack == nil {
+		t.Errorf("Unexpected zero-options wrap: %s %s", zero.Code, zero.Severity)
+	}
+}
+
+func TestWrapVariants(t *testing.T) {
+	inner := New(TestCodeValidation, "inner")
+
+	if err := WrapNoStack(inner, TestCodeDatabase, "no stack"); err.Stack != nil || err.Code != TestCodeDatabase {
+		t.Error("Expected WrapNoStack to skip stack capture")
+	}
+	if err := WrapPreservingCode(inner, "same code"); err.Code != TestCodeValidation || err.Cause != inner {
+		t.Errorf("Expected preserved code, got '%s'", err.Code)
+	}
+	if err := WrapPreservingCode(errors.New("std"), "no code"); err.Code != DefaultErrorCode {
+		t.Errorf("Expected default code without inner *Error, got '%s'", err.Code)
+	}
+	if fn := firstFrameFunction(wrapOnBehalf(inner).Stack); !strings.HasSuffix(fn, "TestWrapVariants") {
+		t.Errorf("Expected WrapWithSkip to skip helper frame, got %s", fn)
+	}
+}
+
+func TestUnmarshalJSONRoundtrip(t *testing.T) {
+	root := errors.New("connection refused")
+	inner := Wrap(root, TestCodeDatabase, "query failed").WithContext("table", "users")
+	orig := Wrap(inner, TestCodeValidation, "request failed").
+		WithUserMessage("Please retry").
+		WithCriticalSeverity().
+		WithContext("user_id", "123").
+		WithContext("attempt", float64(2)).
+		AsRetryable()
+
+	data, err := json.Marshal(orig)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded Error
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if decoded.Code != orig.Code || decoded.Message != orig.Message || decoded.UserMsg != orig.UserMsg ||
+		decoded.Severity != orig.Severity || decoded.Retryable != orig.Retryable {
+		t.Errorf("Roundtrip mismatch:\noriginal %+v\ndecoded  %+v", orig, decoded)
+	}
+	if fmt.Sprint(decoded.Context) != fmt.Sprint(orig.Context) {
+		t.Errorf("Context mismatch: %v vs %v", decoded.Context, orig.Context)
+	}
+	if !decoded.Timestamp.Equal(orig.Timestamp) {
+		t.Errorf("Timestamp mismatch: %v vs %v", decoded.Timestamp, orig.Timestamp)
+	}
+
+	decodedInner, ok := decoded.Cause.(*Error)
+	if !ok {
+		t.Fatalf("Expected structured cause, got %T", decoded.Cause)
+	}
+	if decodedInner.Code != TestCodeDatabase || decodedInner.Context["table"] != "users" {
+		t.Errorf("Unexpected decoded cause: %+v", decodedInner)
+	}
+	if decodedInner.Cause == nil || decodedInner.Cause.Error() != "connection refused" {
+		t.Errorf("Expected plain root cause message, got %v", decodedInner.Cause)
+	}
+	if !HasCode(&decoded, TestCodeDatabase) {
+		t.Error("Expected HasCode to work on decoded chain")
+	}
+
+	// The stack is preserved as opaque text
+	if decoded.Stack != nil {
+		t.Error("Expected decoded Stack to be nil")
+	}
+	again, _ := json.Marshal(&decoded)
+	var first, second map[string]interface{}
+	_ = json.Unmarshal(data, &first)
+	_ = json.Unmarshal(again, &second)
+	if first["stack"] == "" || first["stack"] != second["stack"] {
+		t.Error("Expected stack text to survive a second roundtrip")
+	}
+}
+
+func TestUnmarshalJSONInvalid(t *testing.T) {
+	var e Error
+	if err := json.Unmarshal([]byte(`{"code":`), &e); err == nil {
+		t.Error("Expected error for malformed JSON")
+	}
+	if err := json.Unmarshal([]byte(`{"code":"X","cause":42}`), &e); err == nil {
+		t.Error("Expected error for malformed cause")
+	}
+}
+
+func TestMultiError(t *testing.T) {
+	if NewMultiError().Build() != nil {
+		t.Fatal("Expected Build to return nil without errors")
+	}
+
+	err := NewMultiError().
+		Add(TestCodeValidation, "Email is required", "email", "").
+		Add("TOO_LONG", "Name is too long", "name", "xxxxxxxx").
+		Build()
+
+	var multi *MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("Expected *MultiError, got %T", err)
+	}
+	if multi.ErrorCount() != 2 {
+		t.Errorf("Expected 2 errors, got %d", multi.ErrorCount())
+	}
+	if err.Error() != "2 errors: email: Email is required; name: Name is too long" {
+		t.Errorf("Unexpected summary: %q", err.Error())
+	}
+	if !HasCode(err, "TOO_LONG") || !HasCode(Wrap(err, TestCodeDatabase, "wrapped"), TestCodeValidation) {
+		t.Error("Expected HasCode to scan contained errors")
+	}
+	if HasCode(err, TestCodeDatabase) {
+		t.Error("Expected HasCode to be false for missing code")
+	}
+
+	structured := multi.Errors()
+	if structured[1].Field != "name" || structured[1].Value != "xxxxxxxx" || structured[1].Code != "TOO_LONG" {
+		t.Errorf("Unexpected structured error: %+v", structured[1])
+	}
+	if fields := multi.Fields(); len(fields) != 2 || fields[0].Field != "email" {
+		t.Errorf("Unexpected fields: %v", fields)
+	}
+
+	structured[0].WithContext("form", "signup").WithCriticalSeverity()
+	data, jsonErr := json.Marshal(err)
+	if jsonErr != nil {
+		t.Fatalf("Marshal failed: %v", jsonErr)
+	}
+	var doc struct {
+		Errors []Error `json:"errors"`
+	}
+	if jsonErr := json.Unmarshal(data, &doc); jsonErr != nil || len(doc.Errors) != 2 {
+		t.Fatalf("Expected an array of structured errors, got %s (%v)", data, jsonErr)
+	}
+	first, second := doc.Errors[0], doc.Errors[1]
+	if first.Code != TestCodeValidation || first.Field != "email" || first.Severity != SeverityCritical || first.Context["form"] != "signup" {
+		t.Errorf("Expected the full first entry, got %s", data)
+	}
+	if second.Code != "TOO_LONG" || second.Message != "Name is too long" || second.Value != "xxxxxxxx" || second.Timestamp.IsZero() {
+		t.Errorf("Expected the full second entry, got %s", data)
+	}
+}
+
+func logErrorJSON(t *testing.T, err error) map[string]interface{} {
+	t.Helper()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	logger.Error("request failed", "err", err)
+
+	var entry map[string]interface{}
+	if jsonErr := json.Unmarshal(buf.Bytes(), &entry); jsonErr != nil {
+		t.Fatalf("Invalid log output %q: %v", buf.String(), jsonErr)
+	}
+	group, ok := entry["err"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected err to be logged as a group, got %v", entry["err"])
+	}
+	return group
+}
+
+func TestLogValue(t *testing.T) {
+	inner := New(TestCodeValidation, "inner failure")
+	err := Wrap(inner, TestCodeDatabase, "query failed").
+		WithUserMessage("Try again").
+		WithContext("user_id", "123").
+		AsRetryable()
+
+	group := logErrorJSON(t, err)
+	for key, want := range map[string]interface{}{
+		"code":      "DATABASE_ERROR",
+		"message":   "query failed",
+		"severity":  "error",
+		"user_msg":  "Try again",
+		"retryable": true,
+		"user_id":   "123",
+	} {
+		if group[key] != want {
+			t.Errorf("Expected %s=%v, got %v", key, want, group[key])
+		}
+	}
+	if stack, _ := group["stack"].(string); stack == "" {
+		t.Error("Expected stack for error severity")
+	}
+	cause, ok := group["cause"].(map[string]interface{})
+	if !ok || cause["code"] != "VALIDATION_ERROR" {
+		t.Errorf("Expected nested cause group, got %v", group["cause"])
+	}
+
+	warning := Wrap(errors.New("disk almost full"), TestCodeDatabase, "low space").WithWarningSeverity()
+	group = logErrorJSON(t, warning)
+	if _, ok := group["stack"]; ok {
+		t.Error("Expected stack to be omitted for warning severity")
+	}
+	if cause, _ := group["cause"].(map[string]interface{}); cause["message"] != "disk almost full" {
+		t.Errorf("Expected plain cause message, got %v", group["cause"])
+	}
+}
+
+func TestErrorTemplate(t *testing.T) {
+	tmpl := NewTemplate(TestCodeDatabase, TemplateOpts{
+		UserMsg:   "Please try again",
+		Severity:  SeverityCritical,
+		Retryable: true,
+	})
+
+	err := tmpl.New("Query timed out")
+	if err.Code != TestCodeDatabase || err.UserMsg != "Please try again" ||
+		err.Severity != SeverityCritical || !err.Retryable {
+		t.Errorf("Unexpected templated error: %+v", err)
+	}
+	if !HasCode(err, tmpl.Code()) {
+		t.Error("Expected HasCode to match the template code")
+	}
+
+	cause := errors.New("timeout")
+	wrapped := tmpl.Wrap(cause, "Query failed").WithSeverity(SeverityWarning)
+	if wrapped.Cause != cause || wrapped.Severity != SeverityWarning {
+		t.Errorf("Expected per-call overrides to apply, got %+v", wrapped)
+	}
+	if !strings.HasSuffix(firstFrameFunction(wrapped.Stack), "TestErrorTemplate") {
+		t.Errorf("Expected stack to start at caller, got %s", firstFrameFunction(wrapped.Stack))
+	}
+
+	plain := NewTemplate("", TemplateOpts{})
+	if e := plain.New("x"); e.Code != DefaultErrorCode || e.Severity != SeverityError || e.Retryable {
+		t.Errorf("Unexpected defaults: %+v", e)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	err := New(TestCodeDatabase, "Rate limited").WithRetryAfter(30 * time.Second)
+	if !err.IsRetryable() || err.RetryAfter != 30*time.Second {
+		t.Errorf("Expected retryable error with delay, got %+v", err)
+	}
+
+	var scheduler RetryScheduler = err
+	if scheduler.RetryDelay() != 30*time.Second {
+		t.Error("RetryScheduler interface not working")
+	}
+
+	wrapped := Wrap(fmt.Errorf("upstream: %w", err), TestCodeValidation, "outer")
+	if d, ok := RetryDelay(wrapped); !ok || d != 30*time.Second {
+		t.Errorf("Expected delay from chain, got %v %v", d, ok)
+	}
+	if _, ok := RetryDelay(New(TestCodeValidation, "no delay")); ok {
+		t.Error("Expected no delay for plain error")
+	}
+	if _, ok := RetryDelay(nil); ok {
+		t.Error("Expected no delay for nil error")
+	}
+
+	data, _ := json.Marshal(err)
+	if !strings.Contains(string(data), `"retry_after":30000000000`) {
+		t.Errorf("Expected retry_after in JSON, got %s", data)
+	}
+}
+
+func TestRetryDelayAllocations(t *testing.T) {
+	err := Wrap(New(TestCodeDatabase, "Rate limited").WithRetryAfter(time.Second), TestCodeValidation, "outer")
+	if allocs := testing.AllocsPerRun(100, func() { _, _ = RetryDelay(err) }); allocs != 0 {
+		t.Errorf("Expected RetryDelay to be allocation-free, got %v allocs", allocs)
+	}
+}
+
+// cyclicError is a pathological error whose Unwrap points back into its own chain.
+type cyclicError struct{ next error }
+
+func (c *cyclicError) Error() string { return "cyclic" }
+func (c *cyclicError) Unwrap() error { return c.next }
+
+func findCodeMissing(err error, code ErrorCode) bool {
+	found, ok := FindCode(err, code)
+	return found == nil && !ok
+}
+
+func TestChainAndFindCode(t *testing.T) {
+	if Chain(nil) != nil || !findCodeMissing(nil, TestCodeValidation) {
+		t.Error("Expected nil results for nil error")
+	}
+	if Chain(errors.New("std")) != nil {
+		t.Error("Expected nil chain without *Error nodes")
+	}
+
+	root := New(TestCodeValidation, "root")
+	middle := Wrap(fmt.Errorf("std middle: %w", root), TestCodeDatabase, "middle")
+	outer := Wrap(middle, "OUTER_ERROR", "outer")
+
+	chain := Chain(outer)
+	if len(chain) != 3 || chain[0] != outer || chain[1] != middle || chain[2] != root {
+		t.Errorf("Unexpected chain order: %v", chain)
+	}
+	if found, ok := FindCode(outer, TestCodeValidation); !ok || found != root {
+		t.Error("Expected FindCode to return the root error")
+	}
+	if !findCodeMissing(outer, "MISSING") {
+		t.Error("Expected FindCode to return nil for missing code")
+	}
+
+	// Deeply nested chain
+	var deep error = New(TestCodeValidation, "deepest")
+	for i := 0; i < 50; i++ {
+		deep = Wrap(deep, TestCodeDatabase, fmt.Sprintf("level %d", i))
+	}
+	if len(Chain(deep)) != 51 {
+		t.Errorf("Expected 51 errors in deep chain, got %d", len(Chain(deep)))
+	}
+	if _, ok := FindCode(deep, TestCodeValidation); !ok {
+		t.Error("Expected FindCode to reach the deepest error")
+	}
+}
+
+func TestChainWithJoinAndCycles(t *testing.T) {
+	a := New(TestCodeValidation, "a")
+	b := New(TestCodeDatabase, "b")
+	joined := Wrap(errors.Join(a, errors.New("std"), b), "OUTER_ERROR", "outer")
+
+	chain := Chain(joined)
+	if len(chain) != 3 || chain[1] != a || chain[2] != b {
+		t.Errorf("Unexpected chain through join: %v", chain)
+	}
+	if found, _ := FindCode(joined, TestCodeDatabase); found != b {
+		t.Error("Expected FindCode to search joined branches")
+	}
+
+	cyc := &cyclicError{}
+	loop := Wrap(cyc, TestCodeValidation, "loop")
+	cyc.next = loop
+	if got := Chain(loop); len(got) != 1 || got[0] != loop {
+		t.Errorf("Expected cycle to be reported once, got %v", got)
+	}
+	if !findCodeMissing(loop, "MISSING") {
+		t.Error("Expected FindCode to terminate on cycles")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	inner := New(TestCodeValidation, "bad input").WithContext("field", "email")
+	err := Wrap(inner, TestCodeDatabase, "query failed").
+		WithContext("user_id", "123").
+		WithContext("table", "users")
+
+	if got := fmt.Sprintf("%v", err); got != "[DATABASE_ERROR]: query failed" {
+		t.Errorf("Unexpected %%v output: %q", got)
+	}
+	if got := fmt.Sprintf("%s", err); got != err.Error() {
+		t.Errorf("Unexpected %%s output: %q", got)
+	}
+	if got := fmt.Sprintf("%q", err); got != `"[DATABASE_ERROR]: query failed"` {
+		t.Errorf("Unexpected %%q output: %q", got)
+	}
+
+	full := fmt.Sprintf("%+v", err)
+	for _, want := range []string{
+		"[DATABASE_ERROR]: query failed\nSEVERITY: error",
+		"CONTEXT: table=users user_id=123",
+		"STACK",
+		"TestFormat",
+		"\nCaused by: [VALIDATION_ERROR]: bad input\n  SEVERITY: error\n  CONTEXT: field=email",
+	} {
+		if !strings.Contains(full, want) {
+			t.Errorf("Expected %%+v output to contain %q, got:\n%s", want, full)
+		}
+	}
+
+	plain := fmt.Sprintf("%+v", New(TestCodeValidation, "no cause"))
+	if strings.Contains(plain, "STACK") || strings.Contains(plain, "Caused by") {
+		t.Errorf("Unexpected sections for error without stack or cause:\n%s", plain)
+	}
+	if got := fmt.Sprintf("%+v", Wrap(errors.New("root"), TestCodeDatabase, "x")); !strings.HasSuffix(got, "\nCaused by: root") {
+		t.Errorf("Expected plain cause at the end, got:\n%s", got)
+	}
+}
+
+func stackFunctions(st *Stacktrace) []string {
+	var names []string
+	frames := runtime.CallersFrames(st.Frames)
+	for {
+		frame, more := frames.Next()
+		names = append(names, frame.Function)
+		if !more {
+			return names
+		}
+	}
+}
+
+func TestStackFiltering(t *testing.T) {
+	defer SetDefaultStackFilter(DefaultStackFilter...)
+
+	filtered := CaptureStacktrace(0)
+	for _, fn := range stackFunctions(filtered) {
+		if strings.HasPrefix(fn, "runtime.") || strings.HasPrefix(fn, "testing.") {
+			t.Errorf("Expected default filter to remove %s", fn)
+		}
+	}
+	if names := stackFunctions(filtered); !strings.HasSuffix(names[0], "TestStackFiltering") {
+		t.Errorf("Expected first frame to be the caller, got %s", names[0])
+	}
+
+	SetDefaultStackFilter()
+	raw := CaptureStacktrace(0)
+	if !strings.Contains(raw.String(), "testing.tRunner") {
+		t.Error("Expected empty filter to restore raw frames")
+	}
+	if len(raw.Filter().Frames) != len(raw.Frames) {
+		t.Error("Expected Filter without prefixes to keep every frame")
+	}
+	noTesting := raw.Filter("testing.")
+	if strings.Contains(noTesting.String(), "testing.tRunner") || len(raw.Frames) == len(noTesting.Frames) {
+		t.Error("Expected Filter to remove matching frames")
+	}
+	if !strings.Contains(raw.String(), "testing.tRunner") {
+		t.Error("Expected Filter not to modify the original stacktrace")
+	}
+
+	AddDefaultStackFilter("testing.")
+	if strings.Contains(raw.String(), "testing.tRunner") {
+		t.Error("Expected String to respect the default filter")
+	}
+	if (*Stacktrace)(nil).Filter("x") != nil {
+		t.Error("Expected nil Filter result for nil stacktrace")
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, 0},
+		{"standard error", errors.New("std"), 500},
+		{"default severity", New(TestCodeDatabase, "db"), 500},
+		{"critical", New(TestCodeDatabase, "db").WithCriticalSeverity(), 500},
+		{"warning", New(TestCodeDatabase, "db").WithWarningSeverity(), 400},
+		{"info", New(TestCodeDatabase, "db").WithInfoSeverity(), 200},
+		{"field", NewWithField(TestCodeValidation, "bad", "email", "x"), 400},
+		{"explicit", New("NOT_FOUND", "missing").WithHTTPStatus(404), 404},
+		{"explicit in chain", Wrap(fmt.Errorf("ctx: %w", New("NOT_FOUND", "missing").WithHTTPStatus(404)), TestCodeDatabase, "outer"), 404},
+		{"outer wins", Wrap(New("NOT_FOUND", "missing").WithHTTPStatus(404), TestCodeDatabase, "outer").WithHTTPStatus(409), 409},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTPStatus(tt.err); got != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, got)
+			}
+		})
+	}
+
+	data, _ := json.Marshal(New("NOT_FOUND", "missing").WithHTTPStatus(404))
+	if !strings.Contains(string(data), `"http_status":404`) {
+		t.Errorf("Expected http_status in JSON, got %s", data)
+	}
+	data, _ = json.Marshal(New("NOT_FOUND", "missing"))
+	if strings.Contains(string(data), "http_status") {
+		t.Errorf("Expected http_status to be omitted when unset, got %s", data)
+	}
+	if SeverityToHTTPStatus("unknown") != 500 {
+		t.Error("Expected unknown severity to map to 500")
+	}
+}
+
+func TestErrorContextPropagation(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := FromContext(ctx); ok {
+		t.Error("Expected no error in empty context")
+	}
+	if AllFromContext(ctx) != nil {
+		t.Error("Expected no errors in empty context")
+	}
+
+	err := New(TestCodeValidation, "invalid")
+	ctx = IntoContext(ctx, err)
+	if got, ok := FromContext(ctx); !ok || got != err {
+		t.Error("Expected FromContext to return the stored error")
+	}
+
+	authErr := New("AUTH_ERROR", "forbidden")
+	base := AppendToContext(ctx, err)
+	withAuth := AppendToContext(base, authErr)
+	sibling := AppendToContext(base, New(TestCodeDatabase, "sibling"))
+
+	all := AllFromContext(withAuth)
+	if len(all) != 2 || all[0] != err || all[1] != authErr {
+		t.Errorf("Unexpected accumulated errors: %v", all)
+	}
+	if len(AllFromContext(base)) != 1 || AllFromContext(sibling)[1].Code != TestCodeDatabase {
+		t.Error("Expected derived contexts not to share appended errors")
+	}
+
+	all[0] = nil
+	if AllFromContext(withAuth)[0] != err {
+		t.Error("Expected modifications of the returned slice not to affect the context")
+	}
+
+	derived, cancel := context.WithCancel(withAuth)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, ok := FromContext(derived); !ok || got != err {
+				t.Error("Expected concurrent FromContext to see the stored error")
+			}
+			if len(AllFromContext(derived)) != 2 {
+				t.Error("Expected concurrent AllFromContext to see both errors")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestClone(t *testing.T) {
+	cause := errors.New("root")
+	base := Wrap(cause, TestCodeDatabase, "base").WithContext("shared", "yes")
+	_ = Wrap(base, "OUTER_ERROR", "outer")
+	base.Checkpoint()
+
+	clone := base.Clone()
+	if clone == base || clone.Code != base.Code || clone.Message != base.Message || clone.Cause != cause {
+		t.Fatalf("Unexpected clone: %+v", clone)
+	}
+	if clone.Stack == base.Stack || len(clone.Stack.Frames) != len(base.Stack.Frames) {
+		t.Error("Expected stack to be copied")
+	}
+	if clone.Parent() != nil || clone.HasCheckpoint() {
+		t.Error("Expected clone not to inherit navigation links or checkpoint")
+	}
+
+	clone.WithContext("shared", "no").WithContext("branch", 1)
+	clone.Stack.Frames[0] = 0
+	if base.Context["shared"] != "yes" || base.Context["branch"] != nil {
+		t.Errorf("Expected original context to be unchanged, got %v", base.Context)
+	}
+	if base.Stack.Frames[0] == 0 {
+		t.Error("Expected original stack frames to be unchanged")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			branch := base.Clone().WithContext("branch_id", id)
+			if branch.Context["branch_id"] != id {
+				t.Error("Expected branch context to be set")
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestEncodeJSON(t *testing.T) {
+	err := Wrap(errors.New("root"), TestCodeDatabase, "query failed").
+		WithContext("table", "users").
+		AsRetryable()
+
+	var buf bytes.Buffer
+	if encErr := err.EncodeJSON(&buf); encErr != nil {
+		t.Fatalf("EncodeJSON failed: %v", encErr)
+	}
+	marshaled, _ := json.Marshal(err)
+	if buf.String() != string(marshaled)+"\n" {
+		t.Errorf("Expected EncodeJSON to match MarshalJSON:\n%s\n%s", buf.String(), marshaled)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, "null\n"},
+		{"standard error", errors.New("boom"), `{"message":"boom"}` + "\n"},
+		{"structured", err, string(marshaled) + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if encErr := EncodeJSON(&out, tt.err); encErr != nil {
+				t.Fatalf("EncodeJSON failed: %v", encErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, out.String())
+			}
+		})
+	}
+}
+
+func TestJoinCompatibility(t *testing.T) {
+	dbRoot := errors.New("connection reset")
+	joined := errors.Join(
+		Wrap(dbRoot, "DB_ERROR", "query failed"),
+		New("NET_ERROR", "unreachable"),
+	)
+	wrapped := Wrap(joined, TestCodeValidation, "request failed")
+
+	if !HasCode(joined, "DB_ERROR") || !HasCode(wrapped, "NET_ERROR") {
+		t.Error("Expected HasCode to search every joined branch")
+	}
+	if HasCode(wrapped, "MISSING") {
+		t.Error("Expected HasCode to be false for missing code")
+	}
+	if RootCause(wrapped) != dbRoot {
+		t.Errorf("Expected RootCause to follow the first branch, got %v", RootCause(wrapped))
+	}
+	if RootCause(nil) != nil {
+		t.Error("Expected RootCause of nil to be nil")
+	}
+	if codes := len(Chain(wrapped)); codes != 3 {
+		t.Errorf("Expected 3 structured errors in joined chain, got %d", codes)
+	}
+
+	var visited []string
+	WalkChain(wrapped, func(err error) bool {
+		visited = append(visited, err.Error())
+		return len(visited) < 3
+	})
+	want := []string{wrapped.Error(), joined.Error(), "[DB_ERROR]: query failed"}
+	if fmt.Sprint(visited) != fmt.Sprint(want) {
+		t.Errorf("Expected WalkChain to stop after 3 nodes in order, got %q", visited)
+	}
+
+	shared := New("SHARED", "shared")
+	count := 0
+	WalkChain(errors.Join(shared, shared), func(err error) bool {
+		if err == shared {
+			count++
+		}
+		return true
+	})
+	if count != 1 {
+		t.Errorf("Expected shared *Error to be visited once, got %d", count)
+	}
+}
+
+func TestSentinel(t *testing.T) {
+	errNotFound := NewSentinel("NOT_FOUND", "Resource not found")
+	errMissing := NewSentinel("NOT_FOUND", "Also not found")
+
+	if !errors.Is(errNotFound, errNotFound) {
+		t.Error("Expected a sentinel to match itself")
+	}
+	if errors.Is(errNotFound, errMissing) || errors.Is(errMissing, errNotFound) {
+		t.Error("Expected sentinels with identical codes not to match each other")
+	}
+	if errors.Is(New("NOT_FOUND", "other"), errNotFound) {
+		t.Error("Expected a plain error with the same code not to match the sentinel")
+	}
+
+	wrapped := Wrap(fmt.Errorf("lookup: %w", errNotFound), TestCodeDatabase, "failed")
+	if !errors.Is(wrapped, errNotFound) {
+		t.Error("Expected errors.Is to find the sentinel in the chain")
+	}
+	if !IsSentinel(wrapped, errNotFound) || IsSentinel(wrapped, errMissing) {
+		t.Error("Expected IsSentinel to compare by identity")
+	}
+	if !HasCode(Wrap(errMissing, TestCodeDatabase, "x"), "NOT_FOUND") {
+		t.Error("Expected HasCode to keep matching by code")
+	}
+
+	// Code-based matching is unchanged for non-sentinel targets
+	if !errors.Is(errNotFound, &Error{Code: "NOT_FOUND"}) {
+		t.Error("Expected code comparison against non-sentinel targets")
+	}
+	if IsSentinel(nil, errNotFound) || IsSentinel(wrapped, nil) {
+		t.Error("Expected IsSentinel to be false for nil inputs")
+	}
+
+	clone := errNotFound.Clone().WithContext("id", 7)
+	if !errors.Is(clone, errNotFound) || !errors.Is(clone.Clone(), errNotFound) || errors.Is(clone, errMissing) {
+		t.Error("Expected a clone to match the sentinel it was cloned from")
+	}
+	if clone.sentinel || errNotFound.Context["id"] != nil {
+		t.Error("Expected a clone to be an independent, non-sentinel error")
+	}
+	if IsSentinel(clone, errNotFound) {
+		t.Error("Expected IsSentinel to keep comparing by identity")
+	}
+}
+
+type mockSpan struct {
+	attrs map[string]string
+}
+
+func (s *mockSpan) SetAttributes(attrs map[string]string) {
+	if s.attrs == nil {
+		s.attrs = make(map[string]string)
+	}
+	for k, v := range attrs {
+		s.attrs[k] = v
+	}
+}
+
+func TestOpenTelemetryAttributes(t *testing.T) {
+	err := Wrap(errors.New("reset"), TestCodeDatabase, "query failed").
+		WithContext("table", "users").
+		WithContext("attempt", 3)
+
+	span := &mockSpan{}
+	WrapSpan(span, fmt.Errorf("handler: %w", err))
+
+	for key, want := range map[string]string{
+		"error.type":            "DATABASE_ERROR",
+		"error.message":         "query failed",
+		"error.context.table":   "users",
+		"error.context.attempt": "3",
+	} {
+		if span.attrs[key] != want {
+			t.Errorf("Expected %s=%q, got %q", key, want, span.attrs[key])
+		}
+	}
+	if !strings.Contains(span.attrs["exception.stacktrace"], "TestOpenTelemetryAttributes") {
+		t.Error("Expected exception.stacktrace attribute")
+	}
+
+	if _, ok := New(TestCodeValidation, "x").Attributes()["exception.stacktrace"]; ok {
+		t.Error("Expected no stacktrace attribute without stack")
+	}
+
+	plain := &mockSpan{}
+	WrapSpan(plain, errors.New("boom"))
+	if plain.attrs["error.type"] != "*errors.errorString" || plain.attrs["error.message"] != "boom" {
+		t.Errorf("Unexpected attributes for standard error: %v", plain.attrs)
+	}
+
+	empty := &mockSpan{}
+	WrapSpan(empty, nil)
+	WrapSpan(nil, err)
+	if empty.attrs != nil {
+		t.Error("Expected nil error to be ignored")
+	}
+}
+
+func TestStackOptions(t *testing.T) {
+	if New(TestCodeValidation, "plain").Stack != nil {
+		t.Error("Expected New without options to keep skipping the stack")
+	}
+	if Wrap(errors.New("x"), TestCodeDatabase, "wrap").Stack == nil {
+		t.Error("Expected Wrap without options to keep capturing the stack")
+	}
+
+	err := New(TestCodeValidation, "with stack", WithStack(), WithContext("k", "v"))
+	if err.Stack == nil || firstFrameFunction(err.Stack) != "github.com/agilira/go-errors.TestStackOptions" {
+		t.Errorf("Expected stack starting at caller, got %v", err.Stack)
+	}
+	if err.Context["k"] != "v" {
+		t.Error("Expected WithContext option to add context")
+	}
+	if e := NewWithField(TestCodeValidation, "f", "email", "x", WithStack()); e.Stack == nil {
+		t.Error("Expected NewWithField to honor WithStack")
+	}
+	if e := NewWithContext(TestCodeValidation, "c", nil, WithContext("a", 1)); e.Context["a"] != 1 {
+		t.Error("Expected NewWithContext to honor options with nil context")
+	}
+	if e := Wrap(errors.New("x"), TestCodeDatabase, "no stack", WithoutStack()); e.Stack != nil {
+		t.Error("Expected WithoutStack to disable capture in Wrap")
+	}
+}
+
+func TestGlobalStackPolicy(t *testing.T) {
+	defer SetStackPolicy(OnWrapOnly)
+
+	SetStackPolicy(AlwaysCapture)
+	if GlobalStackPolicy() != AlwaysCapture {
+		t.Fatal("Expected policy to be stored")
+	}
+	if e := New(TestCodeValidation, "always"); e.Stack == nil || !strings.HasSuffix(firstFrameFunction(e.Stack), "TestGlobalStackPolicy") {
+		t.Error("Expected AlwaysCapture to capture in New at the call site")
+	}
+	if e := New(TestCodeValidation, "opt out", WithoutStack()); e.Stack != nil {
+		t.Error("Expected WithoutStack to override AlwaysCapture")
+	}
+	if e := WrapNoStack(errors.New("x"), TestCodeDatabase, "hot path"); e.Stack != nil {
+		t.Error("Expected WrapNoStack to stay stack-free")
+	}
+
+	SetStackPolicy(NeverCapture)
+	if e := Wrap(errors.New("x"), TestCodeDatabase, "never"); e.Stack != nil {
+		t.Error("Expected NeverCapture to disable capture in Wrap")
+	}
+	if e := Wrap(errors.New("x"), TestCodeDatabase, "forced", WithStack()); e.Stack == nil {
+		t.Error("Expected WithStack to override NeverCapture")
+	}
+}
+
+func TestErrorRegistry(t *testing.T) {
+	r := NewRegistry()
+	meta := ErrorMeta{
+		DefaultUserMessage: "Service unavailable",
+		DefaultSeverity:    SeverityCritical,
+		DefaultRetryable:   true,
+		DefaultHTTPStatus:  503,
+	}
+	if err := r.Register(TestCodeDatabase, meta); err != nil {
+		t.Fatalf("Unexpected register error: %v", err)
+	}
+	if err := r.Register(TestCodeDatabase, meta); !HasCode(err, ErrCodeAlreadyRegistered) {
+		t.Errorf("Expected duplicate registration error, got %v", err)
+	}
+	_ = r.Register("NO_MESSAGE_B", ErrorMeta{})
+	_ = r.Register("NO_MESSAGE_A", ErrorMeta{})
+
+	if got, ok := r.Lookup(TestCodeDatabase); !ok || got != meta {
+		t.Errorf("Unexpected lookup result: %+v %v", got, ok)
+	}
+	if _, ok := r.Lookup("MISSING"); ok {
+		t.Error("Expected lookup of unknown code to fail")
+	}
+	all := r.All()
+	all["INJECTED"] = ErrorMeta{}
+	if len(r.All()) != 3 {
+		t.Error("Expected All to return a copy")
+	}
+
+	err := r.New(TestCodeDatabase, "Connection refused")
+	if err.UserMsg != "Service unavailable" || err.Severity != SeverityCritical || !err.Retryable || HTTPStatus(err) != 503 {
+		t.Errorf("Expected registered defaults, got %+v", err)
+	}
+	if plain := r.New("UNREGISTERED", "x"); plain.UserMsg != "" || plain.Severity != SeverityError {
+		t.Errorf("Expected plain error for unregistered code, got %+v", plain)
+	}
+
+	if missing := r.Validate(); fmt.Sprint(missing) != "[NO_MESSAGE_A NO_MESSAGE_B]" {
+		t.Errorf("Unexpected Validate result: %v", missing)
+	}
+
+	if err := Register("REGISTRY_TEST_CODE", meta); err != nil {
+		t.Fatalf("Unexpected default register error: %v", err)
+	}
+	if _, ok := Lookup("REGISTRY_TEST_CODE"); !ok {
+		t.Error("Expected package-level Lookup to use DefaultRegistry")
+	}
+}
+
+func TestWrapFmt(t *testing.T) {
+	base := New(TestCodeDatabase, "Query failed")
+	wrapped := base.WrapFmt("while loading user %d", 42)
+
+	if wrapped.Code != TestCodeDatabase {
+		t.Errorf("Expected code %s, got %s", TestCodeDatabase, wrapped.Code)
+	}
+	if wrapped.Message != "Query failed: while loading user 42" {
+		t.Errorf("Expected appended message, got %q", wrapped.Message)
+	}
+	if wrapped.Cause != base {
+		t.Error("Expected receiver to be the cause")
+	}
+	if wrapped.Stack == nil {
+		t.Error("Expected stack trace to be captured")
+	}
+}
+
+func TestFormatPercentW(t *testing.T) {
+	base := New(TestCodeDatabase, "Query failed")
+	err := fmt.Errorf("loading user: %w", base)
+
+	if err.Error() != "loading user: [DATABASE_ERROR]: Query failed" {
+		t.Errorf("Unexpected message %q", err.Error())
+	}
+	var target *Error
+	if !errors.As(err, &target) || target != base {
+		t.Error("Expected errors.As to find the wrapped *Error")
+	}
+}
+
+func TestUnwrapFmt(t *testing.T) {
+	base := New(TestCodeDatabase, "Query failed")
+
+	tests := []struct {
+		name    string
+		err     error
+		wantOK  bool
+		wantMsg string
+	}{
+		{"nil", nil, false, ""},
+		{"structured", base, false, ""},
+		{"plain", fmt.Errorf("plain"), false, ""},
+		{"single", fmt.Errorf("loading user: %w", base), true, "loading user"},
+		{"nested", fmt.Errorf("handler: %w", fmt.Errorf("loading user: %w", base)), true, "handler: loading user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner, msg, ok := UnwrapFmt(tt.err)
+			if ok != tt.wantOK {
+				t.Fatalf("Expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if !ok {
+				return
+			}
+			if inner != base {
+				t.Error("Expected the wrapped *Error to be returned")
+			}
+			if msg != tt.wantMsg {
+				t.Errorf("Expected message %q, got %q", tt.wantMsg, msg)
+			}
+		})
+	}
+}
+
+func TestAppendAndCombine(t *testing.T) {
+	if Combine(nil, nil) != nil {
+		t.Error("Expected Combine of nil errors to be nil")
+	}
+	if Append(nil) != nil {
+		t.Error("Expected Append without errors to be nil")
+	}
+
+	first := New(TestCodeValidation, "Email is required")
+	plain := fmt.Errorf("disk full")
+	combined := Combine(first, nil, plain)
+
+	var multi *MultiError
+	if !errors.As(combined, &multi) {
+		t.Fatalf("Expected *MultiError, got %T", combined)
+	}
+	if multi.ErrorCount() != 2 {
+		t.Fatalf("Expected 2 errors, got %d", multi.ErrorCount())
+	}
+	if multi.Errors()[0] != first {
+		t.Error("Expected *Error values to be stored as-is")
+	}
+	if !errors.Is(combined, plain) {
+		t.Error("Expected errors.Is to find wrapped plain error")
+	}
+
+	second := New(TestCodeDatabase, "Query failed")
+	appended := Append(combined, second, Combine(New("NESTED", "nested")))
+	if !errors.As(appended, &multi) || multi.ErrorCount() != 4 {
+		t.Fatalf("Expected 4 flattened errors, got %v", appended)
+	}
+	if !HasCode(appended, "NESTED") || !errors.Is(appended, second) {
+		t.Error("Expected nested errors to be reachable")
+	}
+
+	var original *MultiError
+	_ = errors.As(combined, &original)
+	if original.ErrorCount() != 2 {
+		t.Error("Expected Append not to modify its input")
+	}
+
+	data, err := json.Marshal(Combine(first))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"errors":[{"code":"VALIDATION_ERROR","message":"Email is required",`) {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+}
+
+func TestUnmarshalJSONStackFrames(t *testing.T) {
+	original := Wrap(New(TestCodeValidation, "inner"), TestCodeDatabase, "outer")
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded Error
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := original.StackFrames()
+	got := decoded.StackFrames()
+	if len(want) == 0 {
+		t.Fatal("Expected original to have stack frames")
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d frames, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Frame %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+	if !strings.HasSuffix(got[0].Function, "TestUnmarshalJSONStackFrames") || got[0].Line == 0 {
+		t.Errorf("Unexpected first frame: %+v", got[0])
+	}
+	if !strings.Contains(fmt.Sprintf("%+v", &decoded), "STACK:") {
+		t.Error("Expected decoded stack in diagnostic output")
+	}
+	if !HasCode(&decoded, TestCodeValidation) {
+		t.Error("Expected cause chain to be preserved")
+	}
+}
+
+func TestParseStack(t *testing.T) {
+	text := "main.run\n\t/app/main.go:12\nbogus line\nmain.main\n\t/app/main.go:5\n"
+	frames := ParseStack(text)
+	if len(frames) != 2 {
+		t.Fatalf("Expected 2 frames, got %d", len(frames))
+	}
+	if frames[0] != (StackFrame{Function: "main.run", File: "/app/main.go", Line: 12, Package: "main"}) {
+		t.Errorf("Unexpected frame: %+v", frames[0])
+	}
+	if frames[1].Function != "main.main" || frames[1].Line != 5 {
+		t.Errorf("Unexpected frame: %+v", frames[1])
+	}
+	if ParseStack("") != nil || New(TestCodeValidation, "x").StackFrames() != nil {
+		t.Error("Expected nil frames for empty stack")
+	}
+}
+
+func TestRegisterHTTPStatus(t *testing.T) {
+	const codeNotFound ErrorCode = "HTTP_TEST_NOT_FOUND"
+	const codeRegistry ErrorCode = "HTTP_TEST_REGISTRY"
+	RegisterHTTPStatus(codeNotFound, 404)
+	defer RegisterHTTPStatus(codeNotFound, 0)
+
+	if got := HTTPStatus(New(codeNotFound, "missing")); got != 404 {
+		t.Errorf("Expected 404, got %d", got)
+	}
+	if got := HTTPStatus(Wrap(New(codeNotFound, "missing"), TestCodeDatabase, "lookup failed")); got != 404 {
+		t.Errorf("Expected registered status through the chain, got %d", got)
+	}
+	if got := HTTPStatus(New(codeNotFound, "missing").WithHTTPStatus(410)); got != 410 {
+		t.Errorf("Expected explicit status to win, got %d", got)
+	}
+
+	RegisterHTTPStatus(codeNotFound, 0)
+	if got := HTTPStatus(New(codeNotFound, "missing")); got != 500 {
+		t.Errorf("Expected severity default after removal, got %d", got)
+	}
+
+	reg := DefaultRegistry
+	DefaultRegistry = NewRegistry()
+	defer func() { DefaultRegistry = reg }()
+	_ = DefaultRegistry.Register(codeRegistry, ErrorMeta{DefaultHTTPStatus: 503})
+	if got := HTTPStatus(New(codeRegistry, "down")); got != 503 {
+		t.Errorf("Expected registry default status, got %d", got)
+	}
+}
+
+func decodeHTTPError(t *testing.T, rec *httptest.ResponseRecorder) HTTPErrorBody {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", ct)
+	}
+	var resp HTTPErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid response %q: %v", rec.Body.String(), err)
+	}
+	return resp.Error
+}
+
+func TestHandlerWritesErrorEnvelope(t *testing.T) {
+	h := Handler(func(w http.ResponseWriter, r *http.Request) error {
+		return New("USER_NOT_FOUND", "no row for id 7").
+			WithUserMessage("User not found").
+			WithHTTPStatus(http.StatusNotFound)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+	body := decodeHTTPError(t, rec)
+	want := HTTPErrorBody{Code: "USER_NOT_FOUND", Message: "User not found", RequestID: "req-42", Status: 404}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("Expected %+v, got %+v", want, body)
+	}
+}
+
+func TestHandlerWithoutError(t *testing.T) {
+	h := Handler(func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Errorf("Expected untouched response, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMiddlewareRecoversPanic(t *testing.T) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("secret internal state")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", rec.Code)
+	}
+	body := decodeHTTPError(t, rec)
+	if body.Code != ErrCodePanic || body.Status != 500 {
+		t.Errorf("Unexpected body: %+v", body)
+	}
+	if strings.Contains(body.Message, "secret") {
+		t.Errorf("Expected technical message not to leak, got %q", body.Message)
+	}
+}
+
+func TestMiddlewareRepanicsAbortHandler(t *testing.T) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	var recovered interface{}
+	func() {
+		defer func() { recovered = recover() }()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	if recovered != http.ErrAbortHandler {
+		t.Errorf("Expected http.ErrAbortHandler to propagate, got %v", recovered)
+	}
+}
+
+func TestWriteHTTPErrorPlainAndClientErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteHTTPError(rec, nil, fmt.Errorf("dial tcp: refused"))
+	body := decodeHTTPError(t, rec)
+	if body.Code != DefaultErrorCode || body.Message != "Internal Server Error" {
+		t.Errorf("Unexpected body for plain error: %+v", body)
+	}
+
+	rec = httptest.NewRecorder()
+	WriteHTTPError(rec, nil, NewWithField(TestCodeValidation, "Email is required", "email", "").AsRetryable())
+	body = decodeHTTPError(t, rec)
+	if rec.Code != 400 || body.Message != "Email is required" || !body.Retryable {
+		t.Errorf("Unexpected body for client error: %d %+v", rec.Code, body)
+	}
+}
+
+func TestStackFormatFrames(t *testing.T) {
+	err := Wrap(New(TestCodeValidation, "inner"), TestCodeDatabase, "outer").WithStackFormat(StackAsFrames)
+	data, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		t.Fatalf("Marshal failed: %v", marshalErr)
+	}
+
+	var raw struct {
+		Stack []StackFrame `json:"stack"`
+	}
+	if jsonErr := json.Unmarshal(data, &raw); jsonErr != nil {
+		t.Fatalf("Expected stack as array of frames: %v (%s)", jsonErr, data)
+	}
+	if len(raw.Stack) == 0 || !strings.HasSuffix(raw.Stack[0].Function, "TestStackFormatFrames") || raw.Stack[0].Line == 0 {
+		t.Errorf("Unexpected frames: %+v", raw.Stack)
+	}
+
+	var decoded Error
+	if jsonErr := json.Unmarshal(data, &decoded); jsonErr != nil {
+		t.Fatalf("Unmarshal failed: %v", jsonErr)
+	}
+	if got := decoded.StackFrames(); len(got) != len(raw.Stack) || got[0] != raw.Stack[0] {
+		t.Errorf("Expected decoded frames %+v, got %+v", raw.Stack, got)
+	}
+	again, _ := json.Marshal(&decoded)
+	if !strings.Contains(string(again), `"stack":[{"function"`) {
+		t.Errorf("Expected decoded error to keep the frames format, got %s", again)
+	}
+}
+
+func TestSetStackFormat(t *testing.T) {
+	defer SetStackFormat(GetStackFormat())
+	SetStackFormat(StackAsFrames)
+
+	err := Wrap(fmt.Errorf("boom"), TestCodeDatabase, "outer")
+	data, _ := json.Marshal(err)
+	if !strings.Contains(string(data), `"stack":[{"function"`) {
+		t.Errorf("Expected package-level frames format, got %s", data)
+	}
+
+	data, _ = json.Marshal(err.WithStackFormat(StackAsString))
+	if !strings.Contains(string(data), `"stack":"`) {
+		t.Errorf("Expected per-error override to win, got %s", data)
+	}
+
+	data, _ = json.Marshal(New(TestCodeValidation, "no stack"))
+	if strings.Contains(string(data), `"stack"`) {
+		t.Errorf("Expected no stack field, got %s", data)
+	}
+}
+
+func TestDefine(t *testing.T) {
+	base := Define("USER_NOT_FOUND", "user not found")
+	def := base.WithUserMessage("We could not find that account").WithSeverity(SeverityWarning)
+
+	if base.WithSeverity(SeverityCritical); base.New().Severity != SeverityError {
+		t.Error("Expected With* methods not to modify the receiver")
+	}
+
+	e1 := def.New(WithContext("user_id", 7))
+	e2 := def.New()
+	if e1 == e2 {
+		t.Fatal("Expected fresh instances")
+	}
+	if e1.Code != "USER_NOT_FOUND" || e1.Message != "user not found" || e1.UserMsg != "We could not find that account" || e1.Severity != SeverityWarning {
+		t.Errorf("Unexpected error: %+v", e1)
+	}
+	if e1.Context["user_id"] != 7 || len(e2.Context) != 0 {
+		t.Error("Expected context not to be shared between instances")
+	}
+
+	cause := fmt.Errorf("no rows")
+	wrapped := def.AsRetryable().Wrap(cause, WithContext("table", "users"))
+	if wrapped.Cause != cause || !wrapped.Retryable || wrapped.Severity != SeverityWarning || wrapped.Context["table"] != "users" {
+		t.Errorf("Unexpected wrapped error: %+v", wrapped)
+	}
+	if wrapped.Stack == nil || !strings.HasSuffix(firstFrameFunction(wrapped.Stack), "TestDefine") {
+		t.Error("Expected stack trace to start at the caller")
+	}
+	if def.New(WithStack()).Stack == nil || !strings.HasSuffix(firstFrameFunction(def.New(WithStack()).Stack), "TestDefine") {
+		t.Error("Expected WithStack to capture from the caller")
+	}
+	if !def.Is(Wrap(wrapped, TestCodeDatabase, "outer")) || def.Is(cause) {
+		t.Error("Expected Is to match the definition code in the chain")
+	}
+	if def.Code() != "USER_NOT_FOUND" || def.Message() != "user not found" {
+		t.Error("Unexpected accessors")
+	}
+}
+
+type requestIDKey struct{}
+
+func TestWithRequestContext(t *testing.T) {
+	defer func() { contextExtractors = nil }()
+	RegisterContextExtractor(nil)
+	RegisterContextExtractor(func(ctx context.Context) map[string]interface{} {
+		if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+			return map[string]interface{}{"request_id": id, "trace_id": "t-" + id}
+		}
+		return nil
+	})
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "abc")
+	if WithRequestContext(ctx, nil) != nil {
+		t.Error("Expected nil for nil error")
+	}
+
+	base := New(TestCodeDatabase, "Query failed").WithContext("trace_id", "explicit")
+	got := WithRequestContext(ctx, base)
+	if got == base || got.Code != base.Code || got.Context["request_id"] != "abc" || got.Context["trace_id"] != "explicit" {
+		t.Errorf("Expected an enriched copy, got %v", got.Context)
+	}
+	if _, leaked := base.Context["request_id"]; leaked {
+		t.Errorf("Expected the original error to stay unmodified, got %v", base.Context)
+	}
+
+	sentinel := NewSentinel(TestCodeDatabase, "Shared failure")
+	if got := WithRequestContext(ctx, sentinel); !errors.Is(got, sentinel) || got.Context["request_id"] != "abc" || len(sentinel.Context) != 0 {
+		t.Errorf("Expected a sentinel to be wrapped, got %+v", got)
+	}
+
+	plain := fmt.Errorf("outer: %w", New(TestCodeValidation, "bad"))
+	wrapped := WithRequestContext(ctx, plain)
+	if wrapped.Cause != plain || wrapped.Code != TestCodeValidation || wrapped.Context["request_id"] != "abc" {
+		t.Errorf("Unexpected wrapped error: %+v", wrapped)
+	}
+
+	if got := WithRequestContext(context.Background(), New(TestCodeDatabase, "x")); len(got.Context) != 0 {
+		t.Errorf("Expected no metadata without request values, got %v", got.Context)
+	}
+}
+
+func TestOnCriticalStackPolicy(t *testing.T) {
+	defer SetStackPolicy(OnWrapOnly)
+	SetStackPolicy(OnCritical)
+
+	if e := New(TestCodeValidation, "plain"); e.Stack != nil {
+		t.Error("Expected no stack for non-critical New")
+	}
+	if e := Wrap(errors.New("x"), TestCodeDatabase, "wrapped"); e.Stack == nil {
+		t.Error("Expected OnCritical to capture in Wrap")
+	}
+	if e := New(TestCodeDatabase, "opt", WithSeverity(SeverityCritical)); e.Stack == nil ||
+		!strings.HasSuffix(firstFrameFunction(e.Stack), "TestOnCriticalStackPolicy") {
+		t.Error("Expected critical severity option to capture at the call site")
+	}
+	if e := New(TestCodeDatabase, "raised").WithCriticalSeverity(); e.Stack == nil ||
+		!strings.HasSuffix(firstFrameFunction(e.Stack), "TestOnCriticalStackPolicy") {
+		t.Error("Expected WithCriticalSeverity to capture at the call site")
+	}
+	if e := New(TestCodeDatabase, "raised").WithSeverity(SeverityCritical); e.Stack == nil ||
+		!strings.HasSuffix(firstFrameFunction(e.Stack), "TestOnCriticalStackPolicy") {
+		t.Error("Expected WithSeverity to capture at the call site")
+	}
+	if e := New(TestCodeDatabase, "suppressed", WithoutStack()).WithCriticalSeverity(); e.Stack != nil {
+		t.Error("Expected WithoutStack to win over OnCritical")
+	}
+	critical := NewTemplate(TestCodeDatabase, TemplateOpts{Severity: SeverityCritical})
+	if e := critical.New("template"); e.Stack == nil ||
+		!strings.HasSuffix(firstFrameFunction(e.Stack), "TestOnCriticalStackPolicy") {
+		t.Error("Expected a critical template to capture at the call site")
+	}
+	registry := NewRegistry()
+	_ = registry.Register(TestCodeDatabase, ErrorMeta{DefaultSeverity: SeverityCritical})
+	if e := registry.New(TestCodeDatabase, "registry"); e.Stack == nil ||
+		!strings.HasSuffix(firstFrameFunction(e.Stack), "TestOnCriticalStackPolicy") {
+		t.Error("Expected a critical registry code to capture at the call site")
+	}
+	if e := NewTemplate(TestCodeDatabase, TemplateOpts{}).New("plain"); e.Stack != nil {
+		t.Error("Expected no stack for a non-critical template")
+	}
+
+	SetStackPolicy(OnWrapOnly)
+	if e := New(TestCodeDatabase, "raised").WithCriticalSeverity(); e.Stack != nil {
+		t.Error("Expected WithCriticalSeverity not to capture under OnWrapOnly")
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{MaxRetries: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 0},
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{5, time.Second},
+		{100, time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d): expected %v, got %v", tt.attempt, tt.want, got)
+		}
+	}
+	if got := (RetryPolicy{InitialBackoff: time.Second, Multiplier: 3}).Backoff(3); got != 9*time.Second {
+		t.Errorf("Expected custom multiplier, got %v", got)
+	}
+
+	err := New(TestCodeDatabase, "Unavailable").WithRetryPolicy(p)
+	if !err.Retryable {
+		t.Error("Expected WithRetryPolicy to mark the error as retryable")
+	}
+	var advisor RetryAdvisor = err
+	if got, ok := advisor.RetryAdvice(); !ok || got != p {
+		t.Errorf("Expected policy %+v, got %+v", p, got)
+	}
+	if got, ok := RetryPolicyOf(fmt.Errorf("outer: %w", Wrap(err, TestCodeValidation, "wrapped"))); !ok || got.MaxRetries != 5 {
+		t.Errorf("Expected policy from the chain, got %+v %v", got, ok)
+	}
+	if _, ok := RetryPolicyOf(New(TestCodeDatabase, "none")); ok {
+		t.Error("Expected no policy")
+	}
+
+	data, _ := json.Marshal(err)
+	var decoded Error
+	if jsonErr := json.Unmarshal(data, &decoded); jsonErr != nil || decoded.RetryPolicy == nil || *decoded.RetryPolicy != p {
+		t.Errorf("Expected policy to round-trip through JSON, got %s", data)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	fast := RetryBackoff(time.Millisecond, 2*time.Millisecond)
+
+	calls := 0
+	err := Retry(ctx, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return New(TestCodeDatabase, "busy").AsRetryable()
+		}
+		return nil
+	}, fast)
+	if err != nil || calls != 3 {
+		t.Errorf("Expected success after 3 calls, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	err = Retry(ctx, func(context.Context) error {
+		calls++
+		return Wrap(New(TestCodeDatabase, "busy").AsRetryable(), TestCodeValidation, "outer")
+	}, fast, RetryMaxAttempts(4), RetryWithoutJitter())
+	var e *Error
+	if !errors.As(err, &e) || calls != 4 || e.Context[RetryAttemptsKey] != 4 {
+		t.Errorf("Expected 4 attempts recorded, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	plain := fmt.Errorf("fatal")
+	err = Retry(ctx, func(context.Context) error {
+		calls++
+		return plain
+	}, fast)
+	if calls != 1 || !errors.Is(err, plain) || !errors.As(err, &e) || e.Context[RetryAttemptsKey] != 1 {
+		t.Errorf("Expected non-retryable error to stop immediately, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	err = Retry(ctx, func(context.Context) error {
+		calls++
+		return New(TestCodeDatabase, "busy").WithRetryPolicy(RetryPolicy{MaxRetries: 1, InitialBackoff: time.Millisecond})
+	})
+	if calls != 2 {
+		t.Errorf("Expected error policy to limit attempts to 2, got %d", calls)
+	}
+}
+
+func TestRetryContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Retry(ctx, func(context.Context) error {
+		calls++
+		cancel()
+		return New(TestCodeDatabase, "Rate limited").WithRetryAfter(time.Hour)
+	}, RetryMaxAttempts(10))
+
+	var e *Error
+	if !errors.As(err, &e) || calls != 1 {
+		t.Fatalf("Expected retries to stop on cancel, got %v after %d calls", err, calls)
+	}
+	if e.Context[RetryStoppedKey] != context.Canceled.Error() {
+		t.Errorf("Expected cancellation to be recorded, got %v", e.Context)
+	}
+
+	shared := New(TestCodeDatabase, "Rate limited").AsRetryable()
+	err = Retry(context.Background(), func(context.Context) error { return shared },
+		RetryMaxAttempts(2), RetryBackoff(time.Millisecond, time.Millisecond))
+	if !errors.As(err, &e) || e == shared || e.Code != TestCodeDatabase || !e.Retryable || e.Context[RetryAttemptsKey] != 2 {
+		t.Errorf("Expected a wrapper keeping the code and retry flag, got %+v", err)
+	}
+	if !errors.Is(err, shared) || len(shared.Context) != 0 {
+		t.Errorf("Expected the shared error to stay unmodified, got %v", shared.Context)
+	}
+}
+
+func TestFormatVerboseThroughForeignWrappers(t *testing.T) {
+	inner := New(TestCodeValidation, "inner failure").WithContext("field", "email")
+	other := New("OTHER_ERROR", "other branch")
+	err := Wrap(fmt.Errorf("middle: %w", errors.Join(inner, other)), TestCodeDatabase, "outer")
+
+	out := fmt.Sprintf("%+v", err)
+	for _, want := range []string{
+		"[DATABASE_ERROR]: outer",
+		"\nCaused by: middle: [VALIDATION_ERROR]: inner failure\n  [OTHER_ERROR]: other branch",
+		"\n  Caused by: [VALIDATION_ERROR]: inner failure",
+		"\n    CONTEXT: field=email",
+		"\n  Caused by: [OTHER_ERROR]: other branch",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+
+	chain := Chain(err)
+	if len(chain) != 3 || chain[1] != inner || chain[2] != other {
+		t.Errorf("Expected Chain to reach every *Error, got %v", chain)
+	}
+}
+
+type mixedChainError struct {
+	msg   string
+	cause error
+}
+
+func (m *mixedChainError) Error() string { return m.msg }
+func (m *mixedChainError) Unwrap() error { return m.cause }
+
+func TestAsDeepMixedChain(t *testing.T) {
+	root := New(TestCodeValidation, "root")
+	mid := &mixedChainError{msg: "mid", cause: Wrap(root, TestCodeDatabase, "db")}
+	err := fmt.Errorf("top: %w", &mixedChainError{msg: "upper", cause: Wrap(mid, "SERVICE_ERROR", "service")})
+
+	var target *Error
+	if !errors.As(err, &target) || target.Code != "SERVICE_ERROR" {
+		t.Errorf("Expected outermost *Error, got %v", target)
+	}
+	var mixed *mixedChainError
+	if !errors.As(target, &mixed) || mixed != mid {
+		t.Errorf("Expected *Error.As to walk into foreign errors, got %v", mixed)
+	}
+	if !errors.As(Wrap(mid, "X", "x"), &mixed) || mixed != mid {
+		t.Error("Expected As to find the foreign error below a wrapper")
+	}
+	if found, ok := FindCode(err, TestCodeValidation); !ok || found != root {
+		t.Errorf("Expected FindCode to reach the root through mixed types, got %v", found)
+	}
+}
+
+func fingerprintAt(id int) string {
+	return Wrap(errors.New("boom"), TestCodeDatabase, fmt.Sprintf("Query for user %d failed", id)).Fingerprint()
+}
+
+func TestFingerprint(t *testing.T) {
+	if fingerprintAt(42) != fingerprintAt(7) {
+		t.Error("Expected messages differing only in numbers to share a fingerprint")
+	}
+	a := New(TestCodeDatabase, "Query failed")
+	if a.Fingerprint() == New(TestCodeValidation, "Query failed").Fingerprint() {
+		t.Error("Expected different codes to produce different fingerprints")
+	}
+	if a.Fingerprint() == New(TestCodeDatabase, "Insert failed").Fingerprint() {
+		t.Error("Expected different messages to produce different fingerprints")
+	}
+	if a.Fingerprint() == New(TestCodeDatabase, "Query failed", WithStack()).Fingerprint() {
+		t.Error("Expected stack frames to be part of the fingerprint")
+	}
+	if got := a.WithFingerprint("custom").Fingerprint(); got != "custom" {
+		t.Errorf("Expected override, got %q", got)
+	}
+	if messageTemplate("id 123 at 4.5") != "id # at #.#" {
+		t.Errorf("Unexpected template %q", messageTemplate("id 123 at 4.5"))
+	}
+}
+
+func TestFingerprintInJSON(t *testing.T) {
+	err := New(TestCodeDatabase, "Query failed")
+	data, _ := json.Marshal(err)
+	if strings.Contains(string(data), "fingerprint") {
+		t.Errorf("Expected no fingerprint by default, got %s", data)
+	}
+
+	SetFingerprintInJSON(true)
+	defer SetFingerprintInJSON(false)
+	data, _ = json.Marshal(err)
+	if !strings.Contains(string(data), `"fingerprint":"`+err.Fingerprint()+`"`) {
+		t.Errorf("Expected fingerprint in JSON, got %s", data)
+	}
+	var decoded Error
+	if jsonErr := json.Unmarshal(data, &decoded); jsonErr != nil || decoded.Fingerprint() != err.Fingerprint() {
+		t.Errorf("Expected fingerprint to survive decoding, got %q", decoded.Fingerprint())
+	}
+}
+
+func TestHooks(t *testing.T) {
+	defer ClearHooks()
+
+	var mu sync.Mutex
+	var reported, created []ErrorCode
+	RegisterHook(nil)
+	RegisterHook(func(e *Error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, e.Code)
+	})
+	RegisterCreationHook(func(e *Error) {
+		mu.Lock()
+		defer mu.Unlock()
+		created = append(created, e.Code)
+	})
+
+	err := New(TestCodeValidation, "bad input")
+	wrapped := Wrap(err, TestCodeDatabase, "failed")
+	if got := wrapped.Report(); got != wrapped {
+		t.Error("Expected Report to return the error for chaining")
+	}
+
+	if len(created) != 2 || created[0] != TestCodeValidation || created[1] != TestCodeDatabase {
+		t.Errorf("Expected creation hooks for both errors, got %v", created)
+	}
+	if len(reported) != 1 || reported[0] != TestCodeDatabase {
+		t.Errorf("Expected one report, got %v", reported)
+	}
+
+	ClearHooks()
+	New(TestCodeValidation, "after clear").Report()
+	if len(created) != 2 || len(reported) != 1 {
+		t.Error("Expected no hooks after ClearHooks")
+	}
+
+	var seen []string
+	RegisterCreationHook(func(e *Error) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, fmt.Sprintf("%s/%s/%d/%t", e.Code, e.Severity, e.HTTPStatusCode, e.Retryable))
+	})
+	NewNotImplemented("Export")
+	NewTemplate(TestCodeDatabase, TemplateOpts{Severity: SeverityCritical, Retryable: true}).New("t")
+	registry := NewRegistry()
+	_ = registry.Register(TestCodeValidation, ErrorMeta{DefaultSeverity: SeverityWarning, DefaultHTTPStatus: http.StatusBadRequest})
+	registry.New(TestCodeValidation, "r")
+	want := []string{
+		"NOT_IMPLEMENTED/warning/501/false",
+		"DATABASE_ERROR/critical/0/true",
+		"VALIDATION_ERROR/warning/400/false",
+	}
+	if fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Errorf("Expected creation hooks to see the defaults %v, got %v", want, seen)
+	}
+}
+
+func TestCategory(t *testing.T) {
+	err := New("CARD_DECLINED", "Card was declined").WithCategory("billing")
+	wrapped := Wrap(err, "CHECKOUT_FAILED", "Checkout failed").WithCategory("checkout")
+
+	if !HasCategory(wrapped, "billing") || !HasCategory(wrapped, "checkout") {
+		t.Error("Expected HasCategory to search the whole chain")
+	}
+	if HasCategory(wrapped, "shipping") || HasCategory(nil, "billing") {
+		t.Error("Expected HasCategory to be false for missing category")
+	}
+	if !HasCategory(errors.Join(errors.New("x"), err), "billing") {
+		t.Error("Expected HasCategory to search joined errors")
+	}
+
+	data, _ := json.Marshal(err)
+	if !strings.Contains(string(data), `"code":"CARD_DECLINED","category":"billing"`) {
+		t.Errorf("Expected category in JSON, got %s", data)
+	}
+	if data, _ := json.Marshal(New(TestCodeValidation, "x")); strings.Contains(string(data), "category") {
+		t.Errorf("Expected category to be omitted when empty, got %s", data)
+	}
+	if group := logErrorJSON(t, err); group["category"] != "billing" {
+		t.Errorf("Expected category in log output, got %v", group)
+	}
+	if !strings.Contains(fmt.Sprintf("%+v", err), "\nCATEGORY: billing") {
+		t.Error("Expected category in diagnostic output")
+	}
+}
+
+func TestLocalizedUserMessage(t *testing.T) {
+	defer SetTranslator(nil)
+	catalog := map[string]string{
+		"en:checkout.card_declined": "Card ending in %s was declined",
+		"it:checkout.card_declined": "La carta che termina con %s è stata rifiutata",
+	}
+	SetTranslator(TranslatorFunc(func(lang, key string, args ...interface{}) (string, bool) {
+		if lang == "" {
+			lang = "en"
+		}
+		format, ok := catalog[lang+":"+key]
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprintf(format, args...), true
+	}))
+
+	err := New("CARD_DECLINED", "Issuer declined the charge").
+		WithUserMessage("Payment failed").
+		WithUserMessageKey("checkout.card_declined", "4242")
+
+	if got := err.LocalizedUserMessage("it"); got != "La carta che termina con 4242 è stata rifiutata" {
+		t.Errorf("Unexpected Italian message %q", got)
+	}
+	if got := err.UserMessage(); got != "Card ending in 4242 was declined" {
+		t.Errorf("Expected default language rendering, got %q", got)
+	}
+	if got := err.LocalizedUserMessage("fr"); got != "Payment failed" {
+		t.Errorf("Expected UserMsg fallback, got %q", got)
+	}
+	if got := New(TestCodeValidation, "tech").WithUserMessageKey("missing").LocalizedUserMessage("en"); got != "tech" {
+		t.Errorf("Expected Message fallback, got %q", got)
+	}
+
+	data, _ := json.Marshal(err)
+	for _, want := range []string{
+		`"user_msg":"Card ending in 4242 was declined"`,
+		`"user_msg_key":"checkout.card_declined"`,
+		`"user_msg_args":["4242"]`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s in %s", want, data)
+		}
+	}
+
+	SetTranslator(nil)
+	if got := err.LocalizedUserMessage("it"); got != "Payment failed" {
+		t.Errorf("Expected fallback without translator, got %q", got)
+	}
+}
+
+func TestWriteHTTPErrorLocalized(t *testing.T) {
+	defer SetTranslator(nil)
+	SetTranslator(TranslatorFunc(func(lang, key string, args ...interface{}) (string, bool) {
+		if lang == "it" && key == "user.not_found" {
+			return "Utente non trovato", true
+		}
+		return "", false
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "it;q=0.9, en")
+	rec := httptest.NewRecorder()
+	WriteHTTPError(rec, req, New("USER_NOT_FOUND", "no row").WithUserMessageKey("user.not_found").WithHTTPStatus(404))
+	if body := decodeHTTPError(t, rec); body.Message != "Utente non trovato" {
+		t.Errorf("Expected localized message, got %q", body.Message)
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	if JoinErrors(TestCodeDatabase, "nothing", nil, nil) != nil {
+		t.Error("Expected nil when every error is nil")
+	}
+
+	a := New(TestCodeValidation, "bad email")
+	plain := fmt.Errorf("disk full")
+	b := New("LOCK_ERROR", "lock lost")
+	joined := JoinErrors("CLEANUP_FAILED", "Failed to release resources", a, nil, plain, b)
+
+	if joined.Code != "CLEANUP_FAILED" || joined.Stack == nil {
+		t.Errorf("Unexpected joined error: %+v", joined)
+	}
+	if causes := joined.Causes(); len(causes) != 3 || causes[0] != a || causes[1] != plain || causes[2] != b {
+		t.Errorf("Expected 3 causes, got %v", causes)
+	}
+	if !errors.Is(joined, plain) || !errors.Is(joined, b) || !HasCode(joined, "LOCK_ERROR") {
+		t.Error("Expected every branch to be searched")
+	}
+	var target *Error
+	if !errors.As(joined.Cause, &target) || target != a {
+		t.Error("Expected errors.As to find the first branch")
+	}
+	if chain := Chain(joined); len(chain) != 3 {
+		t.Errorf("Expected 3 *Error in chain, got %d", len(chain))
+	}
+
+	if causes := Wrap(plain, TestCodeDatabase, "x").Causes(); len(causes) != 1 || causes[0] != plain {
+		t.Errorf("Expected single cause, got %v", causes)
+	}
+	if New(TestCodeDatabase, "x").Causes() != nil {
+		t.Error("Expected no causes")
+	}
+}
+
+func TestRootCauses(t *testing.T) {
+	if RootCauses(nil) != nil {
+		t.Error("Expected nil for nil error")
+	}
+	dbRoot := errors.New("connection reset")
+	netRoot := New("NET_ERROR", "unreachable")
+	diskRoot := errors.New("disk full")
+	tree := Wrap(errors.Join(
+		Wrap(dbRoot, "DB_ERROR", "query failed"),
+		fmt.Errorf("network: %w", netRoot),
+		errors.Join(diskRoot),
+	), TestCodeValidation, "request failed")
+
+	roots := RootCauses(tree)
+	if len(roots) != 3 || roots[0] != dbRoot || roots[1] != netRoot || roots[2] != diskRoot {
+		t.Errorf("Expected every leaf in order, got %v", roots)
+	}
+	if RootCause(tree) != roots[0] {
+		t.Error("Expected RootCause to be the first of RootCauses")
+	}
+	if roots := RootCauses(Wrap(dbRoot, TestCodeDatabase, "x")); len(roots) != 1 || roots[0] != dbRoot {
+		t.Errorf("Expected single root for a linear chain, got %v", roots)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	base := NewBuilder("QUOTA_EXCEEDED", "Quota exceeded").WithUserMessage("Plan limit reached")
+	a := base.WithContext("tenant", "a").WithSeverity(SeverityWarning)
+	b := base.WithContext("tenant", "b").WithField("plan", "free").AsRetryable()
+
+	ea, eb, ebase := a.Build(), b.Build(), base.Build()
+	if ea.Context["tenant"] != "a" || eb.Context["tenant"] != "b" || len(ebase.Context) != 0 {
+		t.Errorf("Expected builders not to share context: %v %v %v", ea.Context, eb.Context, ebase.Context)
+	}
+	if ea.Severity != SeverityWarning || eb.Severity != SeverityError || !eb.Retryable || ea.Retryable {
+		t.Error("Expected builder changes to stay on their own branch")
+	}
+	if eb.Field != "plan" || eb.Value != "free" || ea.UserMsg != "Plan limit reached" {
+		t.Errorf("Unexpected built error: %+v", eb)
+	}
+	if a.Build() == ea {
+		t.Error("Expected Build to return fresh instances")
+	}
+	ea.WithContext("mutated", true)
+	if _, ok := a.Build().Context["mutated"]; ok {
+		t.Error("Expected built errors to be independent of the builder")
+	}
+
+	cause := errors.New("boom")
+	built := base.WithCause(cause).WithCategory("billing").WithHTTPStatus(429).Build(WithStack())
+	if built.Cause != cause || built.Category != "billing" || built.HTTPStatusCode != 429 {
+		t.Errorf("Unexpected built error: %+v", built)
+	}
+	if built.Stack == nil || !strings.HasSuffix(firstFrameFunction(built.Stack), "TestBuilder") {
+		t.Error("Expected stack trace to start at the caller of Build")
+	}
+	if built.Timestamp.IsZero() {
+		t.Error("Expected timestamp to be set")
+	}
+}
+
+func TestBuilderFromSharedError(t *testing.T) {
+	shared := New(TestCodeDatabase, "Database unavailable")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_ = BuilderFrom(shared).WithContext("request", i).Build()
+		}(i)
+	}
+	wg.Wait()
+	if len(shared.Context) != 0 {
+		t.Errorf("Expected shared error to stay untouched, got %v", shared.Context)
+	}
+
+	sentinel := NewSentinel(TestCodeDatabase, "Database unavailable")
+	built := BuilderFrom(sentinel).WithContext("request", 1).Build()
+	if !errors.Is(built, sentinel) || errors.Is(built, NewSentinel(TestCodeDatabase, "other")) {
+		t.Error("Expected errors built from a sentinel to match it")
+	}
+	if len(sentinel.Context) != 0 {
+		t.Errorf("Expected the sentinel to stay untouched, got %v", sentinel.Context)
+	}
+}
+
+func TestSensitiveContext(t *testing.T) {
+	err := New("AUTH_FAILED", "Invalid credentials").
+		WithSensitiveContext("email", "alice@example.com").
+		WithContext("attempt", 3)
+
+	if got := err.Context["email"].(SensitiveValue).Reveal(); got != "alice@example.com" {
+		t.Errorf("Expected Reveal to return the value, got %v", got)
+	}
+	outputs := map[string]string{
+		"%+v":   fmt.Sprintf("%+v", err),
+		"%#v":   fmt.Sprintf("%#v", err.Context["email"]),
+		"attrs": fmt.Sprint(err.Attributes()),
+	}
+	data, _ := json.Marshal(err)
+	outputs["json"] = string(data)
+	for name, out := range outputs {
+		if strings.Contains(out, "alice") {
+			t.Errorf("Expected %s output to be redacted, got %s", name, out)
+		}
+	}
+	if !strings.Contains(outputs["json"], `"email":"[REDACTED]"`) {
+		t.Errorf("Expected redacted JSON value, got %s", outputs["json"])
+	}
+	if group := logErrorJSON(t, err); group["email"] != RedactedValue {
+		t.Errorf("Expected redacted log value, got %v", group["email"])
+	}
+}
+
+func TestRedactKeys(t *testing.T) {
+	defer ClearRedaction()
+	RedactKeys("Password")
+	RedactPattern(regexp.MustCompile(`(?i)token`))
+	RedactPattern(nil)
+
+	err := NewWithField(TestCodeValidation, "Password too short", "password", "hunter2").
+		WithContext("api_token", "abc123").
+		WithContext("user", "bob")
+
+	data, _ := json.Marshal(err)
+	for _, secret := range []string{"hunter2", "abc123"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q to be redacted in %s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), `"user":"bob"`) || !strings.Contains(string(data), `"value":"[REDACTED]"`) {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+	if out := fmt.Sprintf("%+v", err); strings.Contains(out, "hunter2") || strings.Contains(out, "abc123") {
+		t.Errorf("Expected diagnostic output to be redacted:\n%s", out)
+	}
+	group := logErrorJSON(t, err)
+	if group["api_token"] != RedactedValue || group["value"] != RedactedValue || group["user"] != "bob" {
+		t.Errorf("Unexpected log group %v", group)
+	}
+	if err.Context["api_token"] != "abc123" || err.Value != "hunter2" {
+		t.Error("Expected the error itself not to be modified")
+	}
+
+	ClearRedaction()
+	if data, _ := json.Marshal(err); !strings.Contains(string(data), "abc123") {
+		t.Error("Expected ClearRedaction to remove registered keys")
+	}
+}
+
+func TestPublicError(t *testing.T) {
+	err := Wrap(errors.New("pq: password authentication failed"), TestCodeDatabase, "Query on users failed").
+		WithUserMessage("Service temporarily unavailable").
+		WithContext("request_id", "req-1").
+		WithContext("table", "users").
+		AsRetryable()
+
+	data, jsonErr := err.MarshalPublicJSON()
+	if jsonErr != nil {
+		t.Fatalf("MarshalPublicJSON failed: %v", jsonErr)
+	}
+	want := `{"code":"DATABASE_ERROR","message":"Service temporarily unavailable","retryable":true,"correlation_id":"req-1"}`
+	if string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+
+	err.WithContext("correlation_id", "corr-9")
+	if got := err.PublicError().CorrelationID; got != "corr-9" {
+		t.Errorf("Expected correlation_id to take precedence, got %q", got)
+	}
+
+	bare := New(TestCodeValidation, "internal detail").WithHTTPStatus(http.StatusConflict)
+	if pub := bare.PublicError(); pub.Message != "Conflict" || pub.CorrelationID != "" {
+		t.Errorf("Expected status text instead of technical message, got %+v", pub)
+	}
+}
+
+func TestWrapCtxTraceIDs(t *testing.T) {
+	ctx := ContextWithTrace(context.Background(), "trace-1", "span-1")
+	err := WrapCtx(ctx, errors.New("timeout"), TestCodeDatabase, "Query failed")
+
+	if err.TraceID != "trace-1" || err.SpanID != "span-1" {
+		t.Errorf("Expected IDs from context, got %q %q", err.TraceID, err.SpanID)
+	}
+	if err.Stack == nil || !strings.HasSuffix(firstFrameFunction(err.Stack), "TestWrapCtxTraceIDs") {
+		t.Error("Expected stack trace to start at the caller")
+	}
+	inner := New(TestCodeValidation, "bad input")
+	var created *Error
+	RegisterCreationHook(func(e *Error) {
+		if e.Code == TestCodeDatabase {
+			created = e
+		}
+	})
+	wrapped := WrapCtx(ctx, inner, TestCodeDatabase, "Query failed")
+	ClearHooks()
+	if created != wrapped || created.TraceID != "trace-1" || inner.Parent() != wrapped {
+		t.Error("Expected hooks and Parent to see the returned error with its trace IDs")
+	}
+	data, _ := json.Marshal(err)
+	if !strings.Contains(string(data), `"trace_id":"trace-1","span_id":"span-1"`) {
+		t.Errorf("Expected IDs in JSON, got %s", data)
+	}
+	if err.ToEventPayload()["trace_id"] != "trace-1" || err.PublicError().CorrelationID != "trace-1" {
+		t.Error("Expected trace ID in event payload and public view")
+	}
+	if group := logErrorJSON(t, err); group["trace_id"] != "trace-1" || group["span_id"] != "span-1" {
+		t.Errorf("Expected IDs in log output, got %v", group)
+	}
+
+	if plain := WrapCtx(context.Background(), errors.New("x"), TestCodeDatabase, "y"); plain.TraceID != "" {
+		t.Error("Expected no trace ID without one in the context")
+	}
+}
+
+func TestSetTraceExtractor(t *testing.T) {
+	defer SetTraceExtractor(nil)
+	SetTraceExtractor(func(ctx context.Context) (string, string) {
+		return "sdk-trace", "sdk-span"
+	})
+	err := WrapCtx(context.Background(), errors.New("x"), TestCodeDatabase, "y")
+	if err.TraceID != "sdk-trace" || err.SpanID != "sdk-span" {
+		t.Errorf("Expected custom extractor, got %q %q", err.TraceID, err.SpanID)
+	}
+
+	SetTraceExtractor(nil)
+	err = WrapCtx(ContextWithTrace(context.Background(), "t", "s"), errors.New("x"), TestCodeDatabase, "y")
+	if err.TraceID != "t" {
+		t.Error("Expected nil to restore the default extractor")
+	}
+	if e := New(TestCodeValidation, "x").WithTraceID("a", "b"); e.TraceID != "a" || e.SpanID != "b" {
+		t.Error("Expected WithTraceID to set both IDs")
+	}
+}
+
+type countingSink struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (s *countingSink) IncError(code ErrorCode, severity Severity, event MetricsEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[string(code)+"/"+string(severity)+"/"+string(event)]++
+}
+
+func TestMetricsSink(t *testing.T) {
+	sink := &countingSink{counts: make(map[string]int)}
+	SetMetricsSink(sink)
+	defer SetMetricsSink(nil)
+
+	New(TestCodeValidation, "a")
+	New(TestCodeValidation, "b", WithSeverity(SeverityWarning))
+	Wrap(errors.New("x"), TestCodeDatabase, "c").WithCriticalSeverity().Report()
+
+	want := map[string]int{
+		"VALIDATION_ERROR/error/created":   1,
+		"VALIDATION_ERROR/warning/created": 1,
+		"DATABASE_ERROR/error/created":     1,
+		"DATABASE_ERROR/critical/reported": 1,
+	}
+	if fmt.Sprint(sink.counts) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, sink.counts)
+	}
+
+	SetMetricsSink(nil)
+	New(TestCodeValidation, "after removal").Report()
+	if len(sink.counts) != 4 {
+		t.Error("Expected no metrics after removing the sink")
+	}
+}
+
+func TestToProblemDetails(t *testing.T) {
+	defer func(base string) { ProblemTypeBase = base }(ProblemTypeBase)
+	defer ClearRedaction()
+	RedactKeys("email")
+	ProblemTypeBase = "https://errors.example.com/"
+
+	err := New("USER_NOT_FOUND", "no row for id 7").
+		WithUserMessage("User not found").
+		WithHTTPStatus(http.StatusNotFound).
+		WithContext("instance", "/users/7").
+		WithContext("user_id", 7).
+		WithContext("email", "a@example.com").
+		AsRetryable()
+
+	data, jsonErr := json.Marshal(ToProblemDetails(err))
+	if jsonErr != nil {
+		t.Fatalf("Marshal failed: %v", jsonErr)
+	}
+	var doc map[string]interface{}
+	_ = json.Unmarshal(data, &doc)
+	want := map[string]interface{}{
+		"type":      "https://errors.example.com/USER_NOT_FOUND",
+		"title":     "Not Found",
+		"status":    float64(404),
+		"detail":    "User not found",
+		"instance":  "/users/7",
+		"code":      "USER_NOT_FOUND",
+		"retryable": true,
+		"user_id":   float64(7),
+		"email":     RedactedValue,
+	}
+	if fmt.Sprint(doc) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, doc)
+	}
+
+	plain := ToProblemDetails(errors.New("boom"))
+	if plain.Type != "about:blank" || plain.Status != 500 || plain.Detail != "" || plain.Extensions != nil {
+		t.Errorf("Unexpected document for plain error: %+v", plain)
+	}
+	if ToProblemDetails(nil) != nil {
+		t.Error("Expected nil for nil error")
+	}
+}
+
+func TestFromProblemDetails(t *testing.T) {
+	doc := `{"type":"https://errors.example.com/OUT_OF_CREDIT","title":"Forbidden","status":403,` +
+		`"detail":"Your balance is 30, but that costs 50","instance":"/account/12345/msgs/abc","balance":30,"retryable":true}`
+	e, err := FromProblemDetails([]byte(doc))
+	if err != nil {
+		t.Fatalf("FromProblemDetails failed: %v", err)
+	}
+	if e.Code != "OUT_OF_CREDIT" || e.HTTPStatusCode != 403 || !e.Retryable {
+		t.Errorf("Unexpected error: %+v", e)
+	}
+	if e.Message != "Your balance is 30, but that costs 50" || e.UserMessage() != e.Message {
+		t.Errorf("Unexpected message %q", e.Message)
+	}
+	if e.Context["balance"] != float64(30) || e.Context["instance"] != "/account/12345/msgs/abc" {
+		t.Errorf("Unexpected context %v", e.Context)
+	}
+
+	roundTrip, _ := json.Marshal(ToProblemDetails(New("RATE_LIMITED", "x").WithUserMessage("Slow down").WithHTTPStatus(429)))
+	back, err := FromProblemDetails(roundTrip)
+	if err != nil || back.Code != "RATE_LIMITED" || back.HTTPStatusCode != 429 || back.Message != "Slow down" {
+		t.Errorf("Expected round trip, got %+v (%v)", back, err)
+	}
+
+	if e, _ := FromProblemDetails([]byte(`{"title":"Bad Request","status":400}`)); e.Code != DefaultErrorCode || e.Message != "Bad Request" {
+		t.Errorf("Expected defaults for about:blank, got %+v", e)
+	}
+	if _, err := FromProblemDetails([]byte(`not json`)); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+func TestStacktraceResolve(t *testing.T) {
+	frames := CaptureStacktrace(0).Resolve()
+	if len(frames) == 0 {
+		t.Fatal("Expected resolved frames")
+	}
+	f := frames[0]
+	if f.Function != "github.com/agilira/go-errors.TestStacktraceResolve" || f.Package != "github.com/agilira/go-errors" {
+		t.Errorf("Unexpected frame: %+v", f)
+	}
+	if !strings.HasSuffix(f.File, "errors_test.go") || f.Line == 0 {
+		t.Errorf("Unexpected location: %s:%d", f.File, f.Line)
+	}
+	var nilStack *Stacktrace
+	if nilStack.Resolve() != nil {
+		t.Error("Expected nil frames for nil stack")
+	}
+
+	tests := map[string]string{
+		"main.main": "main",
+		"github.com/agilira/go-errors.(*Error).Wrap": "github.com/agilira/go-errors",
+		"example.com/a.b/pkg.Func.func1":             "example.com/a.b/pkg",
+		"nodot":                                      "",
+	}
+	for fn, want := range tests {
+		if got := functionPackage(fn); got != want {
+			t.Errorf("functionPackage(%q): expected %q, got %q", fn, want, got)
+		}
+	}
+}
+
+func TestFormatWidthAndQuoting(t *testing.T) {
+	err := New(TestCodeValidation, "line1\nline2")
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"%s", "[VALIDATION_ERROR]: line1\nline2"},
+		{"%q", `"[VALIDATION_ERROR]: line1\nline2"`},
+		{"%.10s", "[VALIDATIO"},
+		{"%30v|", fmt.Sprintf("%30s|", err.Error())},
+		{"%-30s|", fmt.Sprintf("%-30s|", err.Error())},
+		{"%d", err.Error()},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, err); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.format, tt.want, got)
+		}
+	}
+}
+
+func TestSetClock(t *testing.T) {
+	fixed := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time { return fixed }))
+	defer SetClock(nil)
+
+	if err := New(TestCodeValidation, "frozen"); !err.Timestamp.Equal(fixed) {
+		t.Errorf("Expected timestamp %v, got %v", fixed, err.Timestamp)
+	}
+	if err := Wrap(fmt.Errorf("cause"), TestCodeDatabase, "wrapped"); !err.Timestamp.Equal(fixed) {
+		t.Errorf("Expected wrapped timestamp %v, got %v", fixed, err.Timestamp)
+	}
+
+	SetClock(nil)
+	if err := New(TestCodeValidation, "default"); err.Timestamp.Equal(fixed) || err.Timestamp.IsZero() {
+		t.Errorf("Expected default clock timestamp, got %v", err.Timestamp)
+	}
+}
+
+func TestSetTimestamps(t *testing.T) {
+	SetTimestamps(false)
+	defer SetTimestamps(true)
+
+	if err := New(TestCodeValidation, "no time"); !err.Timestamp.IsZero() {
+		t.Errorf("Expected zero timestamp, got %v", err.Timestamp)
+	}
+
+	SetTimestamps(true)
+	if err := New(TestCodeValidation, "time"); err.Timestamp.IsZero() {
+		t.Error("Expected timestamp after re-enabling")
+	}
+}
+
+func TestRegistryCatalog(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(TestCodeValidation, ErrorMeta{DefaultUserMessage: "Invalid input", DefaultSeverity: SeverityWarning, DefaultHTTPStatus: http.StatusBadRequest})
+	_ = r.Register(TestCodeDatabase, ErrorMeta{DefaultUserMessage: "Try again later", DefaultRetryable: true, DefaultHTTPStatus: http.StatusServiceUnavailable})
+
+	catalog := r.Catalog()
+	if len(catalog) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(catalog))
+	}
+	if catalog[0].Code != TestCodeDatabase || catalog[1].Code != TestCodeValidation {
+		t.Errorf("Expected entries sorted by code, got %v", catalog)
+	}
+	if !catalog[0].Retryable || catalog[0].HTTPStatus != http.StatusServiceUnavailable {
+		t.Errorf("Expected retryable 503 entry, got %+v", catalog[0])
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteCatalog(&buf); err != nil {
+		t.Fatalf("WriteCatalog failed: %v", err)
+	}
+	var decoded []CatalogEntry
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Catalog is not valid JSON: %v", err)
+	}
+	if len(decoded) != 2 || decoded[1].UserMessage != "Invalid input" || decoded[1].Severity != SeverityWarning {
+		t.Errorf("Unexpected decoded catalog: %+v", decoded)
+	}
+}
+
+func TestWrapWithContextAndField(t *testing.T) {
+	cause := fmt.Errorf("connection reset")
+	ctx := map[string]interface{}{"table": "users"}
+	err := WrapWithContext(cause, TestCodeDatabase, "Query failed", ctx, WithContext("attempt", 2))
+	if err.Cause != cause || err.Code != TestCodeDatabase {
+		t.Errorf("Expected wrapped cause with code %s, got %v", TestCodeDatabase, err)
+	}
+	if err.Context["table"] != "users" || err.Context["attempt"] != 2 {
+		t.Errorf("Expected merged context, got %v", err.Context)
+	}
+	err.Context["table"] = "orders"
+	if ctx["table"] != "users" {
+		t.Error("Expected caller's context map to be copied")
+	}
+	if err.Stack == nil {
+		t.Error("Expected stack trace on wrapped error")
+	}
+
+	fieldErr := WrapWithField(cause, TestCodeValidation, "Invalid email", "email", "bad@")
+	if fieldErr.Field != "email" || fieldErr.Value != "bad@" || fieldErr.Cause != cause {
+		t.Errorf("Expected field email=bad@ with cause, got %+v", fieldErr)
+	}
+}
+
+func TestWrapIf(t *testing.T) {
+	if err := WrapIf(nil, TestCodeDatabase, "Commit failed"); err != nil {
+		t.Errorf("Expected nil for nil error, got %v", err)
+	}
+	cause := fmt.Errorf("disk full")
+	err := WrapIf(cause, TestCodeDatabase, "Commit failed")
+	var e *Error
+	if !errors.As(err, &e) || e.Code != TestCodeDatabase || e.Cause != cause {
+		t.Errorf("Expected wrapped error with code %s, got %v", TestCodeDatabase, err)
+	}
+	if e.Stack == nil || !strings.Contains(firstFrameFunction(e.Stack), "TestWrapIf") {
+		t.Error("Expected stack trace starting at the caller")
+	}
+}
+
+func TestWrapUnlessCode(t *testing.T) {
+	if err := WrapUnlessCode(nil, TestCodeValidation, TestCodeDatabase, "Save failed"); err != nil {
+		t.Errorf("Expected nil for nil error, got %v", err)
+	}
+
+	validation := New(TestCodeValidation, "Email is required")
+	wrapped := Wrap(validation, "SERVICE_ERROR", "Handler failed")
+	if err := WrapUnlessCode(wrapped, TestCodeValidation, TestCodeDatabase, "Save failed"); err != wrapped {
+		t.Errorf("Expected error carrying skip code to pass through, got %v", err)
+	}
+
+	cause := fmt.Errorf("timeout")
+	err := WrapUnlessCode(cause, TestCodeValidation, TestCodeDatabase, "Save failed")
+	if !HasCode(err, TestCodeDatabase) || !errors.Is(err, cause) {
+		t.Errorf("Expected wrapped error with code %s, got %v", TestCodeDatabase, err)
+	}
+}
+
+func TestSeverityOrdering(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Severity
+		ok    bool
+	}{
+		{"critical", SeverityCritical, true},
+		{" Warning ", SeverityWarning, true},
+		{"INFO", SeverityInfo, true},
+		{"fatal", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseSeverity(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseSeverity(%q): expected %q/%v, got %q/%v", tt.input, tt.want, tt.ok, got, ok)
+		}
+	}
+
+	if !SeverityCritical.AtLeast(SeverityError) || SeverityWarning.AtLeast(SeverityError) {
+		t.Error("Expected critical >= error > warning")
+	}
+	if !SeverityInfo.AtLeast(SeverityInfo) {
+		t.Error("Expected a severity to be at least itself")
+	}
+
+	err := New(TestCodeValidation, "Invalid input").WithWarningSeverity()
+	if err.AtLeast(SeverityError) || !err.AtLeast(SeverityWarning) {
+		t.Errorf("Expected warning error to be at least warning but not error")
+	}
+
+	data, _ := json.Marshal(err)
+	var decoded map[string]interface{}
+	_ = json.Unmarshal(data, &decoded)
+	if decoded["severity"] != "warning" {
+		t.Errorf("Expected severity to marshal as string, got %v", decoded["severity"])
+	}
+}
+
+func TestMaxSeverity(t *testing.T) {
+	if got := MaxSeverity(nil); got != "" {
+		t.Errorf("Expected empty severity for nil, got %q", got)
+	}
+	if got := MaxSeverity(fmt.Errorf("plain")); got != "" {
+		t.Errorf("Expected empty severity without *Error, got %q", got)
+	}
+
+	critical := New(TestCodeDatabase, "Disk corrupted").WithCriticalSeverity()
+	wrapped := Wrap(critical, "SERVICE_ERROR", "Save failed").WithWarningSeverity()
+	if got := MaxSeverity(wrapped); got != SeverityCritical {
+		t.Errorf("Expected critical from inner error, got %q", got)
+	}
+
+	joined := fmt.Errorf("batch: %w", errors.Join(
+		New(TestCodeValidation, "a").WithInfoSeverity(),
+		Wrap(critical, TestCodeDatabase, "b").WithWarningSeverity(),
+	))
+	if got := MaxSeverity(joined); got != SeverityCritical {
+		t.Errorf("Expected critical from joined branch, got %q", got)
+	}
+	if !HasSeverity(joined, SeverityInfo) || !HasSeverity(joined, SeverityCritical) {
+		t.Error("Expected HasSeverity to find info and critical in joined tree")
+	}
+	if HasSeverity(joined, SeverityError) {
+		t.Error("Expected no error-level severity in joined tree")
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	agg := NewAggregator(time.Minute)
+	if agg.Add(nil) {
+		t.Error("Expected nil error to be ignored")
+	}
+
+	newErr := func(id int) *Error {
+		return New(TestCodeDatabase, fmt.Sprintf("Query failed for user %d", id))
+	}
+	if !agg.Add(newErr(1)) {
+		t.Error("Expected first occurrence to be reported")
+	}
+	for i := 2; i <= 5; i++ {
+		if agg.Add(newErr(i)) {
+			t.Errorf("Expected occurrence %d to be deduplicated", i)
+		}
+	}
+	if !agg.Add(fmt.Errorf("timeout after 30s")) || agg.Add(fmt.Errorf("timeout after 45s")) {
+		t.Error("Expected plain errors to be grouped by message template")
+	}
+
+	snapshot := agg.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(snapshot))
+	}
+	if snapshot[0].Code != TestCodeDatabase || snapshot[0].Count != 5 {
+		t.Errorf("Expected database group with 5 occurrences first, got %+v", snapshot[0])
+	}
+	if snapshot[1].Count != 2 || snapshot[1].Message != "timeout after 30s" {
+		t.Errorf("Expected plain group with 2 occurrences, got %+v", snapshot[1])
+	}
+
+	if flushed := agg.Flush(); len(flushed) != 2 {
+		t.Errorf("Expected Flush to return 2 groups, got %d", len(flushed))
+	}
+	if len(agg.Snapshot()) != 0 || !agg.Add(newErr(6)) {
+		t.Error("Expected Flush to reset the aggregator")
+	}
+}
+
+func TestAggregatorWindow(t *testing.T) {
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time { return clock }))
+	defer SetClock(nil)
+
+	agg := NewAggregator(time.Minute)
+	err := New(TestCodeValidation, "Invalid input")
+	for i := 0; i < 1000; i++ {
+		agg.Add(err)
+	}
+	clock = clock.Add(2 * time.Minute)
+
+	if snapshot := agg.Snapshot(); len(snapshot) != 1 || snapshot[0].Count != 1000 {
+		t.Errorf("Expected the expired group to keep its count, got %+v", snapshot)
+	}
+	if !agg.Add(err) {
+		t.Error("Expected occurrence after the window to start a new group")
+	}
+	flushed := agg.Flush()
+	if len(flushed) != 1 || flushed[0].Count != 1001 || !flushed[0].LastSeen.After(flushed[0].FirstSeen) {
+		t.Errorf("Expected Flush to report every window since the last flush, got %+v", flushed)
+	}
+	if len(agg.Flush()) != 0 {
+		t.Error("Expected Flush to reset the expired groups")
+	}
+
+	for i := 0; i < maxExpiredAggregates+10; i++ {
+		agg.Add(New(TestCodeDatabase, "failure").WithFingerprint(fmt.Sprint("group-", i)))
+	}
+	clock = clock.Add(2 * time.Minute)
+	entries := agg.Snapshot()
+	if len(entries) != maxExpiredAggregates+1 || len(agg.expired) != maxExpiredAggregates+1 {
+		t.Fatalf("Expected %d kept groups plus the overflow entry, got %d", maxExpiredAggregates, len(entries))
+	}
+	if entries[0].Fingerprint != "" || entries[0].Count != 10 {
+		t.Errorf("Expected the overflow entry to count the extra groups, got %+v", entries[0])
+	}
+}
+
+func TestRecover(t *testing.T) {
+	run := func(v interface{}) (err error) {
+		defer Recover(&err, "JOB_PANIC", "Job processing panicked")
+		if v != nil {
+			panic(v)
+		}
+		return nil
+	}
+
+	if err := run(nil); err != nil {
+		t.Errorf("Expected nil without panic, got %v", err)
+	}
+
+	err := run("index out of range")
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected *Error, got %T", err)
+	}
+	if e.Code != "JOB_PANIC" || e.Message != "Job processing panicked" {
+		t.Errorf("Expected JOB_PANIC with custom message, got %v", e)
+	}
+	if PanicValue(err) != "index out of range" || e.Severity != SeverityCritical {
+		t.Errorf("Expected panic value in context and critical severity, got %v %s", PanicValue(err), e.Severity)
+	}
+	if fn := firstFrameFunction(e.Stack); !strings.Contains(fn, "TestRecover") {
+		t.Errorf("Expected stack to start at the panic site, got %s", fn)
+	}
+}
+
+func TestRecoverFunc(t *testing.T) {
+	cause := errors.New("plain failure")
+	if err := RecoverFunc(func() error { return cause }); err != cause {
+		t.Errorf("Expected fn error to be returned, got %v", err)
+	}
+
+	boom := errors.New("boom")
+	err := RecoverFunc(func() error { panic(boom) })
+	if !HasCode(err, ErrCodePanic) || !errors.Is(err, boom) {
+		t.Errorf("Expected panic converted with ErrCodePanic and cause, got %v", err)
+	}
+
+	defer ClearHooks()
+	defer SetAutoErrorID(false)
+	SetAutoErrorID(true)
+	var created *Error
+	RegisterCreationHook(func(e *Error) { created = e })
+	err = RecoverFunc(func() error { panic("boom") })
+	if created == nil || error(created) != err || created.Message != "boom" || created.Severity != SeverityCritical {
+		t.Errorf("Expected creation hooks to see the panic error, got %v", created)
+	}
+	if created.ErrorID == "" || created.Stack == nil || !strings.Contains(firstFrameFunction(created.Stack), "TestRecoverFunc") {
+		t.Errorf("Expected an error ID and the panic stack, got %q %s", created.ErrorID, firstFrameFunction(created.Stack))
+	}
+}
+
+func TestGroup(t *testing.T) {
+	var g Group
+	if err := g.Wait(); err != nil {
+		t.Errorf("Expected nil for empty group, got %v", err)
+	}
+
+	cause := errors.New("connection refused")
+	g.Go("ok", func() error { return nil })
+	g.Go("db", func() error { return New(TestCodeDatabase, "Query failed") })
+	g.Go("net", func() error { return cause })
+	g.Go("panic", func() error { panic("boom") })
+
+	err := g.Wait()
+	var m *MultiError
+	if !errors.As(err, &m) || m.ErrorCount() != 3 {
+		t.Fatalf("Expected MultiError with 3 failures, got %v", err)
+	}
+
+	errs := m.Errors()
+	if errs[0].Code != TestCodeDatabase || errs[0].Context[TaskNameKey] != "db" || errs[0].Context[TaskIndexKey] != 1 {
+		t.Errorf("Expected db failure at index 1 with its code, got %v %v", errs[0], errs[0].Context)
+	}
+	if errs[1].Code != DefaultErrorCode || errs[1].Message != "connection refused" || errs[1].Context[TaskIndexKey] != 2 {
+		t.Errorf("Expected plain failure at index 2, got %v %v", errs[1], errs[1].Context)
+	}
+	if errs[2].Code != ErrCodePanic || errs[2].Context[TaskNameKey] != "panic" {
+		t.Errorf("Expected panic failure, got %v %v", errs[2], errs[2].Context)
+	}
+	if !errors.Is(err, cause) || !IsPanic(errs[2]) {
+		t.Error("Expected causes to stay reachable through the MultiError")
+	}
+}
+
+func TestValidation(t *testing.T) {
+	v := NewValidation()
+	v.Require(true, "name", "required")
+	if !v.Valid() || v.Err() != nil {
+		t.Fatal("Expected no error when every requirement holds")
+	}
+
+	v.Require(false, "username", "required").
+		Require(len("short") >= 8, "password", "must be at least 8 characters")
+	err := v.Err()
+	var e *Error
+	if !errors.As(err, &e) || e.Code != ErrCodeValidationFailed {
+		t.Fatalf("Expected *Error with code %s, got %v", ErrCodeValidationFailed, err)
+	}
+	if HTTPStatus(err) != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422, got %d", HTTPStatus(err))
+	}
+	if len(e.FieldErrors) != 2 || e.FieldErrors[0].Field != "username" {
+		t.Fatalf("Expected 2 field errors, got %+v", e.FieldErrors)
+	}
+
+	data, _ := json.Marshal(err)
+	var decoded struct {
+		Code   string       `json:"code"`
+		Fields []FieldError `json:"fields"`
+	}
+	if jerr := json.Unmarshal(data, &decoded); jerr != nil {
+		t.Fatalf("Invalid JSON: %v", jerr)
+	}
+	if decoded.Code != string(ErrCodeValidationFailed) || len(decoded.Fields) != 2 || decoded.Fields[1].Field != "password" {
+		t.Errorf("Expected fields array in JSON, got %s", data)
+	}
+
+	var roundTrip Error
+	if jerr := json.Unmarshal(data, &roundTrip); jerr != nil {
+		t.Fatalf("Unmarshal failed: %v", jerr)
+	}
+	if len(roundTrip.FieldErrors) != 2 || roundTrip.FieldErrors[1].Message != "must be at least 8 characters" {
+		t.Errorf("Expected field errors to round-trip, got %+v", roundTrip.FieldErrors)
+	}
+}
+
+func TestWriteHTTPErrorValidationFields(t *testing.T) {
+	err := NewValidation().Require(false, "email", "required").Err()
+	rec := httptest.NewRecorder()
+	WriteHTTPError(rec, httptest.NewRequest(http.MethodPost, "/users", nil), err)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422, got %d", rec.Code)
+	}
+	body := decodeHTTPError(t, rec)
+	if len(body.Fields) != 1 || body.Fields[0].Field != "email" || body.Fields[0].Message != "required" {
+		t.Errorf("Expected email field in response, got %+v", body.Fields)
+	}
+}
+
+func TestAddFieldError(t *testing.T) {
+	err := NewWithField(TestCodeValidation, "Invalid signup form", "name", "").
+		AddFieldError("email", "bad@", "invalid format").
+		AddFieldError("password", "hunter2", "too short")
+
+	if len(err.FieldErrors) != 2 || err.FieldErrors[0].Code != TestCodeValidation {
+		t.Fatalf("Expected 2 field errors with the error's code, got %+v", err.FieldErrors)
+	}
+	fields := err.Fields()
+	if len(fields) != 3 || fields[0].Field != "name" || fields[2].Field != "password" {
+		t.Errorf("Expected Field followed by FieldErrors, got %+v", fields)
+	}
+
+	clone := err.Clone().AddFieldError("age", "12", "too young")
+	if len(err.FieldErrors) != 2 || len(clone.FieldErrors) != 3 {
+		t.Error("Expected clone field errors to be independent")
+	}
+
+	RedactKeys("password")
+	defer ClearRedaction()
+	data, _ := json.Marshal(err)
+	if strings.Contains(string(data), "hunter2") || !strings.Contains(string(data), `"fields":[`) {
+		t.Errorf("Expected redacted fields array, got %s", data)
+	}
+	if err.FieldErrors[1].Value != "hunter2" {
+		t.Error("Expected redaction not to modify the error")
+	}
+}
+
+type sqlStateError struct{ state string }
+
+func (e sqlStateError) Error() string    { return "pq: SQLSTATE " + e.state }
+func (e sqlStateError) SQLState() string { return e.state }
+
+func TestClassifyDB(t *testing.T) {
+	if ClassifyDB(nil) != nil {
+		t.Error("Expected nil for nil error")
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		code      ErrorCode
+		retryable bool
+		status    int
+	}{
+		{"no rows", fmt.Errorf("scan: %w", sql.ErrNoRows), ErrCodeDBNoRows, false, http.StatusNotFound},
+		{"bad conn", driver.ErrBadConn, ErrCodeDBConnection, true, http.StatusServiceUnavailable},
+		{"unique", sqlStateError{"23505"}, ErrCodeDBUniqueViolation, false, http.StatusConflict},
+		{"foreign key", sqlStateError{"23503"}, ErrCodeDBForeignKeyViolation, false, http.StatusConflict},
+		{"not null", sqlStateError{"23502"}, ErrCodeDBConstraintViolation, false, http.StatusConflict},
+		{"deadlock", sqlStateError{"40P01"}, ErrCodeDBDeadlock, true, http.StatusServiceUnavailable},
+		{"connection", sqlStateError{"08006"}, ErrCodeDBConnection, true, http.StatusServiceUnavailable},
+		{"other", errors.New("syntax error"), ErrCodeDB, false, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		e := ClassifyDB(tt.err)
+		if e.Code != tt.code || e.Retryable != tt.retryable || HTTPStatus(e) != tt.status {
+			t.Errorf("%s: expected %s/%v/%d, got %s/%v/%d", tt.name, tt.code, tt.retryable, tt.status, e.Code, e.Retryable, HTTPStatus(e))
+		}
+		if !errors.Is(e, tt.err) {
+			t.Errorf("%s: expected original error to stay reachable", tt.name)
+		}
+	}
+
+	if e := ClassifyDB(sqlStateError{"23505"}); e.Context[SQLStateKey] != "23505" {
+		t.Errorf("Expected sqlstate in context, got %v", e.Context)
+	}
+}
+
+func TestRegisterDBMatcher(t *testing.T) {
+	defer func() { dbMatchers = nil }()
+	driverErr := errors.New("Error 1062: Duplicate entry")
+	RegisterDBMatcher(func(err error) DBErrorKind {
+		if strings.HasPrefix(err.Error(), "Error 1062") {
+			return DBUniqueViolation
+		}
+		return DBUnknown
+	})
+
+	if e := ClassifyDB(driverErr); e.Code != ErrCodeDBUniqueViolation {
+		t.Errorf("Expected custom matcher to classify as %s, got %s", ErrCodeDBUniqueViolation, e.Code)
+	}
+	if e := ClassifyDB(sql.ErrNoRows); e.Code != ErrCodeDBNoRows {
+		t.Errorf("Expected built-in rules after matchers, got %s", e.Code)
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyNet(t *testing.T) {
+	if ClassifyNet(nil) != nil {
+		t.Error("Expected nil for nil error")
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		code      ErrorCode
+		retryable bool
+	}{
+		{"deadline", fmt.Errorf("call: %w", context.DeadlineExceeded), ErrCodeTimeout, true},
+		{"canceled", context.Canceled, ErrCodeCanceled, false},
+		{"net timeout", &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, ErrCodeTimeout, true},
+		{"dns", &net.DNSError{Err: "no such host", Name: "db.internal", IsNotFound: true}, ErrCodeDNS, false},
+		{"dns temporary", &net.DNSError{Err: "server misbehaving", Name: "db.internal", IsTemporary: true}, ErrCodeDNS, true},
+		{"refused", &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, ErrCodeConnectionRefused, true},
+		{"reset", &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, ErrCodeConnectionReset, true},
+		{"other", errors.New("unexpected EOF"), ErrCodeNetwork, false},
+	}
+	for _, tt := range tests {
+		e := ClassifyNet(tt.err)
+		if e.Code != tt.code || e.Retryable != tt.retryable {
+			t.Errorf("%s: expected %s/%v, got %s/%v", tt.name, tt.code, tt.retryable, e.Code, e.Retryable)
+		}
+		if !errors.Is(e, tt.err) {
+			t.Errorf("%s: expected original error to stay reachable", tt.name)
+		}
+	}
+
+	if e := ClassifyNet(context.DeadlineExceeded); HTTPStatus(e) != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 for timeouts, got %d", HTTPStatus(e))
+	}
+	structured := New(TestCodeValidation, "Invalid input")
+	if ClassifyNet(structured) != structured {
+		t.Error("Expected unrecognized *Error to be returned unchanged")
+	}
+}
+
+func TestWrapCtxDeadline(t *testing.T) {
+	err := WrapCtx(context.Background(), errors.New("boom"), TestCodeDatabase, "Query failed")
+	if _, ok := err.Context[ContextErrKey]; ok || err.Retryable {
+		t.Errorf("Expected no context state for a live background context, got %v", err.Context)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	err = WrapCtx(ctx, errors.New("boom"), TestCodeDatabase, "Query failed")
+	if remaining, ok := err.Context[DeadlineRemainingKey].(time.Duration); !ok || remaining <= 0 || remaining > time.Hour {
+		t.Errorf("Expected positive remaining deadline, got %v", err.Context[DeadlineRemainingKey])
+	}
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	err = WrapCtx(expired, expired.Err(), TestCodeDatabase, "Query failed")
+	if err.Context[ContextErrKey] != context.DeadlineExceeded.Error() || !err.Retryable {
+		t.Errorf("Expected deadline exceeded recorded and retryable, got %v retryable=%v", err.Context, err.Retryable)
+	}
+	if remaining, _ := err.Context[DeadlineRemainingKey].(time.Duration); remaining >= 0 {
+		t.Errorf("Expected negative remaining deadline, got %v", remaining)
+	}
+
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	err = WrapCtx(canceled, canceled.Err(), TestCodeDatabase, "Query failed")
+	if err.Context[ContextErrKey] != context.Canceled.Error() || err.Retryable {
+		t.Errorf("Expected cancellation recorded and not retryable, got %v retryable=%v", err.Context, err.Retryable)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	defer func() {
+		exitCodeByCode = make(map[ErrorCode]int)
+		exitCodeBySeverity = make(map[Severity]int)
+	}()
+	RegisterExitCode(TestCodeValidation, 64)
+	RegisterSeverityExitCode(SeverityCritical, 70)
+
+	if ExitCode(nil) != 0 {
+		t.Error("Expected 0 for nil error")
+	}
+	if got := ExitCode(errors.New("plain")); got != DefaultExitCode {
+		t.Errorf("Expected default exit code, got %d", got)
+	}
+	inner := New(TestCodeValidation, "Invalid flag")
+	if got := ExitCode(Wrap(inner, "CLI_ERROR", "Command failed")); got != 64 {
+		t.Errorf("Expected code mapping found in chain, got %d", got)
+	}
+	if got := ExitCode(New(TestCodeDatabase, "Corrupted").WithCriticalSeverity()); got != 70 {
+		t.Errorf("Expected severity mapping, got %d", got)
+	}
+
+	RegisterExitCode(TestCodeValidation, 0)
+	if got := ExitCode(inner); got != DefaultExitCode {
+		t.Errorf("Expected mapping removed, got %d", got)
+	}
+}
+
+func TestFatalIfError(t *testing.T) {
+	var out bytes.Buffer
+	exitCode := -1
+	fatalExit = func(code int) { exitCode = code }
+	fatalOutput = &out
+	defer func() {
+		fatalExit = os.Exit
+		fatalOutput = os.Stderr
+	}()
+
+	FatalIfError(nil)
+	if exitCode != -1 || out.Len() != 0 {
+		t.Fatal("Expected nil error to be ignored")
+	}
+
+	FatalIfError(New(TestCodeDatabase, "dial tcp: refused").WithUserMessage("Cannot reach the database"))
+	if exitCode != DefaultExitCode || out.String() != "Cannot reach the database\n" {
+		t.Errorf("Expected user message and exit code %d, got %q and %d", DefaultExitCode, out.String(), exitCode)
+	}
+}
+
+func TestWithCaller(t *testing.T) {
+	_, file, line, _ := runtime.Caller(0)
+	err := New(TestCodeValidation, "Invalid input", WithCaller())
+	if err.Source == nil {
+		t.Fatal("Expected Source to be recorded")
+	}
+	if err.Source.File != file || err.Source.Line != line+1 || !strings.HasSuffix(err.Source.Function, ".TestWithCaller") {
+		t.Errorf("Expected source at %s:%d in TestWithCaller, got %+v", file, line+1, err.Source)
+	}
+	if err.Stack != nil {
+		t.Error("Expected no stack trace with WithCaller alone")
+	}
+
+	wrapped := Wrap(errors.New("boom"), TestCodeDatabase, "Query failed", WithCaller())
+	if wrapped.Source == nil || wrapped.Source.Line != line+12 {
+		t.Errorf("Expected wrapper source at line %d, got %+v", line+12, wrapped.Source)
+	}
+
+	direct := NewWithCaller(TestCodeValidation, "Invalid input")
+	if direct.Source == nil || direct.Source.Line != line+17 || direct.Source.Package != "github.com/agilira/go-errors" {
+		t.Errorf("Expected NewWithCaller source at line %d, got %+v", line+17, direct.Source)
+	}
+
+	data, _ := json.Marshal(direct)
+	var decoded Error
+	if jerr := json.Unmarshal(data, &decoded); jerr != nil || decoded.Source == nil || *decoded.Source != *direct.Source {
+		t.Errorf("Expected source to round-trip through JSON, got %s", data)
+	}
+	if plain, _ := json.Marshal(New(TestCodeValidation, "x")); strings.Contains(string(plain), `"source"`) {
+		t.Error("Expected no source field without WithCaller")
+	}
+}
+
+func TestHasCodePrefix(t *testing.T) {
+	code := ErrorCode("DB.CONNECTION.TIMEOUT")
+	for _, prefix := range []ErrorCode{"DB", "DB.CONNECTION", "DB.CONNECTION.TIMEOUT"} {
+		if !code.HasPrefix(prefix) {
+			t.Errorf("Expected %s to have prefix %s", code, prefix)
+		}
+	}
+	for _, prefix := range []ErrorCode{"DB.CONN", "D", "CACHE"} {
+		if code.HasPrefix(prefix) {
+			t.Errorf("Expected %s not to have prefix %s", code, prefix)
+		}
+	}
+
+	err := fmt.Errorf("request: %w", Wrap(New(code, "Connection timed out"), "SERVICE.SAVE", "Save failed"))
+	if !HasCodePrefix(err, "DB.CONNECTION") || !HasCodePrefix(err, "SERVICE") {
+		t.Error("Expected prefixes to match anywhere in the chain")
+	}
+	if HasCodePrefix(err, "CACHE") || HasCodePrefix(nil, "DB") {
+		t.Error("Expected unrelated prefix and nil error not to match")
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	db := Namespace("DB")
+	conn := db.Namespace("CONNECTION")
+	if conn.Prefix() != "DB.CONNECTION" || db.Code("") != "DB" {
+		t.Errorf("Expected nested prefix DB.CONNECTION, got %s", conn.Prefix())
+	}
+
+	err := conn.New("TIMEOUT", "Connection timed out", WithStack())
+	if err.Code != "DB.CONNECTION.TIMEOUT" {
+		t.Errorf("Expected namespaced code, got %s", err.Code)
+	}
+	if fn := firstFrameFunction(err.Stack); !strings.Contains(fn, "TestNamespace") {
+		t.Errorf("Expected stack to start at the caller, got %s", fn)
+	}
+
+	wrapped := db.Wrap(errors.New("EOF"), "READ", "Read failed")
+	if wrapped.Code != "DB.READ" || !db.Contains(wrapped) || conn.Contains(wrapped) {
+		t.Errorf("Expected DB.READ inside DB but not DB.CONNECTION, got %s", wrapped.Code)
+	}
+}
+
+func TestToMapFromMapRoundTrip(t *testing.T) {
+	orig := New(TestCodeDatabase, "Query failed").
+		WithContext("table", "users").
+		WithUserMessage("Please retry").
+		WithSeverity(SeverityCritical).
+		AsRetryable().
+		WithRetryAfter(2 * time.Second).
+		WithHTTPStatus(http.StatusServiceUnavailable)
+	orig.Cause = Wrap(errors.New("connection reset"), TestCodeValidation, "Inner")
+
+	m := orig.ToMap()
+	if m["code"] != string(TestCodeDatabase) || m["severity"] != "critical" || m["retryable"] != true {
+		t.Errorf("Unexpected map: %v", m)
+	}
+
+	decoded, err := FromMap(m)
+	if err != nil {
+		t.Fatalf("FromMap failed: %v", err)
+	}
+	if decoded.Code != orig.Code || decoded.Message != orig.Message || decoded.UserMsg != "Please retry" {
+		t.Errorf("Expected code, message and user message to round-trip, got %+v", decoded)
+	}
+	if decoded.Severity != SeverityCritical || !decoded.Retryable || decoded.RetryAfter != 2*time.Second || decoded.HTTPStatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected severity and retry metadata to round-trip, got %+v", decoded)
+	}
+	if !decoded.Timestamp.Equal(orig.Timestamp) || decoded.Context["table"] != "users" {
+		t.Errorf("Expected timestamp and context to round-trip, got %v %v", decoded.Timestamp, decoded.Context)
+	}
+	inner, ok := decoded.Cause.(*Error)
+	if !ok || inner.Code != TestCodeValidation || inner.Cause == nil || inner.Cause.Error() != "connection reset" {
+		t.Errorf("Expected nested cause chain, got %#v", decoded.Cause)
+	}
+
+	// Maps decoded from JSON carry float64 numbers.
+	data, _ := json.Marshal(m)
+	var generic map[string]interface{}
+	_ = json.Unmarshal(data, &generic)
+	if fromJSON, err := FromMap(generic); err != nil || fromJSON.RetryAfter != 2*time.Second || fromJSON.HTTPStatusCode != 503 {
+		t.Errorf("Expected JSON-decoded map to be accepted, got %v %v", fromJSON, err)
+	}
+}
+
+func TestFromMapInvalid(t *testing.T) {
+	if _, err := FromMap(map[string]interface{}{"message": "no code"}); !HasCode(err, ErrCodeInvalidErrorMap) {
+		t.Errorf("Expected missing code to be rejected, got %v", err)
+	}
+	_, err := FromMap(map[string]interface{}{"code": "X", "retryable": "yes"})
+	var e *Error
+	if !errors.As(err, &e) || e.Context["key"] != "retryable" {
+		t.Errorf("Expected wrongly typed key to be reported, got %v", err)
+	}
+	ms := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	got, err := FromMap(map[string]interface{}{"code": "X", "timestamp": ms.UnixMilli(), "retry_after": "1.5s"})
+	if err != nil || !got.Timestamp.Equal(ms) || got.RetryAfter != 1500*time.Millisecond {
+		t.Errorf("Expected Unix milliseconds and duration string, got %+v %v", got, err)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	if c.Err() != nil {
+		t.Error("Expected nil for an empty collector")
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			c.CollectWorker(w, nil)
+			if w%2 == 0 {
+				c.CollectWorker(w, New(TestCodeDatabase, fmt.Sprintf("job %d failed", w)))
+			}
+		}(w)
+	}
+	wg.Wait()
+	c.Collect(errors.New("plain failure"))
+
+	if c.Len() != 5 {
+		t.Fatalf("Expected 5 collected errors, got %d", c.Len())
+	}
+	workers := map[interface{}]bool{}
+	for _, e := range c.Errors()[:4] {
+		if e.Code != TestCodeDatabase {
+			t.Errorf("Expected worker error code to be kept, got %s", e.Code)
+		}
+		workers[e.Context[WorkerKey]] = true
+	}
+	for _, w := range []int{0, 2, 4, 6} {
+		if !workers[w] {
+			t.Errorf("Expected an error from worker %d", w)
+		}
+	}
+
+	var m *MultiError
+	if err := c.Err(); !errors.As(err, &m) || m.ErrorCount() != 5 {
+		t.Errorf("Expected MultiError with 5 errors, got %v", err)
+	}
+	if last := c.Errors()[4]; last.Code != DefaultErrorCode || last.Message != "plain failure" {
+		t.Errorf("Expected plain error under DefaultErrorCode, got %v", last)
+	}
+}
+
+func TestWrapPreserve(t *testing.T) {
+	inner := New(TestCodeDatabase, "Disk full").
+		WithContext("table", "orders").
+		WithContext("shard", 3).
+		WithCriticalSeverity().
+		WithUserMessage("Please try again later").
+		AsRetryable().
+		WithRetryAfter(time.Second)
+
+	wrapped := WrapPreserve(inner, "SERVICE_ERROR", "Failed to save order", WithContext("shard", 7))
+	if wrapped.Context["table"] != "orders" || wrapped.Context["shard"] != 7 {
+		t.Errorf("Expected inherited context with wrapper entries winning, got %v", wrapped.Context)
+	}
+	if wrapped.Severity != SeverityCritical || !wrapped.Retryable || wrapped.RetryAfter != time.Second {
+		t.Errorf("Expected inherited severity and retry metadata, got %s %v %v", wrapped.Severity, wrapped.Retryable, wrapped.RetryAfter)
+	}
+	if wrapped.UserMsg != "Please try again later" || wrapped.Cause != inner {
+		t.Errorf("Expected inherited user message and cause, got %q", wrapped.UserMsg)
+	}
+	if _, ok := inner.Context["shard"]; !ok || inner.Context["shard"] != 3 {
+		t.Error("Expected inner context to stay unchanged")
+	}
+
+	explicit := WrapWithOptions(inner, WrapOptions{Code: "SERVICE_ERROR", Severity: SeverityWarning, Inherit: true})
+	if explicit.Severity != SeverityWarning || !explicit.Retryable {
+		t.Errorf("Expected explicit severity to win over inheritance, got %s", explicit.Severity)
+	}
+
+	plain := Wrap(inner, "SERVICE_ERROR", "Failed to save order")
+	if plain.Retryable || len(plain.Context) != 0 || plain.Severity != SeverityError {
+		t.Error("Expected Wrap not to inherit metadata")
+	}
+
+	foreign := WrapPreserve(errors.New("boom"), "SERVICE_ERROR", "Failed")
+	if foreign.Severity != SeverityError || foreign.Retryable {
+		t.Error("Expected defaults when the chain has no *Error")
+	}
+}
+
+func TestStackDeduplication(t *testing.T) {
+	inner := Wrap(errors.New("boom"), TestCodeDatabase, "Query failed")
+	if inner.Stack == nil {
+		t.Fatal("Expected the innermost wrapper to capture a stack")
+	}
+	outer := Wrap(fmt.Errorf("repo: %w", inner), "SERVICE_ERROR", "Save failed")
+	if outer.Stack != nil {
+		t.Error("Expected no second stack when the chain already has one")
+	}
+	if forced := Wrap(inner, "SERVICE_ERROR", "Save failed", WithStack()); forced.Stack == nil {
+		t.Error("Expected WithStack to force a capture")
+	}
+
+	SetStackDeduplication(false)
+	defer SetStackDeduplication(true)
+	if again := Wrap(inner, "SERVICE_ERROR", "Save failed"); again.Stack == nil {
+		t.Error("Expected a stack on every wrapper with deduplication disabled")
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	email := NewWithField(TestCodeValidation, "Email is required", "email", "").WithContext("form", "signup")
+	name := NewWithField(TestCodeValidation, "Name is too long", "name", "x")
+	err := fmt.Errorf("handler: %w", Wrap(email, "SERVICE_ERROR", "Signup failed"))
+
+	if !errors.Is(name, email) {
+		t.Error("Expected plain errors.Is to match on code only")
+	}
+	if !errors.Is(err, MatchCode(TestCodeValidation)) || errors.Is(err, MatchCode(TestCodeDatabase)) {
+		t.Error("Expected MatchCode to match on code through the chain")
+	}
+	if !errors.Is(err, MatchCodeAndField(TestCodeValidation, "email")) {
+		t.Error("Expected MatchCodeAndField to find the email error")
+	}
+	if MatchCodeAndField(TestCodeValidation, "email").Match(name) {
+		t.Error("Expected MatchCodeAndField not to match another field")
+	}
+
+	m := MatchCodeAndField(TestCodeValidation, "email").WithContext("form", "signup")
+	if !m.Match(err) || m.WithContext("form", "login").Match(err) {
+		t.Error("Expected context entries to be compared")
+	}
+	if !m.WithMessage("Email is required").Match(err) || m.WithMessage("other").Match(err) {
+		t.Error("Expected message to be compared")
+	}
+	if got := m.Error(); got != "match code=VALIDATION_ERROR field=email form=signup" {
+		t.Errorf("Unexpected matcher description %q", got)
+	}
+}
+
+func TestIsMode(t *testing.T) {
+	got := Wrap(NewWithField(TestCodeValidation, "Email is required", "email", ""), "SERVICE_ERROR", "failed")
+	want := NewWithField(TestCodeValidation, "Email is required", "email", "")
+	other := NewWithField(TestCodeValidation, "Email is invalid", "email", "")
+
+	if !IsMode(got, want, MatchByCode|MatchByField|MatchByMessage) {
+		t.Error("Expected code, field and message to match")
+	}
+	if IsMode(got, other, MatchByCode|MatchByField|MatchByMessage) || !IsMode(got, other, MatchByCode|MatchByField) {
+		t.Error("Expected the message to be compared only when selected")
+	}
+	if IsMode(got, nil, MatchByCode) {
+		t.Error("Expected nil target not to match")
+	}
+}
+
+func TestMust(t *testing.T) {
+	if got := Must(42, nil); got != 42 {
+		t.Errorf("Expected 42, got %d", got)
+	}
+
+	var err error
+	_, _, line, _ := runtime.Caller(0)
+	func() {
+		defer Recover(&err, "", "")
+		Must(0, New(TestCodeDatabase, "Connection refused"))
+	}()
+	mustErr, ok := PanicValue(err).(*Error)
+	if !ok {
+		t.Fatalf("Expected Must to panic with *Error, got %v", PanicValue(err))
+	}
+	if mustErr.Code != TestCodeDatabase || mustErr.Message != "Connection refused" || mustErr.Severity != SeverityCritical {
+		t.Errorf("Expected critical error keeping code and message, got %v (%s)", mustErr, mustErr.Severity)
+	}
+	if mustErr.Source == nil || mustErr.Source.Line != line+3 {
+		t.Errorf("Expected source at line %d, got %+v", line+3, mustErr.Source)
+	}
+
+	func() {
+		defer Recover(&err, "", "")
+		Must("", errors.New("plain failure"))
+	}()
+	if !HasCode(err, ErrCodeInvariant) {
+		t.Errorf("Expected %s for a plain error, got %v", ErrCodeInvariant, err)
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	if err := Ensure(true, TestCodeValidation, "ok"); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+	_, _, line, _ := runtime.Caller(0)
+	err := Ensure(false, TestCodeValidation, "workers must be positive")
+	e, ok := err.(*Error)
+	if !ok || e.Code != TestCodeValidation || e.Message != "workers must be positive" {
+		t.Fatalf("Expected structured error, got %v", err)
+	}
+	if e.Source == nil || e.Source.Line != line+1 {
+		t.Errorf("Expected source at line %d, got %+v", line+1, e.Source)
+	}
+}
+
+func TestMarshalJSONCauseChain(t *testing.T) {
+	inner := New(TestCodeDatabase, "Connection lost")
+	plain := fmt.Errorf("retrying: %w", inner)
+	outer := Wrap(plain, TestCodeValidation, "Request failed")
+	outer.Cause = plain
+
+	data, err := json.Marshal(outer)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var doc map[string]interface{}
+	_ = json.Unmarshal(data, &doc)
+	cause, ok := doc["cause"].(map[string]interface{})
+	if !ok || cause["message"] != "retrying: [DATABASE_ERROR]: Connection lost" {
+		t.Fatalf("Expected plain wrapper as message object, got %v", doc["cause"])
+	}
+	if nested, _ := cause["cause"].(map[string]interface{}); nested["code"] != string(TestCodeDatabase) {
+		t.Errorf("Expected structured error below the plain wrapper, got %v", cause["cause"])
+	}
+
+	var decoded Error
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Cause == nil || decoded.Cause.Error() != plain.Error() || !HasCode(&decoded, TestCodeDatabase) {
+		t.Errorf("Expected plain wrapper and nested code to round-trip, got %v", decoded.Cause)
+	}
+
+	leaf, _ := json.Marshal(Wrap(errors.New("disk full"), TestCodeDatabase, "Write failed"))
+	if !strings.Contains(string(leaf), `"cause":"disk full"`) {
+		t.Errorf("Expected plain cause as a string, got %s", leaf)
+	}
+	var decodedLeaf Error
+	if err := json.Unmarshal(leaf, &decodedLeaf); err != nil || decodedLeaf.Cause.Error() != "disk full" {
+		t.Errorf("Expected string cause to round-trip, got %v %v", decodedLeaf.Cause, err)
+	}
+	var legacy Error
+	if err := json.Unmarshal([]byte(`{"code":"X","cause":{"message":"old format"}}`), &legacy); err != nil || legacy.Cause.Error() != "old format" {
+		t.Errorf("Expected message object cause to be accepted, got %v %v", legacy.Cause, err)
+	}
+}
+
+func TestMarshalJSONCauseDepth(t *testing.T) {
+	defer SetMaxCauseDepth(0)
+	SetMaxCauseDepth(2)
+	if GetMaxCauseDepth() != 2 {
+		t.Fatalf("Expected depth 2, got %d", GetMaxCauseDepth())
+	}
+
+	var err error = errors.New("root")
+	for i := 0; i < 5; i++ {
+		err = WrapNoStack(err, TestCodeDatabase, fmt.Sprintf("level %d", i))
+	}
+	data, _ := json.Marshal(err)
+	var doc map[string]interface{}
+	_ = json.Unmarshal(data, &doc)
+	level1, _ := doc["cause"].(map[string]interface{})
+	level2, _ := level1["cause"].(map[string]interface{})
+	if level2 == nil {
+		t.Fatalf("Expected two nested cause objects, got %s", data)
+	}
+	if s, ok := level2["cause"].(string); !ok || !strings.HasPrefix(s, "[DATABASE_ERROR]: level 1") {
+		t.Errorf("Expected causes beyond the limit as a string, got %v", level2["cause"])
+	}
+
+	SetMaxCauseDepth(0)
+	if GetMaxCauseDepth() != DefaultMaxCauseDepth {
+		t.Errorf("Expected default depth to be restored, got %d", GetMaxCauseDepth())
+	}
+}
+
+func TestUnmarshalJSONCauseDepth(t *testing.T) {
+	const levels = 9000
+	body := `{"code":"TOP","message":"top","cause":` +
+		strings.Repeat(`{"code":"NESTED","message":"nested","cause":`, levels) + `"root"` +
+		strings.Repeat("}", levels) + "}"
+
+	start := time.Now()
+	var decoded Error
+	if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	resp := &http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+	remote := FromHTTPResponse(resp)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected deep causes to decode in linear time, took %v", elapsed)
+	}
+
+	for _, e := range []*Error{&decoded, remote} {
+		depth := 0
+		cause := e.Cause
+		for {
+			nested, ok := cause.(*Error)
+			if !ok {
+				break
+			}
+			depth++
+			cause = nested.Cause
+		}
+		if depth != GetMaxCauseDepth() || cause == nil || cause.Error() != "[NESTED]: nested" {
+			t.Errorf("Expected %d decoded levels and a plain truncated cause, got %d and %v", GetMaxCauseDepth(), depth, cause)
+		}
+	}
+}
+
+func TestStackSampling(t *testing.T) {
+	defer SetStackPolicy(OnWrapOnly)
+	SetStackPolicy(AlwaysCapture)
+	SetStackSampling(2)
+	defer SetStackSampling(0)
+
+	captured, sampled := 0, 0
+	for i := 0; i < 10; i++ {
+		err := New(TestCodeDatabase, "Connection lost")
+		if err.Stack != nil {
+			captured++
+		} else if err.Context[StackSampledKey] == false {
+			sampled++
+		}
+	}
+	// A burst straddling a second boundary may get up to twice the budget.
+	if captured < 2 || captured > 4 || captured+sampled != 10 {
+		t.Errorf("Expected 2 to 4 stacks and the rest sampled out, got %d stacks and %d sampled", captured, sampled)
+	}
+	if err := New(TestCodeValidation, "Other code"); err.Stack == nil {
+		t.Error("Expected the budget to be per code")
+	}
+	if err := New(TestCodeDatabase, "Forced", WithStack()); err.Stack == nil {
+		t.Error("Expected WithStack to bypass the budget")
+	}
+
+	SetStackSampling(0)
+	if err := New(TestCodeDatabase, "Unlimited"); err.Stack == nil || err.Context[StackSampledKey] != nil {
+		t.Error("Expected sampling to be disabled")
+	}
+}
+
+func TestStacktraceResolutionCache(t *testing.T) {
+	stack := CaptureStacktrace(0)
+	first := stack.String()
+	if first == "" || stack.String() != first {
+		t.Fatalf("Expected cached text to be stable, got %q", first)
+	}
+	frames := stack.Resolve()
+	frames[0].Function = "modified"
+	if stack.Resolve()[0].Function == "modified" {
+		t.Error("Expected Resolve to return a copy of the cached frames")
+	}
+
+	defer SetDefaultStackFilter("runtime.", "testing.")
+	SetDefaultStackFilter("github.com/agilira/go-errors.")
+	if text := stack.String(); text == first || strings.Contains(text, "TestStacktraceResolutionCache") {
+		t.Errorf("Expected the stack to be resolved again with the new filter, got %q", text)
+	}
+}
+
+func TestVerboseError(t *testing.T) {
+	inner := Wrap(errors.New("connection reset"), TestCodeDatabase, "Query failed")
+	outer := Wrap(inner, TestCodeValidation, "Load failed")
+	if got := outer.Error(); got != "[VALIDATION_ERROR]: Load failed" {
+		t.Errorf("Expected code and message only by default, got %q", got)
+	}
+
+	want := "[VALIDATION_ERROR]: Load failed: [DATABASE_ERROR]: Query failed: connection reset"
+	if got := outer.Clone().WithVerboseError().Error(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	SetVerboseErrors(true)
+	defer SetVerboseErrors(false)
+	if got := outer.Error(); got != want {
+		t.Errorf("Expected %q with SetVerboseErrors, got %q", want, got)
+	}
+	if got := fmt.Errorf("handler: %w", outer).Error(); got != "handler: "+want {
+		t.Errorf("Expected fmt.Errorf to include the chain, got %q", got)
+	}
+	if diag := fmt.Sprintf("%+v", outer); !strings.HasPrefix(diag, "[VALIDATION_ERROR]: Load failed\n") {
+		t.Errorf("Expected %%+v headline without the chain, got %q", diag)
+	}
+
+	defer SetMaxCauseDepth(0)
+	SetMaxCauseDepth(3)
+	cyclic := New(TestCodeValidation, "A")
+	cyclic.Cause = New(TestCodeDatabase, "B")
+	cyclic.Cause.(*Error).Cause = cyclic
+	if got := cyclic.Error(); got != "[VALIDATION_ERROR]: A: [DATABASE_ERROR]: B: [VALIDATION_ERROR]: A: [DATABASE_ERROR]: B: ..." {
+		t.Errorf("Expected the cycle to be cut at the depth limit, got %q", got)
+	}
+}
+
+func TestOps(t *testing.T) {
+	inner := New(TestCodeDatabase, "Insert failed", WithOp("store.InsertOrder"))
+	mid := Wrap(inner, "CHARGE_FAILED", "Charge failed").WithOp("billing.ChargeCard")
+	same := Wrap(mid, "CHARGE_FAILED", "Retry failed").WithOp("billing.ChargeCard")
+	outer := fmt.Errorf("request: %w", Wrap(same, "REQUEST_FAILED", "Request failed").WithOp("api.CreateOrder"))
+
+	want := []string{"api.CreateOrder", "billing.ChargeCard", "store.InsertOrder"}
+	if got := Ops(outer); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if Ops(errors.New("plain")) != nil {
+		t.Error("Expected no operations for a plain error")
+	}
+	if built := NewBuilder(TestCodeValidation, "x").WithOp("cfg.Load").Build(); built.Op != "cfg.Load" {
+		t.Errorf("Expected builder to set Op, got %q", built.Op)
+	}
+
+	data, _ := json.Marshal(mid)
+	var decoded Error
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Op != "billing.ChargeCard" {
+		t.Errorf("Expected Op to round-trip through JSON, got %q (%v)", decoded.Op, err)
+	}
+	if diag := fmt.Sprintf("%+v", mid); !strings.Contains(diag, "OP: billing.ChargeCard") {
+		t.Errorf("Expected OP line in diagnostic output, got %s", diag)
+	}
+}
+
+func TestPresetConstructors(t *testing.T) {
+	tests := []struct {
+		err       *Error
+		code      ErrorCode
+		status    int
+		severity  Severity
+		retryable bool
+	}{
+		{NotFound("user not found"), ErrCodeNotFound, http.StatusNotFound, SeverityWarning, false},
+		{Conflict("email taken"), ErrCodeAlreadyExists, http.StatusConflict, SeverityWarning, false},
+		{BadRequest("bad page size"), ErrCodeInvalidArgument, http.StatusBadRequest, SeverityWarning, false},
+		{Unauthorized("token expired"), ErrCodeUnauthenticated, http.StatusUnauthorized, SeverityWarning, false},
+		{Forbidden("admins only"), ErrCodePermissionDenied, http.StatusForbidden, SeverityWarning, false},
+		{TooManyRequests("slow down"), ErrCodeResourceExhausted, http.StatusTooManyRequests, SeverityWarning, true},
+		{Internal(errors.New("disk"), "commit failed"), ErrCodeInternal, http.StatusInternalServerError, SeverityError, false},
+		{Unavailable(errors.New("refused"), "db down"), ErrCodeUnavailable, http.StatusServiceUnavailable, SeverityError, true},
+	}
+	for _, tt := range tests {
+		e := tt.err
+		if e.Code != tt.code || HTTPStatus(e) != tt.status || e.Severity != tt.severity || e.Retryable != tt.retryable {
+			t.Errorf("Unexpected %s error: status %d, severity %s, retryable %v", e.Code, HTTPStatus(e), e.Severity, e.Retryable)
+		}
+	}
+
+	wrapped := Internal(errors.New("disk full"), "commit failed")
+	if wrapped.Cause == nil || wrapped.Cause.Error() != "disk full" {
+		t.Errorf("Expected Internal to wrap its cause, got %v", wrapped.Cause)
+	}
+	if fn := firstFrameFunction(wrapped.Stack); !strings.HasSuffix(fn, "TestPresetConstructors") {
+		t.Errorf("Expected stack to start at the caller, got %s", fn)
+	}
+	if e := NotFound("gone", func(e *Error) { e.HTTPStatusCode = http.StatusGone }, WithContext("id", 7)); e.HTTPStatusCode != http.StatusGone || e.Context["id"] != 7 {
+		t.Errorf("Expected options to override the defaults, got %d %v", e.HTTPStatusCode, e.Context)
+	}
+}
+
+func TestCodeTranslator(t *testing.T) {
+	tr := NewCodeTranslator()
+	tr.Register("PAYMENTS", TranslationRule{Code: "CHECKOUT_PAYMENT_ERROR"})
+	tr.Register("PAYMENTS.CARD_DECLINED", TranslationRule{
+		Code:        "CHECKOUT_PAYMENT_REFUSED",
+		UserMessage: "Your card was declined.",
+		HTTPStatus:  http.StatusPaymentRequired,
+	})
+
+	if tr.Translate(nil) != nil {
+		t.Error("Expected nil for a nil error")
+	}
+	upstream := New("PAYMENTS.CARD_DECLINED.INSUFFICIENT_FUNDS", "Insufficient funds").
+		WithUserMessage("Internal payments message").
+		WithRetryAfter(time.Minute)
+	err := tr.Translate(fmt.Errorf("charge: %w", upstream))
+	e, ok := err.(*Error)
+	if !ok || e.Code != "CHECKOUT_PAYMENT_REFUSED" || e.Message != "upstream error" {
+		t.Fatalf("Expected the longest prefix rule to apply, got %v", err)
+	}
+	if e.UserMessage() != "Your card was declined." || HTTPStatus(e) != http.StatusPaymentRequired || e.RetryAfter != time.Minute {
+		t.Errorf("Unexpected translation: %q %d %v", e.UserMessage(), HTTPStatus(e), e.RetryAfter)
+	}
+	if UpstreamCode(err) != "PAYMENTS.CARD_DECLINED.INSUFFICIENT_FUNDS" || !HasCode(e, upstream.Code) {
+		t.Errorf("Expected the upstream error to stay in the chain, got %q", UpstreamCode(err))
+	}
+	if len(e.Context) != 0 || UpstreamCode(upstream) != "" {
+		t.Errorf("Expected the upstream code out of the context, got %v", e.Context)
+	}
+	if data, _ := json.Marshal(ToProblemDetails(e)); strings.Contains(string(data), "PAYMENTS") {
+		t.Errorf("Expected the upstream code not to reach clients, got %s", data)
+	}
+	if described := tr.Translate(upstream); described.(*Error).Message != "upstream error" {
+		t.Error("Expected the upstream message not to become the technical message")
+	}
+	if pub := e.PublicError(); pub.Code != "CHECKOUT_PAYMENT_REFUSED" || pub.Message != "Your card was declined." {
+		t.Errorf("Expected the public view to show only the translation, got %+v", pub)
+	}
+
+	generic := tr.Translate(New("PAYMENTS.TIMEOUT", "Gateway timeout").WithUserMessage("leaky"))
+	if ge := generic.(*Error); ge.Code != "CHECKOUT_PAYMENT_ERROR" || ge.UserMsg != "" {
+		t.Errorf("Expected the namespace rule without the upstream user message, got %v %q", ge, ge.UserMsg)
+	}
+
+	other := New("INVENTORY_ERROR", "out of stock")
+	if tr.Translate(other) != error(other) {
+		t.Error("Expected errors without a rule to be returned unchanged")
+	}
+	tr.SetFallback(&TranslationRule{Code: "UPSTREAM_ERROR", UserMessage: "Please try again later."})
+	if got := tr.Translate(errors.New("dial tcp: refused")); !HasCode(got, "UPSTREAM_ERROR") || HTTPStatus(got) != http.StatusInternalServerError {
+		t.Errorf("Expected the fallback to apply to plain errors, got %v", got)
+	}
+	tr.SetFallback(nil)
+	if tr.Translate(other) != error(other) {
+		t.Error("Expected the fallback to be removed")
+	}
+}
+
+func TestErrorID(t *testing.T) {
+	err := New(TestCodeValidation, "Invalid email").WithUserMessage("Please check your email.").WithErrorID()
+	id := err.ErrorID
+	if len(id) != len(ErrorIDPrefix)+6 || !strings.HasPrefix(id, ErrorIDPrefix) || strings.ToUpper(id) != id {
+		t.Fatalf("Expected a reference like ERR-7F3A2C, got %q", id)
+	}
+	if err.WithErrorID().ErrorID != id {
+		t.Error("Expected WithErrorID to keep an existing ID")
+	}
+	if got := err.UserMessage(); got != "Please check your email. (reference: "+id+")" {
+		t.Errorf("Expected the reference in the user message, got %q", got)
+	}
+	if pub := err.PublicError(); pub.ErrorID != id || !strings.Contains(pub.Message, id) {
+		t.Errorf("Expected the reference in the public error, got %+v", pub)
+	}
+	data, _ := json.Marshal(err)
+	var decoded Error
+	if jsonErr := json.Unmarshal(data, &decoded); jsonErr != nil || decoded.ErrorID != id {
+		t.Errorf("Expected error_id to round-trip, got %q (%v) from %s", decoded.ErrorID, jsonErr, data)
+	}
+	if !strings.Contains(fmt.Sprintf("%+v", err), "ERROR ID: "+id) {
+		t.Errorf("Expected the reference in the diagnostic format, got %+v", err)
+	}
+	if New(TestCodeValidation, "no id").ErrorID != "" {
+		t.Error("Expected no ID by default")
+	}
+
+	SetAutoErrorID(true)
+	defer SetAutoErrorID(false)
+	inner := New(TestCodeDatabase, "Query failed")
+	if inner.ErrorID == "" {
+		t.Fatal("Expected an ID on creation")
+	}
+	outer := Wrap(fmt.Errorf("load: %w", inner), "SERVICE_ERROR", "Load failed")
+	if outer.ErrorID != inner.ErrorID {
+		t.Errorf("Expected the wrapper to reuse %s, got %s", inner.ErrorID, outer.ErrorID)
+	}
+}
+
+func TestSourceContext(t *testing.T) {
+	err := New(TestCodeValidation, "with snippet", WithStack()) // source context marker
+	_, _, line, _ := runtime.Caller(0)
+	line--
+	if strings.Contains(err.Stack.String(), "source context marker") {
+		t.Fatal("Expected no source context by default")
+	}
+
+	EnableSourceContext(true)
+	defer EnableSourceContext(false)
+	text := err.Stack.String()
+	marked := fmt.Sprintf("> %d | \terr := New(TestCodeValidation, \"with snippet\", WithStack()) // source context marker", line)
+	if !strings.Contains(text, marked) {
+		t.Errorf("Expected the marked source line %q in:\n%s", marked, text)
+	}
+	if !strings.Contains(text, fmt.Sprintf("  %d | ", line-SourceContextLines)) || !strings.Contains(text, fmt.Sprintf("  %d | ", line+SourceContextLines)) {
+		t.Errorf("Expected %d lines of context around line %d in:\n%s", SourceContextLines, line, text)
+	}
+	if frames := ParseStack(text); len(frames) != len(err.Stack.Resolve()) {
+		t.Errorf("Expected ParseStack to ignore the snippets, got %d frames", len(frames))
+	}
+
+	missing := formatFrames([]StackFrame{{Function: "main.main", File: "/no/such/file.go", Line: 3}}, true)
+	if missing != "main.main\n\t/no/such/file.go:3\n" {
+		t.Errorf("Expected frames without sources to render as usual, got %q", missing)
+	}
+}
+
+func TestChainCycleDetection(t *testing.T) {
+	inner := New(TestCodeDatabase, "inner")
+	loop := &cyclicError{}
+	loop.next = Wrap(loop, TestCodeValidation, "wrapper")
+	inner.Cause = loop
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if !HasCode(inner, TestCodeValidation) || HasCode(inner, "MISSING") {
+			t.Error("Expected HasCode to answer on a cyclic chain")
+		}
+		if RootCause(inner) == nil || IsPanic(inner) || HTTPStatus(inner) == 0 {
+			t.Error("Expected the helpers to return on a cyclic chain")
+		}
+		if _, ok := RetryDelay(inner); ok {
+			t.Error("Expected no retry delay in a cyclic chain")
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected chain helpers to terminate on a cyclic chain")
+	}
+
+	if cerr := CheckChain(inner); !HasCode(cerr, ErrCodeChainCycle) {
+		t.Errorf("Expected %s, got %v", ErrCodeChainCycle, cerr)
+	}
+	if cerr := CheckChain(Wrap(New(TestCodeDatabase, "root"), TestCodeValidation, "outer")); cerr != nil {
+		t.Errorf("Expected a well-formed chain, got %v", cerr)
+	}
+	if CheckChain(nil) != nil {
+		t.Error("Expected nil for a nil error")
+	}
+
+	SetMaxChainDepth(5)
+	defer SetMaxChainDepth(0)
+	var deep error = New(TestCodeValidation, "deepest")
+	for i := 0; i < 10; i++ {
+		deep = Wrap(deep, TestCodeDatabase, "level")
+	}
+	if cerr := CheckChain(deep); !HasCode(cerr, ErrCodeChainTooDeep) {
+		t.Errorf("Expected %s, got %v", ErrCodeChainTooDeep, cerr)
+	}
+	if HasCode(deep, TestCodeValidation) || len(Chain(deep)) != 5 {
+		t.Errorf("Expected the traversal to stop after 5 errors, got %d", len(Chain(deep)))
+	}
+	if SetMaxChainDepth(-1); GetMaxChainDepth() != DefaultMaxChainDepth {
+		t.Errorf("Expected the default depth, got %d", GetMaxChainDepth())
+	}
+}
+
+func TestWithEventTimeline(t *testing.T) {
+	tick := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time {
+		tick = tick.Add(time.Second)
+		return tick
+	}))
+	defer SetClock(nil)
+
+	inner := New(TestCodeDatabase, "Query failed").WithEvent("query attempt %d failed", 1)
+	inner.WithEvent("retrying connection")
+	outer := Wrap(fmt.Errorf("repo: %w", inner), "SERVICE_ERROR", "Load failed").WithEvent("fell back to cache")
+
+	if len(inner.Events) != 2 || inner.Events[0].Message != "query attempt 1 failed" || !inner.Events[0].Time.Before(inner.Events[1].Time) {
+		t.Errorf("Expected ordered events, got %+v", inner.Events)
+	}
+	timeline := Timeline(outer)
+	if len(timeline) != 3 || timeline[0].Message != "query attempt 1 failed" || timeline[2].Message != "fell back to cache" {
+		t.Errorf("Expected the events of the chain in time order, got %+v", timeline)
+	}
+	if Timeline(errors.New("plain")) != nil {
+		t.Error("Expected no events for a plain error")
+	}
+
+	data, err := json.Marshal(inner)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded Error
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(decoded.Events) != 2 || decoded.Events[1].Message != "retrying connection" || !decoded.Events[1].Time.Equal(inner.Events[1].Time) {
+		t.Errorf("Expected events to round-trip, got %+v from %s", decoded.Events, data)
+	}
+	if !strings.Contains(fmt.Sprintf("%+v", outer), "EVENT: 2025-01-01T00:00:0") {
+		t.Errorf("Expected events in the diagnostic format, got %+v", outer)
+	}
+}
+
+func TestHTTPResponseConversion(t *testing.T) {
+	orig := Wrap(errors.New("connection reset"), TestCodeDatabase, "Query failed").
+		WithHTTPStatus(http.StatusServiceUnavailable).
+		WithContext("table", "users").
+		AsRetryable()
+	rec := httptest.NewRecorder()
+	WriteHTTP(rec, orig)
+	resp := rec.Result()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	remote := FromHTTPResponse(resp)
+	if remote == nil || remote.Code != TestCodeDatabase || remote.Message != "Query failed" || !remote.Retryable ||
+		remote.Context["table"] != "users" || remote.Cause == nil || remote.Cause.Error() != "connection reset" {
+		t.Fatalf("Expected the structured error to round-trip, got %+v", remote)
+	}
+	if stack, _ := remote.Context[RemoteStackKey].(string); stack != orig.Stack.String() || remote.StackFrames() != nil {
+		t.Errorf("Expected the remote stack in context, got %q", stack)
+	}
+
+	rec = httptest.NewRecorder()
+	WriteHTTPError(rec, httptest.NewRequest(http.MethodGet, "/", nil), NotFound("User not found"))
+	if e := FromHTTPResponse(rec.Result()); e == nil || e.Code != ErrCodeNotFound || HTTPStatus(e) != http.StatusNotFound {
+		t.Errorf("Expected the WriteHTTPError envelope to be decoded, got %+v", e)
+	}
+
+	rec = httptest.NewRecorder()
+	rec.Header().Set("Content-Type", ProblemContentType+"; charset=utf-8")
+	rec.WriteHeader(http.StatusConflict)
+	_, _ = rec.WriteString(`{"type":"https://errors.example.com/ALREADY_EXISTS","title":"Conflict","status":409,"detail":"Email taken"}`)
+	if e := FromHTTPResponse(rec.Result()); e == nil || e.Code != ErrCodeAlreadyExists || e.Message != "Email taken" {
+		t.Errorf("Expected the Problem Details document to be decoded, got %+v", e)
+	}
+
+	rec = httptest.NewRecorder()
+	rec.WriteHeader(http.StatusBadGateway)
+	_, _ = rec.WriteString("<html>bad gateway</html>")
+	if e := FromHTTPResponse(rec.Result()); e == nil || e.Code != ErrCodeHTTPResponse || HTTPStatus(e) != http.StatusBadGateway ||
+		e.Context["body"] != "<html>bad gateway</html>" {
+		t.Errorf("Expected a generic error for an unstructured body, got %+v", e)
+	}
+
+	if FromHTTPResponse(nil) != nil || FromHTTPResponse(&http.Response{StatusCode: http.StatusOK}) != nil {
+		t.Error("Expected nil for a nil or successful response")
+	}
+}
+
+func TestJSONOptions(t *testing.T) {
+	SetJSONOptions(JSONOptions{
+		FieldNames: map[string]string{"code": "error_code", "message": "detail"},
+		Envelope:   "error",
+	})
+	defer SetJSONOptions(JSONOptions{})
+
+	err := Wrap(New(TestCodeDatabase, "Query failed"), TestCodeValidation, "Load failed", WithoutStack())
+	data, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		t.Fatalf("Marshal failed: %v", marshalErr)
+	}
+	var doc map[string]map[string]interface{}
+	if jsonErr := json.Unmarshal(data, &doc); jsonErr != nil {
+		t.Fatalf("Expected valid JSON, got %v: %s", jsonErr, data)
+	}
+	body := doc["error"]
+	cause, _ := body["cause"].(map[string]interface{})
+	if body["error_code"] != string(TestCodeValidation) || body["detail"] != "Load failed" || body["code"] != nil ||
+		cause["error_code"] != string(TestCodeDatabase) || cause["detail"] != "Query failed" {
+		t.Errorf("Expected renamed fields in an envelope, got %s", data)
+	}
+
+	var buf bytes.Buffer
+	if encodeErr := err.EncodeJSON(&buf); encodeErr != nil || buf.String() != string(data)+"\n" {
+		t.Errorf("Expected EncodeJSON to match MarshalJSON, got %q (%v)", buf.String(), encodeErr)
+	}
+
+	var decoded Error
+	if jsonErr := json.Unmarshal(data, &decoded); jsonErr != nil {
+		t.Fatalf("Unmarshal failed: %v", jsonErr)
+	}
+	if decoded.Code != TestCodeValidation || decoded.Message != "Load failed" || !HasCode(&decoded, TestCodeDatabase) {
+		t.Errorf("Expected the custom layout to round-trip, got %+v", decoded)
+	}
+	if opts := GetJSONOptions(); opts.Envelope != "error" || opts.FieldNames["code"] != "error_code" {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+
+	SetJSONOptions(JSONOptions{})
+	if data, _ := json.Marshal(err); !strings.HasPrefix(string(data), `{"code":"VALIDATION_ERROR"`) {
+		t.Errorf("Expected the default layout to be restored, got %s", data)
+	}
+}
+
+func TestMarshalWithOmissions(t *testing.T) {
+	err := Wrap(New(TestCodeDatabase, "Query failed").AsRetryable(), TestCodeValidation, "Load failed", WithStack()).AsRetryable()
+	data, marshalErr := err.MarshalWith(JSONOptions{OmitTimestamp: true, OmitSeverity: true, OmitRetryable: true, OmitStack: true})
+	if marshalErr != nil {
+		t.Fatalf("MarshalWith failed: %v", marshalErr)
+	}
+	for _, key := range []string{"timestamp", "severity", "retryable", "stack"} {
+		if strings.Contains(string(data), `"`+key+`"`) {
+			t.Errorf("Expected %q to be omitted at every level, got %s", key, data)
+		}
+	}
+	if !strings.Contains(string(data), `"code":"DATABASE_ERROR"`) {
+		t.Errorf("Expected the cause to be kept, got %s", data)
+	}
+
+	if data, _ := err.MarshalWith(JSONOptions{}); !strings.Contains(string(data), `"severity":"error"`) {
+		t.Errorf("Expected the zero options to keep the default layout, got %s", data)
+	}
+
+	SetJSONOptions(JSONOptions{OmitTimestamp: true})
+	defer SetJSONOptions(JSONOptions{})
+	data, _ = json.Marshal(err)
+	if strings.Contains(string(data), `"timestamp"`) || !strings.Contains(string(data), `"stack"`) {
+		t.Errorf("Expected only the timestamp to be omitted package-wide, got %s", data)
+	}
+	var decoded Error
+	if jsonErr := json.Unmarshal(data, &decoded); jsonErr != nil || !decoded.Timestamp.IsZero() || decoded.Code != TestCodeValidation {
+		t.Errorf("Expected the document to decode with a zero timestamp, got %+v (%v)", decoded, jsonErr)
+	}
+}
+
+func TestEnsureStructured(t *testing.T) {
+	if EnsureStructured(nil, TestCodeDatabase, "unused") != nil {
+		t.Error("Expected nil for a nil error")
+	}
+
+	structured := New(TestCodeValidation, "Invalid email")
+	got := EnsureStructured(structured, TestCodeDatabase, "Save failed", WithContext("user_id", 42), WithStack())
+	if got != structured || got.Code != TestCodeValidation || got.Context["user_id"] != 42 || got.Cause != nil {
+		t.Errorf("Expected the *Error to be returned unchanged with the added context, got %+v", got)
+	}
+	if EnsureStructured(EnsureStructured(got, TestCodeDatabase, "again"), TestCodeDatabase, "again") != structured {
+		t.Error("Expected repeated calls not to wrap")
+	}
+
+	plain := errors.New("disk full")
+	wrapped := EnsureStructured(plain, TestCodeDatabase, "Save failed")
+	if wrapped.Code != TestCodeDatabase || wrapped.Message != "Save failed" || wrapped.Cause != plain {
+		t.Errorf("Expected a plain error to be wrapped, got %+v", wrapped)
+	}
+
+	mixed := EnsureStructured(fmt.Errorf("repo: %w", structured), TestCodeDatabase, "Save failed")
+	if mixed.Code != TestCodeValidation || !errors.Is(mixed, structured) {
+		t.Errorf("Expected the code of the inner *Error to be kept, got %s", mixed.Code)
+	}
+}
@@ -0,0 +1,206 @@
+// errortest.go: Test assertions for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+// Package errortest provides assertions for tests of code returning go-errors errors.
+// Each assertion reports a failure with t.Errorf and returns whether it passed, so that
+// callers can stop with t.FailNow when later checks depend on it.
+//
+// Example:
+//
+//	err := svc.CreateUser(ctx, req)
+//	errortest.AssertCode(t, err, ErrCodeValidation)
+//	errortest.AssertContextKey(t, err, "field", "email")
+package errortest
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/agilira/go-errors"
+)
+
+// TimestampKey is the JSON key removed by AssertJSON before comparing documents.
+const TimestampKey = "timestamp"
+
+// AssertCode reports an error unless an *errors.Error in the chain of err has code.
+func AssertCode(t testing.TB, err error, code errors.ErrorCode) bool {
+	t.Helper()
+	if errors.HasCode(err, code) {
+		return true
+	}
+	t.Errorf("Expected error with code %s, got %v", code, codesOf(err))
+	return false
+}
+
+// AssertRetryable reports an error unless an *errors.Error in the chain of err is retryable.
+func AssertRetryable(t testing.TB, err error) bool {
+	t.Helper()
+	for _, e := range errors.Chain(err) {
+		if e.IsRetryable() {
+			return true
+		}
+	}
+	t.Errorf("Expected retryable error, got %v", err)
+	return false
+}
+
+// AssertSeverity reports an error unless the outermost *errors.Error in the chain of err
+// has severity.
+func AssertSeverity(t testing.TB, err error, severity errors.Severity) bool {
+	t.Helper()
+	e, ok := outermost(t, err)
+	if !ok {
+		return false
+	}
+	if e.Severity != severity {
+		t.Errorf("Expected severity %s, got %s", severity, e.Severity)
+		return false
+	}
+	return true
+}
+
+// AssertUserMessage reports an error unless the outermost *errors.Error in the chain of
+// err has the user message msg, as returned by UserMessage.
+func AssertUserMessage(t testing.TB, err error, msg string) bool {
+	t.Helper()
+	e, ok := outermost(t, err)
+	if !ok {
+		return false
+	}
+	if got := e.UserMessage(); got != msg {
+		t.Errorf("Expected user message %q, got %q", msg, got)
+		return false
+	}
+	return true
+}
+
+// AssertContextKey reports an error unless an *errors.Error in the chain of err has the
+// context entry key with a value equal to value, compared with reflect.DeepEqual.
+func AssertContextKey(t testing.TB, err error, key string, value interface{}) bool {
+	t.Helper()
+	var found []interface{}
+	for _, e := range errors.Chain(err) {
+		if got, ok := e.Context[key]; ok {
+			if reflect.DeepEqual(got, value) {
+				return true
+			}
+			found = append(found, got)
+		}
+	}
+	if len(found) == 0 {
+		t.Errorf("Expected context key %q, not found in %v", key, err)
+	} else {
+		t.Errorf("Expected context %q=%v, got %v", key, value, found)
+	}
+	return false
+}
+
+// AssertJSON reports an error unless the JSON representation of err equals the document
+// want. Both documents are compared as decoded values after removing the TimestampKey
+// entries and the ignore keys at every nesting level, so that the timestamps of the error
+// and of its causes do not make the comparison fail.
+//
+// Example:
+//
+//	errortest.AssertJSON(t, err, `{"code":"NOT_FOUND","message":"User not found","severity":"error"}`, "stack")
+func AssertJSON(t testing.TB, err error, want string, ignore ...string) bool {
+	t.Helper()
+	got, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		t.Errorf("Failed to marshal error: %v", marshalErr)
+		return false
+	}
+	keys := append([]string{TimestampKey}, ignore...)
+	gotValue, decodeErr := decodeJSON(got, keys)
+	if decodeErr != nil {
+		t.Errorf("Failed to decode error JSON: %v", decodeErr)
+		return false
+	}
+	wantValue, decodeErr := decodeJSON([]byte(want), keys)
+	if decodeErr != nil {
+		t.Errorf("Failed to decode expected JSON: %v", decodeErr)
+		return false
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("Expected JSON %s, got %s", compact(want), got)
+		return false
+	}
+	return true
+}
+
+// AssertGoldenJSON is like AssertJSON with the expected document read from the golden
+// file at path.
+func AssertGoldenJSON(t testing.TB, err error, path string, ignore ...string) bool {
+	t.Helper()
+	want, readErr := os.ReadFile(path)
+	if readErr != nil {
+		t.Errorf("Failed to read golden file: %v", readErr)
+		return false
+	}
+	return AssertJSON(t, err, string(want), ignore...)
+}
+
+// outermost returns the first *errors.Error in the chain of err, reporting an error if
+// there is none.
+func outermost(t testing.TB, err error) (*errors.Error, bool) {
+	t.Helper()
+	var e *errors.Error
+	if !stderrors.As(err, &e) {
+		t.Errorf("Expected *errors.Error, got %T: %v", err, err)
+		return nil, false
+	}
+	return e, true
+}
+
+// codesOf returns the codes in the chain of err for failure messages.
+func codesOf(err error) []errors.ErrorCode {
+	var codes []errors.ErrorCode
+	for _, e := range errors.Chain(err) {
+		codes = append(codes, e.Code)
+	}
+	return codes
+}
+
+// decodeJSON decodes data and removes the keys from every object in it.
+func decodeJSON(data []byte, keys []string) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	stripKeys(v, keys)
+	return v, nil
+}
+
+// stripKeys removes the keys from every object nested in v.
+func stripKeys(v interface{}, keys []string) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for _, k := range keys {
+			delete(x, k)
+		}
+		for _, child := range x {
+			stripKeys(child, keys)
+		}
+	case []interface{}:
+		for _, child := range x {
+			stripKeys(child, keys)
+		}
+	}
+}
+
+// compact returns the JSON document s without insignificant whitespace, or s itself if it
+// is not valid JSON.
+func compact(s string) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(s)); err != nil {
+		return s
+	}
+	return buf.String()
+}
@@ -0,0 +1,102 @@
+// errortest_test.go: Tests for the errortest package of the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errortest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agilira/go-errors"
+)
+
+// recorder captures the failures reported by the assertions.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func newTestError() error {
+	e := errors.New("VALIDATION_ERROR", "Email is required").
+		WithUserMessage("Please enter your email").
+		WithContext("field", "email").
+		WithWarningSeverity().
+		AsRetryable()
+	return fmt.Errorf("handler: %w", errors.WrapPreserve(e, "SIGNUP_FAILED", "Signup failed"))
+}
+
+func TestAssertionsPass(t *testing.T) {
+	r := &recorder{TB: t}
+	err := newTestError()
+
+	ok := AssertCode(r, err, "VALIDATION_ERROR") &&
+		AssertRetryable(r, err) &&
+		AssertSeverity(r, err, errors.SeverityWarning) &&
+		AssertUserMessage(r, err, "Please enter your email") &&
+		AssertContextKey(r, err, "field", "email")
+	if !ok || len(r.failures) != 0 {
+		t.Errorf("Expected assertions to pass, got %v", r.failures)
+	}
+}
+
+func TestAssertionsFail(t *testing.T) {
+	r := &recorder{TB: t}
+	err := newTestError()
+
+	checks := []bool{
+		AssertCode(r, err, "NOT_FOUND"),
+		AssertRetryable(r, errors.New("FATAL", "Fatal")),
+		AssertSeverity(r, err, errors.SeverityCritical),
+		AssertUserMessage(r, fmt.Errorf("plain"), "message"),
+		AssertContextKey(r, err, "field", "name"),
+		AssertContextKey(r, err, "missing", nil),
+	}
+	for i, ok := range checks {
+		if ok {
+			t.Errorf("Expected check %d to fail", i)
+		}
+	}
+	if len(r.failures) != len(checks) {
+		t.Errorf("Expected %d failures, got %v", len(checks), r.failures)
+	}
+}
+
+func TestAssertJSON(t *testing.T) {
+	cause := errors.New("DB_ERROR", "Connection lost").WithContext("host", "db1")
+	err := errors.WrapNoStack(cause, "QUERY_FAILED", "Query failed")
+	cause.Stack = nil
+	want := `{
+		"code": "QUERY_FAILED",
+		"message": "Query failed",
+		"severity": "error",
+		"cause": {"code": "DB_ERROR", "message": "Connection lost", "severity": "error", "context": {"host": "db1"}}
+	}`
+
+	r := &recorder{TB: t}
+	if !AssertJSON(r, err, want) {
+		t.Errorf("Expected JSON to match ignoring timestamps, got %v", r.failures)
+	}
+	if AssertJSON(r, err, `{"code":"QUERY_FAILED"}`) {
+		t.Error("Expected JSON mismatch to fail")
+	}
+
+	path := filepath.Join(t.TempDir(), "error.golden.json")
+	if writeErr := os.WriteFile(path, []byte(`{"code":"QUERY_FAILED","message":"Query failed"}`), 0o600); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	r = &recorder{TB: t}
+	if !AssertGoldenJSON(r, err, path, "severity", "cause") {
+		t.Errorf("Expected golden file to match with ignored keys, got %v", r.failures)
+	}
+}
@@ -0,0 +1,73 @@
+// event.go: Event-driven architecture integration for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"strings"
+)
+
+// ErrCodeEventChannel is the code returned by PublishToChannel when the event cannot be sent.
+const ErrCodeEventChannel ErrorCode = "EVENT_CHANNEL_ERROR"
+
+// EventName returns the event name of the error for event-sourced systems.
+// Unless overridden with WithEventName, the name is derived from the code by lowercasing it,
+// dropping a trailing "_ERROR" and adding the "error." prefix:
+// "VALIDATION_ERROR" becomes "error.validation" and "USER_NOT_FOUND" becomes "error.user_not_found".
+func (e *Error) EventName() string {
+	if e.eventName != "" {
+		return e.eventName
+	}
+	name := strings.ToLower(string(e.Code))
+	name = strings.TrimSuffix(name, "_error")
+	if name == "" || name == "error" {
+		return "error"
+	}
+	return "error." + name
+}
+
+// WithEventName overrides the event name derived from the code and returns the error for chaining.
+func (e *Error) WithEventName(name string) *Error {
+	e.eventName = name
+	return e
+}
+
+// ToEventPayload returns a flat map suitable for publishing the error as an event.
+// It contains the event name, code, message, severity and the timestamp in Unix milliseconds.
+// The trace ID is included when set with WithTraceID or WrapCtx, or when the error context
+// holds a "trace_id" entry.
+func (e *Error) ToEventPayload() map[string]interface{} {
+	payload := map[string]interface{}{
+		"event":     e.EventName(),
+		"code":      string(e.Code),
+		"message":   e.Message,
+		"severity":  string(e.Severity),
+		"timestamp": e.Timestamp.UnixMilli(),
+	}
+	if e.TraceID != "" {
+		payload["trace_id"] = e.TraceID
+	} else if traceID, ok := e.Context["trace_id"]; ok {
+		payload["trace_id"] = traceID
+	}
+	return payload
+}
+
+// PublishToChannel sends the event payload of the error to ch without blocking.
+// It returns an error with code ErrCodeEventChannel if ch is nil or has no room for the event,
+// so that publishing never stalls the error path.
+func (e *Error) PublishToChannel(ch chan<- interface{}) error {
+	if ch == nil {
+		return New(ErrCodeEventChannel, "event channel is nil")
+	}
+	select {
+	case ch <- e.ToEventPayload():
+		return nil
+	default:
+		retryable := func(e *Error) { e.Retryable = true }
+		return New(ErrCodeEventChannel, "event channel is full",
+			WithContext("event", e.EventName()), retryable)
+	}
+}
@@ -190,6 +190,25 @@ func ExampleError_chainMethods() {
 	// Severity: warning
 }
 
+// Example of the fmt verbs supported by Error
+func ExampleError_Format() {
+	err := errors.New(ErrCodeValidation, `Name contains "quotes"`)
+
+	fmt.Printf("%v\n", err)
+	fmt.Printf("%q\n", err)
+	fmt.Printf("%#q\n", err)
+	fmt.Printf("[%-45s]\n", err)
+	fmt.Printf("%+v\n", err.WithContext("field", "name"))
+	// Output:
+	// [VALIDATION_ERROR]: Name contains "quotes"
+	// "[VALIDATION_ERROR]: Name contains \"quotes\""
+	// `[VALIDATION_ERROR]: Name contains "quotes"`
+	// [[VALIDATION_ERROR]: Name contains "quotes"   ]
+	// [VALIDATION_ERROR]: Name contains "quotes"
+	// SEVERITY: error
+	// CONTEXT: field=name
+}
+
 // Example of real-world usage patterns
 func Example_realWorldUsage() {
 	// Simulate a service layer function
@@ -0,0 +1,118 @@
+// exitcode.go: Process exit codes for CLI tools for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// DefaultExitCode is the exit code returned by ExitCode for errors without a registered
+// mapping.
+const DefaultExitCode = 1
+
+var (
+	exitCodeMu         sync.RWMutex
+	exitCodeByCode     = make(map[ErrorCode]int)
+	exitCodeBySeverity = make(map[Severity]int)
+)
+
+// fatalExit and fatalOutput are the process exit and the writer used by FatalIfError,
+// replaced in tests.
+var (
+	fatalExit             = os.Exit
+	fatalOutput io.Writer = os.Stderr
+)
+
+// RegisterExitCode maps code to the process exit code returned by ExitCode. Registering
+// a code again replaces its exit code, and an exit code of 0 removes the mapping.
+// It is safe for concurrent use, but is meant to be called at startup.
+//
+// Example:
+//
+//	func init() {
+//		errors.RegisterExitCode(ErrCodeConfig, 78)   // EX_CONFIG
+//		errors.RegisterExitCode(ErrCodeNotFound, 66) // EX_NOINPUT
+//	}
+func RegisterExitCode(code ErrorCode, exitCode int) {
+	exitCodeMu.Lock()
+	defer exitCodeMu.Unlock()
+	if exitCode == 0 {
+		delete(exitCodeByCode, code)
+		return
+	}
+	exitCodeByCode[code] = exitCode
+}
+
+// RegisterSeverityExitCode maps severity to the exit code returned by ExitCode for errors
+// whose code has no mapping. An exit code of 0 removes the mapping.
+func RegisterSeverityExitCode(severity Severity, exitCode int) {
+	exitCodeMu.Lock()
+	defer exitCodeMu.Unlock()
+	if exitCode == 0 {
+		delete(exitCodeBySeverity, severity)
+		return
+	}
+	exitCodeBySeverity[severity] = exitCode
+}
+
+// ExitCode returns the process exit code for err: 0 if err is nil, otherwise the exit
+// code registered for the code of the outermost *Error in the chain that has one, then
+// the one registered for the severity of the outermost *Error, and DefaultExitCode if
+// neither is registered.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	exitCodeMu.RLock()
+	defer exitCodeMu.RUnlock()
+
+	exitCode := 0
+	var w chainWalk
+	walkErrors(err, func(cur error) bool {
+		if e, ok := cur.(*Error); ok {
+			exitCode = exitCodeByCode[e.Code]
+		}
+		return exitCode == 0
+	}, &w)
+	if exitCode != 0 {
+		return exitCode
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		if exitCode, ok := exitCodeBySeverity[e.Severity]; ok {
+			return exitCode
+		}
+	}
+	return DefaultExitCode
+}
+
+// FatalIfError does nothing if err is nil. Otherwise it prints the user message of err,
+// or its Error() text if it is not an *Error, to standard error and exits the process
+// with ExitCode(err). Deferred functions are not run.
+//
+// Example:
+//
+//	func main() {
+//		errors.FatalIfError(run(os.Args[1:]))
+//	}
+func FatalIfError(err error) {
+	if err == nil {
+		return
+	}
+	message := err.Error()
+	var e *Error
+	if errors.As(err, &e) {
+		message = e.UserMessage()
+	}
+	_, _ = fmt.Fprintln(fatalOutput, message)
+	fatalExit(ExitCode(err))
+}
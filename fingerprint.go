@@ -0,0 +1,88 @@
+// fingerprint.go: Error grouping fingerprints for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// fingerprintFrames is the number of top stack frames included in a fingerprint.
+const fingerprintFrames = 3
+
+var fingerprintInJSON atomic.Bool
+
+// SetFingerprintInJSON controls whether MarshalJSON includes a "fingerprint" field.
+// It is disabled by default.
+func SetFingerprintInJSON(enabled bool) {
+	fingerprintInJSON.Store(enabled)
+}
+
+// WithFingerprint overrides the fingerprint returned by Fingerprint and returns the error
+// for chaining. Use it to merge groups that the computed fingerprint keeps apart, or to
+// split ones it merges.
+func (e *Error) WithFingerprint(fingerprint string) *Error {
+	e.fingerprint = fingerprint
+	return e
+}
+
+// Fingerprint returns a stable identifier for grouping occurrences of the same error in
+// aggregation tools such as Sentry or a log pipeline. It hashes the code, the message
+// with digit sequences masked, so that "user 42" and "user 7" group together, and the
+// function names of the top stack frames. Line numbers are left out, so the fingerprint
+// survives unrelated edits to the source. An override set with WithFingerprint is
+// returned as is.
+//
+// Example:
+//
+//	sentry.WithScope(func(scope *sentry.Scope) {
+//		scope.SetFingerprint([]string{err.Fingerprint()})
+//		sentry.CaptureException(err)
+//	})
+func (e *Error) Fingerprint() string {
+	if e.fingerprint != "" {
+		return e.fingerprint
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(e.Code))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(messageTemplate(e.Message)))
+	frames := e.StackFrames()
+	if len(frames) > fingerprintFrames {
+		frames = frames[:fingerprintFrames]
+	}
+	for _, f := range frames {
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(f.Function))
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// messageTemplate replaces every run of digits in msg with a single '#'.
+func messageTemplate(msg string) string {
+	if strings.IndexAny(msg, "0123456789") < 0 {
+		return msg
+	}
+	var b strings.Builder
+	b.Grow(len(msg))
+	inDigits := false
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= '0' && c <= '9' {
+			if !inDigits {
+				b.WriteByte('#')
+			}
+			inDigits = true
+			continue
+		}
+		inDigits = false
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,181 @@
+// format.go: fmt.Formatter support for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format implements fmt.Formatter.
+//
+//	%s, %v  the Error() string, e.g. "[CODE]: message"
+//	%q      the Error() string, double-quoted with Go escapes; %#q uses backquotes when possible
+//	%+v     the full diagnostic view: code and message, severity, category, field,
+//	        context as key=value pairs, the stack trace, and then the cause chain, each cause
+//	        introduced by "Caused by: " and indented one level deeper; causes of other
+//	        types are unwrapped too, including the branches of errors.Join
+//	%w      in fmt.Errorf, the Error() string; the *Error stays reachable through
+//	        errors.As and can be recovered with UnwrapFmt
+//
+// Width, precision and the '-' flag apply to %s, %v and %q as for strings, e.g. %-40s.
+//
+// Example:
+//
+//	fmt.Printf("%+v\n", err)
+//	// [DATABASE_ERROR]: Query failed
+//	// SEVERITY: error
+//	// CONTEXT: table=users user_id=123
+//	// STACK:
+//	// main.loadUser
+//	//	/app/users.go:42
+//	// ...
+//	// Caused by: connection refused
+func (e *Error) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if f.Flag('+') {
+			e.writeDiagnostic(f, 0)
+			return
+		}
+		_, _ = fmt.Fprintf(f, fmt.FormatString(f, verb), e.Error())
+	case 's', 'q':
+		_, _ = fmt.Fprintf(f, fmt.FormatString(f, verb), e.Error())
+	default:
+		_, _ = io.WriteString(f, e.Error())
+	}
+}
+
+// writeDiagnostic writes the %+v representation of e, indenting every line by depth levels.
+func (e *Error) writeDiagnostic(w io.Writer, depth int) {
+	indent := strings.Repeat("  ", depth)
+	var b strings.Builder
+	b.WriteString(e.headline())
+	line := func(label, value string) {
+		b.WriteByte('\n')
+		b.WriteString(indent)
+		b.WriteString(label)
+		b.WriteString(value)
+	}
+
+	context, value, _ := e.redacted()
+	line("SEVERITY: ", string(e.Severity))
+	if e.Category != "" {
+		line("CATEGORY: ", e.Category)
+	}
+	if e.Op != "" {
+		line("OP: ", e.Op)
+	}
+	if e.ErrorID != "" {
+		line("ERROR ID: ", e.ErrorID)
+	}
+	if e.Field != "" {
+		line("FIELD: ", e.Field+"="+value)
+	}
+	if len(context) > 0 {
+		keys := make([]string, 0, len(context))
+		for k := range context {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = fmt.Sprintf("%s=%v", k, context[k])
+		}
+		line("CONTEXT: ", strings.Join(pairs, " "))
+	}
+	for _, ev := range e.Events {
+		line("EVENT: ", ev.Time.Format(time.RFC3339Nano)+" "+ev.Message)
+	}
+	if stack := e.stackText(); stack != "" {
+		line("STACK:", "")
+		for _, s := range strings.Split(strings.TrimSuffix(stack, "\n"), "\n") {
+			line(s, "")
+		}
+	}
+	_, _ = io.WriteString(w, b.String())
+
+	writeCause(w, e.Cause, depth)
+}
+
+// writeCause writes cause as a "Caused by: " section indented below depth. Errors of other
+// types are written with their message and then unwrapped, so that an *Error wrapped by
+// fmt.Errorf is still printed in full; the branches of an errors.Join aggregate are written
+// as sibling causes. A cause implementing fmt.Formatter is written with %+v and not
+// unwrapped, since it prints its own chain.
+func writeCause(w io.Writer, cause error, depth int) {
+	if cause == nil || depth >= GetMaxChainDepth() {
+		return
+	}
+	if u, ok := cause.(interface{ Unwrap() []error }); ok {
+		if _, formatter := cause.(fmt.Formatter); !formatter {
+			for _, branch := range u.Unwrap() {
+				writeCause(w, branch, depth)
+			}
+			return
+		}
+	}
+	indent := strings.Repeat("  ", depth)
+	_, _ = io.WriteString(w, "\n"+indent+"Caused by: ")
+	if ce, ok := cause.(*Error); ok {
+		ce.writeDiagnostic(w, depth+1)
+		return
+	}
+	if _, ok := cause.(fmt.Formatter); ok {
+		_, _ = fmt.Fprintf(w, "%+v", cause)
+		return
+	}
+	_, _ = io.WriteString(w, strings.ReplaceAll(cause.Error(), "\n", "\n"+indent+"  "))
+	if u, ok := cause.(interface{ Unwrap() error }); ok {
+		writeCause(w, u.Unwrap(), depth+1)
+	}
+}
+
+// WrapFmt wraps the error like fmt.Errorf would, but returns a structured *Error: the new error
+// keeps the receiver's code, has the receiver as its Cause, and its message is the receiver's
+// message followed by the formatted text. It bridges code migrating from fmt.Errorf.
+//
+// Example:
+//
+//	return err.WrapFmt("while loading user %d", userID)
+//	// [DATABASE_ERROR]: Query failed: while loading user 42
+func (e *Error) WrapFmt(format string, args ...interface{}) *Error {
+	return wrapWithOptions(e, WrapOptions{
+		Message:      e.Message + ": " + fmt.Sprintf(format, args...),
+		PreserveCode: true,
+	}, 1)
+}
+
+// UnwrapFmt detects a *Error wrapped by fmt.Errorf("...: %w", structuredErr), possibly
+// through several fmt.Errorf layers, and returns it along with the text the outer layers
+// added in front of it. It returns false if err is itself a *Error or contains no *Error.
+//
+// Example:
+//
+//	if inner, msg, ok := errors.UnwrapFmt(err); ok {
+//		return inner.WrapFmt("%s", msg)
+//	}
+func UnwrapFmt(err error) (*Error, string, bool) {
+	if err == nil {
+		return nil, "", false
+	}
+	if _, ok := err.(*Error); ok {
+		return nil, "", false
+	}
+	for cur := range unwrapChain(errors.Unwrap(err)) {
+		if inner, ok := cur.(*Error); ok {
+			msg := strings.TrimSuffix(err.Error(), inner.Error())
+			msg = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(msg), ":"))
+			return inner, msg, true
+		}
+	}
+	return nil, "", false
+}
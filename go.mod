@@ -1,5 +1,5 @@
 module github.com/agilira/go-errors
 
-go 1.23.11
+go 1.24.0
 
 require github.com/agilira/go-timecache v1.0.2
@@ -0,0 +1,102 @@
+// group.go: Concurrent task groups for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Context keys recorded by Group on the errors of failed tasks.
+const (
+	TaskNameKey  = "task"       // name passed to Group.Go
+	TaskIndexKey = "task_index" // position of the task among the Go calls, starting at 0
+)
+
+// Group runs functions concurrently, like golang.org/x/sync/errgroup, but collects every
+// failure instead of only the first one. The zero value is ready to use; a Group must not
+// be copied after first use.
+//
+// Example:
+//
+//	var g errors.Group
+//	for _, id := range ids {
+//		g.Go("load "+id, func() error { return load(id) })
+//	}
+//	if err := g.Wait(); err != nil {
+//		return err // *MultiError with one entry per failed task
+//	}
+type Group struct {
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	count int
+	fails []groupFailure
+}
+
+// groupFailure is the error of a task along with its position among the Go calls.
+type groupFailure struct {
+	index int
+	err   *Error
+}
+
+// Go runs fn in a new goroutine. If fn returns an error or panics, the failure is recorded
+// with the task name and index in its context under TaskNameKey and TaskIndexKey. The
+// error keeps its code when it carries one; a panic is converted by NewFromPanic.
+func (g *Group) Go(name string, fn func() error) {
+	g.mu.Lock()
+	index := g.count
+	g.count++
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		err := RecoverFunc(fn)
+		if err == nil {
+			return
+		}
+		failure := annotateFailure(err, map[string]interface{}{TaskNameKey: name, TaskIndexKey: index})
+
+		g.mu.Lock()
+		g.fails = append(g.fails, groupFailure{index: index, err: failure})
+		g.mu.Unlock()
+	}()
+}
+
+// Wait blocks until every function started with Go has returned, then returns the
+// failures as a *MultiError ordered by task index, or nil if every task succeeded.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.fails) == 0 {
+		return nil
+	}
+	sort.Slice(g.fails, func(i, j int) bool { return g.fails[i].index < g.fails[j].index })
+	errs := make([]*Error, len(g.fails))
+	for i, f := range g.fails {
+		errs[i] = f.err
+	}
+	return &MultiError{errs: errs}
+}
+
+// annotateFailure wraps err without a stack trace, keeping its code and message, and adds
+// context to the wrapper. It tags the failures collected by Group and Collector.
+func annotateFailure(err error, context map[string]interface{}) *Error {
+	message := err.Error()
+	var e *Error
+	if errors.As(err, &e) {
+		message = e.Message
+	}
+	return wrapWithOptions(err, WrapOptions{
+		Message:      message,
+		SkipStack:    true,
+		PreserveCode: true,
+		Context:      context,
+	}, 1)
+}
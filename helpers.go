@@ -9,10 +9,15 @@ package errors
 import (
 	"errors"
 	"fmt"
-
-	"github.com/agilira/go-timecache"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
+// verboseErrors is set by SetVerboseErrors.
+var verboseErrors atomic.Bool
+
 // Wrap wraps an existing error with a new code and message, capturing the current stack trace.
 // This is useful for adding context to errors that occur deeper in the call stack.
 // If code is empty or whitespace-only, DefaultErrorCode will be used instead.
@@ -22,27 +27,290 @@ import (
 //	if err := someOperation(); err != nil {
 //		return Wrap(err, "OPERATION_FAILED", "Failed to process user data")
 //	}
-func Wrap(err error, code ErrorCode, message string) *Error {
-	if !validateErrorCode(code) {
-		code = DefaultErrorCode
-	}
-	return &Error{
-		Code:      code,
-		Message:   message,
-		Timestamp: timecache.CachedTime(),
-		Severity:  SeverityError,
+func Wrap(err error, code ErrorCode, message string, opts ...Option) *Error {
+	return wrapWithOptions(err, WrapOptions{Code: code, Message: message}, 1, opts...)
+}
+
+// Wrap wraps the error with a new code and message, like the package-level Wrap function.
+// It allows multi-layer wrapping to read left to right instead of inside out.
+//
+// Example:
+//
+//	return err.Wrap("SERVICE_ERROR", "Failed to load profile").
+//		WithContext("user_id", userID)
+func (e *Error) Wrap(code ErrorCode, message string, opts ...Option) *Error {
+	return wrapWithOptions(e, WrapOptions{Code: code, Message: message}, 1, opts...)
+}
+
+// WrapOptions configures WrapWithOptions. The zero value behaves like Wrap with an empty
+// code and message.
+type WrapOptions struct {
+	Code         ErrorCode              // Code of the wrapper; DefaultErrorCode if empty
+	Message      string                 // Message of the wrapper
+	SkipStack    bool                   // Do not capture a stack trace
+	StackSkip    int                    // Extra frames to skip above the caller when capturing the stack
+	PreserveCode bool                   // Reuse the code of the first *Error in the chain, if any
+	Context      map[string]interface{} // Initial context, copied into the wrapper
+	Severity     Severity               // Severity of the wrapper; SeverityError if empty
+	Inherit      bool                   // Inherit the metadata of the first *Error in the chain, see WrapPreserve
+}
+
+// WrapWithOptions wraps err according to opts. It is the single implementation behind
+// Wrap and its variants, and can be used directly when several knobs are needed at once.
+//
+// Example:
+//
+//	return WrapWithOptions(err, WrapOptions{
+//		Message:      "Failed to charge card",
+//		PreserveCode: true,
+//		Severity:     SeverityCritical,
+//		Context:      map[string]interface{}{"order_id": orderID},
+//	})
+func WrapWithOptions(err error, opts WrapOptions) *Error {
+	return wrapWithOptions(err, opts, 1)
+}
+
+// WrapNoStack wraps err like Wrap but without capturing a stack trace.
+// Use it on hot paths where the cause already carries enough location information.
+func WrapNoStack(err error, code ErrorCode, message string) *Error {
+	return wrapWithOptions(err, WrapOptions{Code: code, Message: message, SkipStack: true}, 1)
+}
+
+// WrapPreservingCode wraps err with a new message, keeping the code of the first *Error
+// in the chain. If the chain has no *Error, DefaultErrorCode is used.
+func WrapPreservingCode(err error, message string) *Error {
+	return wrapWithOptions(err, WrapOptions{Message: message, PreserveCode: true}, 1)
+}
+
+// WrapWithSkip wraps err like Wrap, skipping skip additional frames when capturing the
+// stack trace. It is meant for helper functions that wrap errors on behalf of their caller.
+func WrapWithSkip(err error, code ErrorCode, message string, skip int) *Error {
+	return wrapWithOptions(err, WrapOptions{Code: code, Message: message, StackSkip: skip}, 1)
+}
+
+// WrapPreserve wraps err like Wrap, but the wrapper inherits the metadata of the first
+// *Error in the chain, so that adding a layer does not hide it from the top-level view:
+// the context entries (the wrapper's own entries win), the severity, the retryable flag
+// with its RetryAfter and RetryPolicy, and the user message. Options are applied after the
+// inheritance and can override it.
+//
+// Example:
+//
+//	// The critical severity and user message of err stay visible on the wrapper
+//	return errors.WrapPreserve(err, "SERVICE_ERROR", "Failed to process order")
+func WrapPreserve(err error, code ErrorCode, message string, opts ...Option) *Error {
+	return wrapWithOptions(err, WrapOptions{Code: code, Message: message, Inherit: true}, 1, opts...)
+}
+
+// WrapWithContext wraps err like Wrap and copies context into the wrapper's context,
+// like NewWithContext does for new errors.
+//
+// Example:
+//
+//	if err := db.QueryRow(q, id).Scan(&u); err != nil {
+//		return errors.WrapWithContext(err, ErrCodeDB, "Query failed",
+//			map[string]interface{}{"table": "users", "user_id": id})
+//	}
+func WrapWithContext(err error, code ErrorCode, message string, context map[string]interface{}, opts ...Option) *Error {
+	return wrapWithOptions(err, WrapOptions{Code: code, Message: message, Context: context}, 1, opts...)
+}
+
+// WrapWithField wraps err like Wrap and sets the field name and value of the wrapper,
+// like NewWithField does for new errors.
+//
+// Example:
+//
+//	if _, err := mail.ParseAddress(req.Email); err != nil {
+//		return errors.WrapWithField(err, ErrCodeValidation, "Invalid email", "email", req.Email)
+//	}
+func WrapWithField(err error, code ErrorCode, message, field, value string, opts ...Option) *Error {
+	setField := func(e *Error) {
+		e.Field = field
+		e.Value = value
+	}
+	return wrapWithOptions(err, WrapOptions{Code: code, Message: message}, 1, append([]Option{setField}, opts...)...)
+}
+
+// WrapIf wraps err like Wrap, or returns nil if err is nil. It returns error rather than
+// *Error so that the nil result stays a nil interface in the caller's return statement.
+//
+// Example:
+//
+//	return errors.WrapIf(tx.Commit(), ErrCodeDB, "Commit failed")
+func WrapIf(err error, code ErrorCode, message string, opts ...Option) error {
+	if err == nil {
+		return nil
+	}
+	return wrapWithOptions(err, WrapOptions{Code: code, Message: message}, 1, opts...)
+}
+
+// WrapUnlessCode wraps err like Wrap unless its chain already contains skipCode, in which
+// case err is returned unchanged. It returns nil if err is nil. Use it to avoid wrapping
+// the same failure twice as it crosses layers.
+//
+// Example:
+//
+//	// Validation errors pass through untouched, everything else becomes a service error
+//	return errors.WrapUnlessCode(err, ErrCodeValidation, ErrCodeService, "Failed to save user")
+func WrapUnlessCode(err error, skipCode, code ErrorCode, message string, opts ...Option) error {
+	if err == nil {
+		return nil
+	}
+	if HasCode(err, skipCode) {
+		return err
+	}
+	return wrapWithOptions(err, WrapOptions{Code: code, Message: message}, 1, opts...)
+}
+
+// EnsureStructured returns err as an *Error without piling up wrappers with redundant
+// codes. An *Error is returned unchanged, except for the options applied to it, such as
+// WithContext; options that only affect construction, such as WithStack, are ignored. A
+// chain holding an *Error below plain wrappers, such as those of fmt.Errorf, is wrapped
+// with message but keeps the code of that *Error. Only a plain error is wrapped with code
+// and message, like Wrap. EnsureStructured returns nil if err is nil.
+//
+// Example:
+//
+//	// Safe to call at every layer: err is wrapped at most once
+//	return errors.EnsureStructured(err, ErrCodeStorage, "Failed to save order",
+//		errors.WithContext("order_id", orderID))
+func EnsureStructured(err error, code ErrorCode, message string, opts ...Option) *Error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		choice, withCaller := e.stackChoice, e.withCaller
+		for _, opt := range opts {
+			opt(e)
+		}
+		e.stackChoice, e.withCaller = choice, withCaller
+		return e
+	}
+	return wrapWithOptions(err, WrapOptions{Code: code, Message: message, PreserveCode: true}, 1, opts...)
+}
+
+// wrapWithOptions implements the Wrap family. The skip parameter is the number of frames
+// above wrapWithOptions' caller to omit from the stack trace, so that it starts at the
+// user's call site. SkipStack acts like a WithoutStack option that options can override.
+func wrapWithOptions(err error, opts WrapOptions, skip int, options ...Option) *Error {
+	var inner *Error
+	hasInner := errors.As(err, &inner)
+
+	code := opts.Code
+	if opts.PreserveCode && hasInner {
+		code = inner.Code
+	}
+	severity := opts.Severity
+	if severity == "" {
+		severity = SeverityError
+	}
+	context := make(map[string]interface{}, len(opts.Context))
+	for k, v := range opts.Context {
+		context[k] = v
+	}
+
+	wrapper := &Error{
+		Code:      checkCode(code),
+		Message:   opts.Message,
+		Timestamp: now(),
+		Severity:  severity,
 		Cause:     err,
-		Context:   make(map[string]interface{}),
-		Stack:     CaptureStacktrace(1),
+		Context:   context,
+	}
+	if opts.Inherit && hasInner {
+		wrapper.inherit(inner, opts.Severity == "")
+	}
+	if opts.SkipStack {
+		wrapper.stackChoice = stackSuppress
+	}
+	wrapper.finish(options, true, skip+opts.StackSkip+1)
+	if hasInner {
+		recordWrap(inner, wrapper)
+	}
+	return wrapper
+}
+
+// inherit copies the metadata of inner into the wrapper e, as described in WrapPreserve.
+// The severity is inherited only when the wrapper has no explicit one.
+func (e *Error) inherit(inner *Error, severity bool) {
+	for k, v := range inner.Context {
+		if _, exists := e.Context[k]; !exists {
+			e.Context[k] = v
+		}
+	}
+	if severity && inner.Severity != "" {
+		e.Severity = inner.Severity
+	}
+	if e.ErrorID == "" {
+		e.ErrorID = inner.ErrorID
+	}
+	e.Retryable = inner.Retryable
+	e.RetryAfter = inner.RetryAfter
+	e.RetryPolicy = inner.RetryPolicy
+	if e.UserMsg == "" && e.UserMsgKey == "" {
+		e.UserMsg, e.UserMsgKey, e.UserMsgArgs = inner.UserMsg, inner.UserMsgKey, inner.UserMsgArgs
 	}
 }
 
 // Error implements the error interface for *Error.
-// It returns a formatted string containing the error code and message.
+// It returns a formatted string containing the error code and message. In verbose mode,
+// enabled for every error with SetVerboseErrors or for one error with WithVerboseError,
+// it is followed by the cause chain like the text of fmt.Errorf("%w"), e.g.
+// "[SERVICE_ERROR]: Load failed: [DB_ERROR]: Query failed: connection reset".
 func (e *Error) Error() string {
+	if e.Cause == nil || !(e.verbose || verboseErrors.Load()) {
+		return e.headline()
+	}
+	var b strings.Builder
+	b.WriteString(e.headline())
+	writeCauseText(&b, e.Cause, 1)
+	return b.String()
+}
+
+// headline returns the "[CODE]: message" text of the error, without its causes.
+func (e *Error) headline() string {
 	return fmt.Sprintf("[%s]: %s", e.Code, e.Message)
 }
 
+// SetVerboseErrors controls whether Error() appends the cause chain for every *Error, so
+// that the underlying cause is not lost when an error reaches log.Printf. It is disabled
+// by default, keeping Error() to the code and message.
+func SetVerboseErrors(enabled bool) {
+	verboseErrors.Store(enabled)
+}
+
+// WithVerboseError makes Error() append the cause chain for this error, regardless of
+// SetVerboseErrors, and returns the error for chaining.
+//
+// Example:
+//
+//	log.Printf("sync failed: %v", errors.Wrap(err, "SYNC_FAILED", "Sync failed").WithVerboseError())
+func (e *Error) WithVerboseError() *Error {
+	e.verbose = true
+	return e
+}
+
+// writeCauseText appends ": " and the text of cause to b. The causes of an *Error are
+// followed up to GetMaxCauseDepth levels, after which "..." marks the truncation, which
+// also stops cycles; any other error contributes its own Error() text.
+func writeCauseText(b *strings.Builder, cause error, depth int) {
+	b.WriteString(": ")
+	ce, ok := cause.(*Error)
+	if !ok {
+		b.WriteString(cause.Error())
+		return
+	}
+	b.WriteString(ce.headline())
+	if ce.Cause == nil {
+		return
+	}
+	if depth >= GetMaxCauseDepth() {
+		b.WriteString(": ...")
+		return
+	}
+	writeCauseText(b, ce.Cause, depth+1)
+}
+
 // Unwrap returns the underlying cause error, implementing the error wrapping interface.
 func (e *Error) Unwrap() error {
 	return e.Cause
@@ -50,14 +318,51 @@ func (e *Error) Unwrap() error {
 
 // RootCause returns the original error in the error chain by unwrapping all nested errors.
 // This is useful for finding the root cause of an error that has been wrapped multiple times.
+// For aggregates implementing Unwrap() []error, such as the result of errors.Join, the
+// first branch is followed, so the root cause is the leftmost leaf of the error tree.
 func RootCause(err error) error {
-	for {
-		unwrapped := errors.Unwrap(err)
-		if unwrapped == nil {
-			return err
+	root := err
+	var w chainWalk
+	walkErrors(err, func(cur error) bool {
+		root = cur
+		switch u := cur.(type) {
+		case interface{ Unwrap() []error }:
+			return len(u.Unwrap()) > 0
+		case interface{ Unwrap() error }:
+			return u.Unwrap() != nil
 		}
-		err = unwrapped
-	}
+		return false
+	}, &w)
+	return root
+}
+
+// RootCauses returns every leaf of the error tree of err, from left to right: the root
+// cause of each branch of the aggregates implementing Unwrap() []error, such as the result
+// of errors.Join. For a linear chain it returns the single RootCause. It returns nil if
+// err is nil.
+//
+// Example:
+//
+//	for _, cause := range RootCauses(err) {
+//		log.Printf("root cause: %v", cause)
+//	}
+func RootCauses(err error) []error {
+	var roots []error
+	WalkChain(err, func(cur error) bool {
+		switch u := cur.(type) {
+		case interface{ Unwrap() []error }:
+			if len(u.Unwrap()) > 0 {
+				return true
+			}
+		case interface{ Unwrap() error }:
+			if u.Unwrap() != nil {
+				return true
+			}
+		}
+		roots = append(roots, cur)
+		return true
+	})
+	return roots
 }
 
 // HasCode checks if any error in the error chain has the given error code.
@@ -69,32 +374,106 @@ func RootCause(err error) error {
 //		// Handle validation-specific error
 //		log.Warning("Validation failed", "error", err)
 //	}
+//
+// Aggregates implementing Unwrap() []error, such as errors.Join results, MultiError and
+// ErrorGroup, are searched branch by branch.
 func HasCode(err error, code ErrorCode) bool {
-	for err != nil {
-		if ec, ok := err.(*Error); ok && ec.Code == code {
-			return true
+	found := false
+	var w chainWalk
+	walkErrors(err, func(cur error) bool {
+		if ec, ok := cur.(*Error); ok && ec.Code == code {
+			found = true
+		}
+		return !found
+	}, &w)
+	return found
+}
+
+// HasCategory checks if any error in the error chain has the given category, set with
+// WithCategory. Aggregates are searched branch by branch, like HasCode.
+//
+// Example:
+//
+//	if HasCategory(err, "billing") {
+//		alertBillingTeam(err)
+//	}
+func HasCategory(err error, category string) bool {
+	found := false
+	var w chainWalk
+	walkErrors(err, func(cur error) bool {
+		if ec, ok := cur.(*Error); ok && ec.Category == category {
+			found = true
+		}
+		return !found
+	}, &w)
+	return found
+}
+
+// RetryDelay returns the first non-zero retry delay found in the error chain, set with
+// WithRetryAfter or provided by any error implementing RetryScheduler.
+// The second return value is false if no error in the chain carries a delay.
+//
+// Example:
+//
+//	if delay, ok := RetryDelay(err); ok {
+//		time.Sleep(delay)
+//	}
+func RetryDelay(err error) (time.Duration, bool) {
+	for cur := range unwrapChain(err) {
+		if rs, ok := cur.(RetryScheduler); ok {
+			if d := rs.RetryDelay(); d != 0 {
+				return d, true
+			}
 		}
-		err = errors.Unwrap(err)
 	}
-	return false
+	return 0, false
 }
 
 // Is implements errors.Is compatibility for error comparison.
 // It returns true if the target error has the same error code.
+// A target created by NewSentinel only matches itself and its clones, so that errors
+// sharing a sentinel's code are not mistaken for it. A *Matcher target, such as the one returned
+// by MatchCodeAndField, compares the attributes it selects.
 func (e *Error) Is(target error) bool {
 	if target == nil {
 		return false
 	}
+	if m, ok := target.(*Matcher); ok {
+		return m.matches(e)
+	}
 	if te, ok := target.(*Error); ok {
+		if e == te {
+			return true
+		}
+		if te.sentinel {
+			return e.origin == te
+		}
 		return e.Code == te.Code
 	}
 	return false
 }
 
 // As implements errors.As compatibility for error type assertion.
-// It delegates the check to the underlying Cause error.
-// Note: It will not match the *Error instance itself, only errors in its cause chain.
+// It matches the *Error instance itself first: a target of type **Error, or a pointer to
+// any interface implemented by *Error (such as error or ErrorCoder), receives e.
+// Otherwise the check is delegated to the underlying Cause chain.
+// It returns false if target is nil or not a non-nil pointer.
 func (e *Error) As(target interface{}) bool {
+	if t, ok := target.(**Error); ok {
+		if t == nil {
+			return false
+		}
+		*t = e
+		return true
+	}
+	val := reflect.ValueOf(target)
+	if !val.IsValid() || val.Kind() != reflect.Ptr || val.IsNil() {
+		return false
+	}
+	if reflect.TypeOf(e).AssignableTo(val.Type().Elem()) {
+		val.Elem().Set(reflect.ValueOf(e))
+		return true
+	}
 	return errors.As(e.Cause, target)
 }
 
@@ -0,0 +1,93 @@
+// hooks.go: Error reporting hooks for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Hook receives errors for forwarding to an external system, such as Sentry, OpenTelemetry
+// span events or a metrics counter, without this package depending on its SDK.
+// Hooks run synchronously on the caller's goroutine and must be safe for concurrent use.
+// They should not create errors themselves with creation hooks registered, to avoid
+// unbounded recursion.
+type Hook func(*Error)
+
+var (
+	hooksMu       sync.Mutex // serializes registrations, readers use the atomic pointers
+	reportHooks   atomic.Pointer[[]Hook]
+	creationHooks atomic.Pointer[[]Hook]
+)
+
+// RegisterHook adds a hook invoked by (*Error).Report, in registration order.
+//
+// Example:
+//
+//	errors.RegisterHook(func(e *errors.Error) {
+//		errorCounter.WithLabelValues(string(e.Code), string(e.Severity)).Inc()
+//	})
+func RegisterHook(h Hook) {
+	addHook(&reportHooks, h)
+}
+
+// RegisterCreationHook adds a hook invoked for every error built by New, NewWithField,
+// NewWithContext and the Wrap family, right after construction and before any chained
+// With* call. With no creation hook registered, constructors pay a single atomic load.
+func RegisterCreationHook(h Hook) {
+	addHook(&creationHooks, h)
+}
+
+// ClearHooks removes every registered hook.
+func ClearHooks() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	reportHooks.Store(nil)
+	creationHooks.Store(nil)
+}
+
+// Report passes the error to every hook registered with RegisterHook, records the
+// MetricsReported event and returns the error for chaining. Call it where the error is
+// handled, so that each failure is reported once.
+//
+// Example:
+//
+//	if err := svc.Process(ctx, job); err != nil {
+//		return errors.Wrap(err, "JOB_FAILED", "Job processing failed").Report()
+//	}
+func (e *Error) Report() *Error {
+	runHooks(&reportHooks, e)
+	recordMetric(e, MetricsReported)
+	return e
+}
+
+// addHook appends h to the hook list without modifying the slice seen by readers.
+func addHook(list *atomic.Pointer[[]Hook], h Hook) {
+	if h == nil {
+		return
+	}
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	var hooks []Hook
+	if prev := list.Load(); prev != nil {
+		hooks = make([]Hook, len(*prev), len(*prev)+1)
+		copy(hooks, *prev)
+	}
+	hooks = append(hooks, h)
+	list.Store(&hooks)
+}
+
+// runHooks calls every hook of list with e.
+func runHooks(list *atomic.Pointer[[]Hook], e *Error) {
+	hooks := list.Load()
+	if hooks == nil {
+		return
+	}
+	for _, h := range *hooks {
+		h(e)
+	}
+}
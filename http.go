@@ -0,0 +1,117 @@
+// http.go: HTTP status helpers for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+var (
+	httpStatusMu     sync.RWMutex
+	httpStatusByCode = make(map[ErrorCode]int)
+)
+
+// RegisterHTTPStatus maps code to an HTTP status used by HTTPStatus for every error with
+// that code, so handlers no longer need their own code-to-status switch. Registering a
+// code again replaces its status, and a status of 0 removes the mapping.
+// It is safe for concurrent use, but is meant to be called at startup.
+//
+// Example:
+//
+//	func init() {
+//		errors.RegisterHTTPStatus(ErrCodeNotFound, http.StatusNotFound)
+//		errors.RegisterHTTPStatus(ErrCodeValidation, http.StatusUnprocessableEntity)
+//	}
+func RegisterHTTPStatus(code ErrorCode, status int) {
+	httpStatusMu.Lock()
+	defer httpStatusMu.Unlock()
+	if status == 0 {
+		delete(httpStatusByCode, code)
+		return
+	}
+	httpStatusByCode[code] = status
+}
+
+// registeredHTTPStatus returns the status registered for code with RegisterHTTPStatus,
+// falling back to the DefaultHTTPStatus of the code in DefaultRegistry.
+func registeredHTTPStatus(code ErrorCode) (int, bool) {
+	httpStatusMu.RLock()
+	status, ok := httpStatusByCode[code]
+	httpStatusMu.RUnlock()
+	if ok {
+		return status, true
+	}
+	if meta, ok := DefaultRegistry.Lookup(code); ok && meta.DefaultHTTPStatus != 0 {
+		return meta.DefaultHTTPStatus, true
+	}
+	return 0, false
+}
+
+// WithHTTPStatus sets the HTTP status code to use when the error is returned by a REST
+// handler and returns the error for chaining.
+//
+// Example:
+//
+//	return New("USER_NOT_FOUND", "User does not exist").WithHTTPStatus(http.StatusNotFound)
+func (e *Error) WithHTTPStatus(code int) *Error {
+	e.HTTPStatusCode = code
+	return e
+}
+
+// HTTPStatus returns the HTTP status code for err. It returns the first non-zero status set
+// with WithHTTPStatus in the chain, then the status registered for the code of the first
+// *Error in the chain that has one (see RegisterHTTPStatus and ErrorMeta.DefaultHTTPStatus).
+// Otherwise the status is derived from the outermost *Error: 400 if it has a Field set,
+// or SeverityToHTTPStatus of its severity.
+// Errors that are not *Error map to 500, and a nil error to 0.
+//
+// Example:
+//
+//	if apiErr, ok := err.(*errors.Error); ok {
+//		http.Error(w, apiErr.UserMessage(), errors.HTTPStatus(apiErr))
+//	}
+func HTTPStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	for cur := range unwrapChain(err) {
+		if e, ok := cur.(*Error); ok && e.HTTPStatusCode != 0 {
+			return e.HTTPStatusCode
+		}
+	}
+	for cur := range unwrapChain(err) {
+		if e, ok := cur.(*Error); ok {
+			if status, ok := registeredHTTPStatus(e.Code); ok {
+				return status
+			}
+		}
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		return http.StatusInternalServerError
+	}
+	if e.Field != "" {
+		return http.StatusBadRequest
+	}
+	return SeverityToHTTPStatus(e.Severity)
+}
+
+// SeverityToHTTPStatus maps a severity level to a default HTTP status:
+// critical and error map to 500, warning to 400 and info to 200.
+// Unknown severities map to 500.
+func SeverityToHTTPStatus(severity Severity) int {
+	switch severity {
+	case SeverityWarning:
+		return http.StatusBadRequest
+	case SeverityInfo:
+		return http.StatusOK
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,83 @@
+// i18n.go: Localized user messages for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"sync"
+)
+
+// Translator renders localized user messages from a message key and its arguments.
+// An empty lang selects the translator's default language. The boolean is false if the
+// key has no translation, in which case the error falls back to its UserMsg and Message.
+type Translator interface {
+	Translate(lang, key string, args ...interface{}) (string, bool)
+}
+
+// TranslatorFunc adapts an ordinary function to the Translator interface.
+type TranslatorFunc func(lang, key string, args ...interface{}) (string, bool)
+
+// Translate calls f(lang, key, args...).
+func (f TranslatorFunc) Translate(lang, key string, args ...interface{}) (string, bool) {
+	return f(lang, key, args...)
+}
+
+var (
+	translatorMu sync.RWMutex
+	translator   Translator
+)
+
+// SetTranslator installs the translator used to render message keys. Pass nil to remove it.
+//
+// Example:
+//
+//	errors.SetTranslator(errors.TranslatorFunc(func(lang, key string, args ...interface{}) (string, bool) {
+//		return catalog.Render(lang, key, args...)
+//	}))
+func SetTranslator(t Translator) {
+	translatorMu.Lock()
+	defer translatorMu.Unlock()
+	translator = t
+}
+
+// WithUserMessageKey sets a message key and its parameters, rendered by the installed
+// Translator in the language of the user, and returns the error for chaining.
+// A message set with WithUserMessage acts as the fallback when the key has no translation.
+//
+// Example:
+//
+//	err := New("CARD_DECLINED", "Issuer declined the charge").
+//		WithUserMessageKey("checkout.card_declined", last4)
+func (e *Error) WithUserMessageKey(key string, args ...interface{}) *Error {
+	e.UserMsgKey = key
+	e.UserMsgArgs = args
+	return e
+}
+
+// LocalizedUserMessage returns the user message in lang: the translation of the message key
+// if one is set and the installed Translator knows it, otherwise UserMsg, otherwise Message.
+// An empty lang selects the translator's default language.
+//
+// Example:
+//
+//	lang := r.Header.Get("Accept-Language")
+//	http.Error(w, apiErr.LocalizedUserMessage(lang), errors.HTTPStatus(apiErr))
+func (e *Error) LocalizedUserMessage(lang string) string {
+	if e.UserMsgKey != "" {
+		translatorMu.RLock()
+		t := translator
+		translatorMu.RUnlock()
+		if t != nil {
+			if msg, ok := t.Translate(lang, e.UserMsgKey, e.UserMsgArgs...); ok {
+				return e.withErrorIDReference(msg)
+			}
+		}
+	}
+	if e.UserMsg != "" {
+		return e.withErrorIDReference(e.UserMsg)
+	}
+	return e.withErrorIDReference(e.Message)
+}
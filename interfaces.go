@@ -6,6 +6,8 @@
 
 package errors
 
+import "time"
+
 // ErrorCoder allows extracting an error code from an error.
 // This interface enables type-safe error code checking without type assertions.
 type ErrorCoder interface {
@@ -18,6 +20,19 @@ type Retryable interface {
 	IsRetryable() bool
 }
 
+// RetryScheduler indicates how long to wait before retrying an operation.
+// This interface complements Retryable for errors carrying a retry delay, such as rate limits.
+type RetryScheduler interface {
+	RetryDelay() time.Duration
+}
+
+// RetryAdvisor provides structured retry guidance, such as the maximum number of attempts
+// and backoff parameters, so retry frameworks do not have to guess from a boolean.
+// The second return value is false if the error carries no retry policy.
+type RetryAdvisor interface {
+	RetryAdvice() (RetryPolicy, bool)
+}
+
 // UserMessager allows extracting a user-friendly message from an error.
 // This interface enables displaying safe, non-technical messages to end users.
 type UserMessager interface {
@@ -0,0 +1,64 @@
+// invariant.go: Invariant checks for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"errors"
+)
+
+// ErrCodeInvariant is the code used by Must when the failing error is not an *Error.
+const ErrCodeInvariant ErrorCode = "INVARIANT_VIOLATION"
+
+// Must returns v if err is nil and panics otherwise. Instead of panicking with the bare
+// error, it panics with a critical *Error wrapping err, which keeps the code of the first
+// *Error in the chain (ErrCodeInvariant if there is none) and records the caller of Must
+// in Source. It is meant for initialization code, where a failure is a programming or
+// deployment error; Recover and NewFromPanic keep the structured error as the cause.
+//
+// Example:
+//
+//	var tmpl = errors.Must(template.ParseFS(files, "*.tmpl"))
+func Must[T any](v T, err error) T {
+	if err != nil {
+		message := err.Error()
+		var e *Error
+		if errors.As(err, &e) {
+			message = e.Message
+		}
+		panic(wrapWithOptions(err, WrapOptions{
+			Code:         ErrCodeInvariant,
+			Message:      message,
+			PreserveCode: true,
+			Severity:     SeverityCritical,
+		}, 1, WithCaller()))
+	}
+	return v
+}
+
+// Ensure returns nil if cond holds, and otherwise an error with code and message that
+// records the caller of Ensure in Source, like NewWithCaller.
+//
+// Example:
+//
+//	if err := errors.Ensure(cfg.Workers > 0, "INVALID_CONFIG", "workers must be positive"); err != nil {
+//		return err
+//	}
+func Ensure(cond bool, code ErrorCode, message string) error {
+	if cond {
+		return nil
+	}
+	e := &Error{
+		Code:       checkCode(code),
+		Message:    message,
+		Timestamp:  now(),
+		Severity:   SeverityError,
+		Context:    make(map[string]interface{}),
+		withCaller: true,
+	}
+	e.finish(nil, false, 1)
+	return e
+}
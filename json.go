@@ -7,23 +7,368 @@
 package errors
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
 )
 
+// StackFormat selects how MarshalJSON serializes the stack trace.
+type StackFormat int32
+
+const (
+	// StackAsString serializes the stack as the text of Stacktrace.String (default).
+	StackAsString StackFormat = iota
+	// StackAsFrames serializes the stack as an array of {"function","file","line"} objects,
+	// so that log pipelines can index individual frames.
+	StackAsFrames
+)
+
+var globalStackFormat atomic.Int32
+
+// DefaultMaxCauseDepth is the number of nested causes serialized by MarshalJSON unless
+// changed with SetMaxCauseDepth.
+const DefaultMaxCauseDepth = 32
+
+// maxCauseDepth holds the value set with SetMaxCauseDepth; 0 selects DefaultMaxCauseDepth.
+var maxCauseDepth atomic.Int32
+
+// SetStackFormat sets the stack layout used by MarshalJSON for errors that do not override
+// it with WithStackFormat.
+//
+// Example:
+//
+//	errors.SetStackFormat(errors.StackAsFrames)
+func SetStackFormat(format StackFormat) {
+	globalStackFormat.Store(int32(format))
+}
+
+// GetStackFormat returns the stack layout set with SetStackFormat.
+func GetStackFormat() StackFormat {
+	return StackFormat(globalStackFormat.Load())
+}
+
+// WithStackFormat sets the stack layout used when this error is serialized to JSON,
+// overriding the package-level setting, and returns the error for chaining.
+func (e *Error) WithStackFormat(format StackFormat) *Error {
+	e.stackFormat = format
+	e.hasStackFormat = true
+	return e
+}
+
+// SetMaxCauseDepth sets how many levels of causes MarshalJSON nests as objects. Deeper
+// causes are serialized as the string returned by their Error method, which also bounds
+// the output for pathologically long or cyclic chains. A depth of 0 or less restores
+// DefaultMaxCauseDepth.
+func SetMaxCauseDepth(depth int) {
+	if depth < 0 {
+		depth = 0
+	}
+	maxCauseDepth.Store(int32(depth))
+}
+
+// GetMaxCauseDepth returns the depth set with SetMaxCauseDepth.
+func GetMaxCauseDepth() int {
+	if depth := maxCauseDepth.Load(); depth > 0 {
+		return int(depth)
+	}
+	return DefaultMaxCauseDepth
+}
+
+// jsonStackFormat returns the stack layout used when marshaling e.
+func (e *Error) jsonStackFormat() StackFormat {
+	if e.hasStackFormat {
+		return e.stackFormat
+	}
+	return GetStackFormat()
+}
+
+// jsonCause is the JSON shape of an error that is not an *Error but wraps one, and of the
+// errors written by the package-level EncodeJSON.
+type jsonCause struct {
+	Message string      `json:"message"`
+	Cause   interface{} `json:"cause,omitempty"`
+}
+
+// decodedCause is a plain cause decoded from JSON that wraps a nested cause, so that
+// errors.As still finds the *Error below it.
+type decodedCause struct {
+	message string
+	cause   error
+}
+
+func (c *decodedCause) Error() string { return c.message }
+func (c *decodedCause) Unwrap() error { return c.cause }
+
+// errorAlias has the fields of Error without its methods, so that encoding it does not
+// recurse into MarshalJSON.
+type errorAlias Error
+
+// jsonError is the JSON representation of an Error: the stack trace is rendered as text
+// or as frames, and the cause is nested as described in MarshalJSON.
+type jsonError struct {
+	*errorAlias
+	UserMsg     string      `json:"user_msg,omitempty"`
+	Cause       interface{} `json:"cause,omitempty"`
+	Stack       interface{} `json:"stack,omitempty"`
+	Fingerprint string      `json:"fingerprint,omitempty"`
+}
+
+// jsonView returns the JSON representation of e, which is nested depth causes deep.
+func (e *Error) jsonView(depth int) *jsonError {
+	view := &jsonError{
+		errorAlias: (*errorAlias)(e.redactedView()),
+		UserMsg:    e.UserMsg,
+		Cause:      marshalCause(e.Cause, depth+1),
+	}
+	if e.UserMsgKey != "" {
+		view.UserMsg = e.UserMessage()
+	}
+	if e.jsonStackFormat() == StackAsFrames {
+		if frames := e.StackFrames(); len(frames) > 0 {
+			view.Stack = frames
+		}
+	} else if stack := e.stackText(); stack != "" {
+		view.Stack = stack
+	}
+	if fingerprintInJSON.Load() {
+		view.Fingerprint = e.Fingerprint()
+	}
+	return view
+}
+
 // MarshalJSON implements custom JSON marshaling for Error.
-// It converts the stack trace to a string representation for JSON serialization.
+// It converts the stack trace to a string, or to an array of frames when StackAsFrames is
+// selected with SetStackFormat or WithStackFormat. When a message key is set with
+// WithUserMessageKey, user_msg holds the message rendered by UserMessage next to the key
+// and its arguments. Sensitive values are redacted, see RedactKeys. The Fingerprint is
+// included when enabled with SetFingerprintInJSON.
+// Causes are serialized recursively: an *Error is nested as a structured object, a plain
+// error wrapping an *Error as {"message":"...","cause":{...}}, and any other error as the
+// string returned by its Error method. Causes deeper than GetMaxCauseDepth are serialized
+// as strings. Field names and an envelope can be configured with SetJSONOptions.
 func (e *Error) MarshalJSON() ([]byte, error) {
-	type Alias Error
-	return json.Marshal(&struct {
-		*Alias
-		Stack string `json:"stack,omitempty"`
+	return jsonOptions.Load().marshalDocument(e.jsonView(0))
+}
+
+// EncodeJSON writes the JSON representation of the error to w, followed by a newline.
+// It produces the same document as MarshalJSON but streams it through a json.Encoder,
+// avoiding the intermediate buffers of json.Marshal in HTTP handlers.
+//
+// Example:
+//
+//	w.Header().Set("Content-Type", "application/json")
+//	w.WriteHeader(errors.HTTPStatus(err))
+//	_ = err.EncodeJSON(w)
+func (e *Error) EncodeJSON(w io.Writer) error {
+	opts := jsonOptions.Load()
+	if opts == nil {
+		return json.NewEncoder(w).Encode(e.jsonView(0))
+	}
+	data, err := opts.marshalDocument(e.jsonView(0))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// EncodeJSON writes err to w as JSON. An *Error is encoded like (*Error).EncodeJSON, any
+// other error is encoded as a {"message":"..."} envelope, and a nil error as null.
+func EncodeJSON(w io.Writer, err error) error {
+	switch e := err.(type) {
+	case nil:
+		return json.NewEncoder(w).Encode(nil)
+	case *Error:
+		return e.EncodeJSON(w)
+	default:
+		return json.NewEncoder(w).Encode(jsonCause{Message: err.Error()})
+	}
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Error, so that errors received from
+// other services can be reconstructed. A nested cause with a "code" field is decoded as an
+// *Error, a string cause as a plain error, and any other object as a plain error carrying
+// its "message" and wrapping its own "cause", if any.
+// Program counters cannot be rebuilt from text, so the stack is kept as text, written back
+// unchanged by MarshalJSON and available as parsed frames through StackFrames; the Stack
+// field itself stays nil. A stack serialized as frames is accepted too, and keeps that
+// format when the error is marshaled again. A decoded fingerprint is kept as the error's
+// Fingerprint, so that groups stay stable across services. Documents in the layout set with
+// SetJSONOptions are converted back first.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	data, err := jsonOptions.Load().unmarshalDocument(data)
+	if err != nil {
+		return err
+	}
+	return e.unmarshalJSON(data)
+}
+
+// unmarshalJSON decodes a document in the default layout.
+func (e *Error) unmarshalJSON(data []byte) error {
+	aux := &struct {
+		*errorAlias
+		Cause       json.RawMessage `json:"cause,omitempty"`
+		Stack       json.RawMessage `json:"stack,omitempty"`
+		Fingerprint string          `json:"fingerprint,omitempty"`
 	}{
-		Alias: (*Alias)(e),
-		Stack: func() string {
-			if e.Stack != nil {
-				return e.Stack.String()
+		errorAlias: (*errorAlias)(e),
+	}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	e.Stack = nil
+	e.rawStack = ""
+	e.fingerprint = aux.Fingerprint
+	if len(aux.Stack) > 0 && aux.Stack[0] == '[' {
+		var frames []StackFrame
+		if err := json.Unmarshal(aux.Stack, &frames); err != nil {
+			return err
+		}
+		e.rawStack = formatFrames(frames, false)
+		e.WithStackFormat(StackAsFrames)
+	} else if len(aux.Stack) > 0 && string(aux.Stack) != "null" {
+		if err := json.Unmarshal(aux.Stack, &e.rawStack); err != nil {
+			return err
+		}
+	}
+
+	cause, err := unmarshalCause(aux.Cause, 1)
+	if err != nil {
+		return err
+	}
+	e.Cause = cause
+	return nil
+}
+
+// marshalCause returns the JSON representation of a cause nested depth levels deep.
+func marshalCause(cause error, depth int) interface{} {
+	if cause == nil {
+		return nil
+	}
+	if depth > GetMaxCauseDepth() {
+		return cause.Error()
+	}
+	if ce, ok := cause.(*Error); ok {
+		return ce.jsonView(depth)
+	}
+	var structured *Error
+	if inner := errors.Unwrap(cause); inner != nil && errors.As(inner, &structured) {
+		return jsonCause{Message: cause.Error(), Cause: marshalCause(inner, depth+1)}
+	}
+	return cause.Error()
+}
+
+// unmarshalCause decodes a cause written by marshalCause at the given depth. The cause
+// tree is read in a single pass, and causes deeper than GetMaxCauseDepth are kept as plain
+// errors, the way marshalCause truncates them, so that a hostile document cannot make the
+// decoding cost grow with the square of its nesting.
+func unmarshalCause(raw json.RawMessage, depth int) (error, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	return decodeCause(json.NewDecoder(bytes.NewReader(raw)), depth)
+}
+
+// decodeCause reads the next cause from dec. Each level only buffers its own fields, and
+// its nested cause is decoded from the stream.
+func decodeCause(dec *json.Decoder, depth int) (error, error) {
+	if depth > GetMaxCauseDepth() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		return truncatedCause(raw)
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return errors.New(tok), nil
+	case json.Delim:
+		if tok != '{' {
+			return nil, fmt.Errorf("invalid cause: unexpected %v", tok)
+		}
+	default:
+		return nil, fmt.Errorf("invalid cause: unexpected %v", tok)
+	}
+
+	fields := make(map[string]json.RawMessage)
+	var cause error
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		if key == "cause" {
+			if cause, err = decodeCause(dec, depth+1); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields[key] = value
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	if code, ok := fields["code"]; !ok || string(code) == "null" {
+		var message string
+		if raw, ok := fields["message"]; ok {
+			if err := json.Unmarshal(raw, &message); err != nil {
+				return nil, err
 			}
-			return ""
-		}(),
-	})
+		}
+		if cause == nil {
+			return errors.New(message), nil
+		}
+		return &decodedCause{message: message, cause: cause}, nil
+	}
+	object, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	e := &Error{}
+	if err := e.unmarshalJSON(object); err != nil {
+		return nil, err
+	}
+	e.Cause = cause
+	return e, nil
+}
+
+// truncatedCause returns a cause beyond GetMaxCauseDepth as a plain error: the text
+// written by marshalCause, or the headline of an object, whose own causes are dropped.
+func truncatedCause(raw json.RawMessage) (error, error) {
+	if string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] == '"' {
+		var message string
+		if err := json.Unmarshal(raw, &message); err != nil {
+			return nil, err
+		}
+		return errors.New(message), nil
+	}
+	var probe struct {
+		Code    *string `json:"code"`
+		Message string  `json:"message"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, err
+	}
+	if probe.Code == nil {
+		return errors.New(probe.Message), nil
+	}
+	return fmt.Errorf("[%s]: %s", *probe.Code, probe.Message), nil
 }
@@ -0,0 +1,182 @@
+// jsonconfig.go: Configurable JSON layout for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// JSONOptions customizes the documents produced by MarshalJSON, EncodeJSON and MarshalWith,
+// so that errors match the schema required by an API style guide or payload contract
+// without wrapping the type. UnmarshalJSON reverts the field names and envelope set with
+// SetJSONOptions, so documents round-trip; omitted members are decoded as zero values.
+type JSONOptions struct {
+	// FieldNames maps default keys, such as "code" and "message", to the names used
+	// instead. It applies to the error and to its nested causes. Two keys must not be
+	// mapped to the same name.
+	FieldNames map[string]string
+
+	// Envelope, if set, is the key of an object wrapping the document: "error" gives
+	// {"error":{"code":...}}. It applies to the outermost error only.
+	Envelope string
+
+	// OmitTimestamp, OmitSeverity, OmitRetryable and OmitStack remove the "timestamp",
+	// "severity", "retryable" and "stack" members from the error and its nested causes,
+	// for payload contracts that reject unknown fields.
+	OmitTimestamp bool
+	OmitSeverity  bool
+	OmitRetryable bool
+	OmitStack     bool
+}
+
+// jsonOptions holds the options set with SetJSONOptions; nil selects the default layout.
+var jsonOptions atomic.Pointer[JSONOptions]
+
+// SetJSONOptions sets the JSON layout of all errors. The zero JSONOptions restores the
+// default layout, which is also the fastest since the document is encoded in one pass;
+// renaming fields or adding an envelope re-encodes it.
+//
+// Example:
+//
+//	errors.SetJSONOptions(errors.JSONOptions{
+//		FieldNames: map[string]string{"code": "error_code", "message": "detail"},
+//	})
+//	// {"error_code":"USER_NOT_FOUND","detail":"User not found",...}
+func SetJSONOptions(opts JSONOptions) {
+	jsonOptions.Store(opts.normalize())
+}
+
+// GetJSONOptions returns the options set with SetJSONOptions.
+func GetJSONOptions() JSONOptions {
+	if opts := jsonOptions.Load(); opts != nil {
+		return *opts
+	}
+	return JSONOptions{}
+}
+
+// MarshalWith encodes the error like MarshalJSON, but with opts instead of the options set
+// with SetJSONOptions, for the payloads of a single contract.
+//
+// Example:
+//
+//	payload, err := apiErr.MarshalWith(errors.JSONOptions{OmitTimestamp: true, OmitStack: true})
+func (e *Error) MarshalWith(opts JSONOptions) ([]byte, error) {
+	return opts.normalize().marshalDocument(e.jsonView(0))
+}
+
+// normalize returns a private copy of o, or nil if o selects the default layout.
+func (o JSONOptions) normalize() *JSONOptions {
+	if len(o.FieldNames) == 0 && o.Envelope == "" && !o.OmitTimestamp && !o.OmitSeverity && !o.OmitRetryable && !o.OmitStack {
+		return nil
+	}
+	names := make(map[string]string, len(o.FieldNames))
+	for k, v := range o.FieldNames {
+		names[k] = v
+	}
+	o.FieldNames = names
+	return &o
+}
+
+// omitted returns the set of default keys removed by o, or nil.
+func (o *JSONOptions) omitted() map[string]bool {
+	omit := make(map[string]bool, 4)
+	if o.OmitTimestamp {
+		omit["timestamp"] = true
+	}
+	if o.OmitSeverity {
+		omit["severity"] = true
+	}
+	if o.OmitRetryable {
+		omit["retryable"] = true
+	}
+	if o.OmitStack {
+		omit["stack"] = true
+	}
+	if len(omit) == 0 {
+		return nil
+	}
+	return omit
+}
+
+// marshalDocument encodes the JSON representation view of an error with the options.
+func (o *JSONOptions) marshalDocument(view interface{}) ([]byte, error) {
+	data, err := o.marshalEntry(view)
+	if err != nil || o == nil || o.Envelope == "" {
+		return data, err
+	}
+	return json.Marshal(map[string]json.RawMessage{o.Envelope: data})
+}
+
+// marshalEntry encodes view like marshalDocument, without the envelope, for errors
+// embedded in a larger document.
+func (o *JSONOptions) marshalEntry(view interface{}) ([]byte, error) {
+	data, err := json.Marshal(view)
+	if err != nil || o == nil {
+		return data, err
+	}
+	if omit := o.omitted(); len(o.FieldNames) > 0 || omit != nil {
+		return rewriteKeys(data, o.FieldNames, omit, "cause", 0)
+	}
+	return data, nil
+}
+
+// unmarshalDocument reverts marshalDocument, returning a document in the default layout.
+// A document without the envelope is accepted as is.
+func (o *JSONOptions) unmarshalDocument(data []byte) ([]byte, error) {
+	if o == nil {
+		return data, nil
+	}
+	if o.Envelope != "" {
+		var envelope map[string]json.RawMessage
+		if json.Unmarshal(data, &envelope) == nil && len(envelope) == 1 && envelope[o.Envelope] != nil {
+			data = envelope[o.Envelope]
+		}
+	}
+	if len(o.FieldNames) == 0 {
+		return data, nil
+	}
+	reverse := make(map[string]string, len(o.FieldNames))
+	for k, v := range o.FieldNames {
+		reverse[v] = k
+	}
+	causeKey := "cause"
+	if name, ok := o.FieldNames[causeKey]; ok {
+		causeKey = name
+	}
+	return rewriteKeys(data, reverse, nil, causeKey, 0)
+}
+
+// rewriteKeys removes the omit keys from the JSON object data and renames its other keys,
+// and does the same to the object nested under causeKey, recursively down to the causes
+// kept by GetMaxCauseDepth. Values that are not objects are returned unchanged.
+func rewriteKeys(data []byte, names map[string]string, omit map[string]bool, causeKey string, depth int) ([]byte, error) {
+	if len(data) == 0 || data[0] != '{' {
+		return data, nil
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	renamed := make(map[string]json.RawMessage, len(doc))
+	for k, v := range doc {
+		if omit[k] {
+			continue
+		}
+		if k == causeKey && depth <= GetMaxCauseDepth() {
+			var err error
+			if v, err = rewriteKeys(v, names, omit, causeKey, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		if name, ok := names[k]; ok {
+			k = name
+		}
+		renamed[k] = v
+	}
+	return json.Marshal(renamed)
+}
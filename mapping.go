@@ -0,0 +1,250 @@
+// mapping.go: Generic map conversion for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCodeInvalidErrorMap is the code of the error returned by FromMap for a malformed map.
+const ErrCodeInvalidErrorMap ErrorCode = "INVALID_ERROR_MAP"
+
+// ToMap returns the error as a map with the keys of its JSON representation, for systems
+// that carry errors as generic maps such as message queues or dynamic configuration.
+// Values are plain strings, booleans, numbers and maps: the timestamp is an RFC 3339 string,
+// retry_after a number of nanoseconds, and a cause is nested as a map when it is an *Error
+// and is its Error() string otherwise. Zero-valued optional keys are left out and sensitive
+// values are redacted, as in MarshalJSON. FromMap reverses the conversion.
+//
+// Example:
+//
+//	msg := queue.Message{Headers: map[string]interface{}{"error": err.ToMap()}}
+func (e *Error) ToMap() map[string]interface{} {
+	v := e.redactedView()
+	m := map[string]interface{}{
+		"code":      string(v.Code),
+		"message":   v.Message,
+		"severity":  string(v.Severity),
+		"timestamp": v.Timestamp.Format(time.RFC3339Nano),
+	}
+	setString := func(key, value string) {
+		if value != "" {
+			m[key] = value
+		}
+	}
+	setString("category", v.Category)
+	setString("op", v.Op)
+	setString("field", v.Field)
+	setString("value", v.Value)
+	if v.UserMsgKey != "" {
+		setString("user_msg", v.UserMessage())
+	} else {
+		setString("user_msg", v.UserMsg)
+	}
+	setString("trace_id", v.TraceID)
+	setString("span_id", v.SpanID)
+	setString("error_id", v.ErrorID)
+	setString("callsite", v.Callsite)
+	setString("stack", v.stackText())
+	if len(v.Context) > 0 {
+		context := make(map[string]interface{}, len(v.Context))
+		for k, val := range v.Context {
+			context[k] = val
+		}
+		m["context"] = context
+	}
+	if v.Retryable {
+		m["retryable"] = true
+	}
+	if v.RetryAfter != 0 {
+		m["retry_after"] = int64(v.RetryAfter)
+	}
+	if v.HTTPStatusCode != 0 {
+		m["http_status"] = v.HTTPStatusCode
+	}
+	if ce, ok := v.Cause.(*Error); ok {
+		m["cause"] = ce.ToMap()
+	} else if v.Cause != nil {
+		m["cause"] = v.Cause.Error()
+	}
+	return m
+}
+
+// FromMap rebuilds an error from a map produced by ToMap or decoded from JSON. The "code"
+// key is required. Numbers may have any integer or floating-point type; the timestamp may
+// be a time.Time, an RFC 3339 string or Unix milliseconds, and retry_after a number of
+// nanoseconds or a duration string such as "1.5s". Unknown keys are ignored. Like
+// UnmarshalJSON, it does not apply the code convention, and the stack is kept as text.
+// FromMap returns an error with code ErrCodeInvalidErrorMap if a key has the wrong type.
+//
+// Example:
+//
+//	if raw, ok := msg.Headers["error"].(map[string]interface{}); ok {
+//		if e, err := errors.FromMap(raw); err == nil {
+//			log.Error("upstream failure", "error", e)
+//		}
+//	}
+func FromMap(m map[string]interface{}) (*Error, error) {
+	code, ok := m["code"].(string)
+	if !ok || code == "" {
+		return nil, invalidErrorMap("code", m["code"])
+	}
+	e := &Error{
+		Code:     ErrorCode(code),
+		Severity: SeverityError,
+		Context:  make(map[string]interface{}),
+	}
+
+	stringFields := map[string]*string{
+		"message":  &e.Message,
+		"category": &e.Category,
+		"op":       &e.Op,
+		"field":    &e.Field,
+		"value":    &e.Value,
+		"user_msg": &e.UserMsg,
+		"trace_id": &e.TraceID,
+		"span_id":  &e.SpanID,
+		"error_id": &e.ErrorID,
+		"callsite": &e.Callsite,
+		"stack":    &e.rawStack,
+	}
+	for key, dst := range stringFields {
+		if raw, present := m[key]; present {
+			s, ok := raw.(string)
+			if !ok {
+				return nil, invalidErrorMap(key, raw)
+			}
+			*dst = s
+		}
+	}
+	if raw, present := m["severity"]; present {
+		s, ok := raw.(string)
+		if !ok {
+			return nil, invalidErrorMap("severity", raw)
+		}
+		if s != "" {
+			e.Severity = Severity(s)
+		}
+	}
+	if raw, present := m["context"]; present {
+		context, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, invalidErrorMap("context", raw)
+		}
+		for k, v := range context {
+			e.Context[k] = v
+		}
+	}
+	if raw, present := m["retryable"]; present {
+		if e.Retryable, ok = raw.(bool); !ok {
+			return nil, invalidErrorMap("retryable", raw)
+		}
+	}
+	if raw, present := m["http_status"]; present {
+		status, ok := mapInt64(raw)
+		if !ok {
+			return nil, invalidErrorMap("http_status", raw)
+		}
+		e.HTTPStatusCode = int(status)
+	}
+	if raw, present := m["retry_after"]; present {
+		d, ok := mapDuration(raw)
+		if !ok {
+			return nil, invalidErrorMap("retry_after", raw)
+		}
+		e.RetryAfter = d
+	}
+	if raw, present := m["timestamp"]; present {
+		ts, ok := mapTime(raw)
+		if !ok {
+			return nil, invalidErrorMap("timestamp", raw)
+		}
+		e.Timestamp = ts
+	}
+	if raw, present := m["cause"]; present && raw != nil {
+		cause, err := mapCause(raw)
+		if err != nil {
+			return nil, err
+		}
+		e.Cause = cause
+	}
+	return e, nil
+}
+
+// mapCause decodes the "cause" value of FromMap.
+func mapCause(raw interface{}) (error, error) {
+	switch c := raw.(type) {
+	case string:
+		return errors.New(c), nil
+	case map[string]interface{}:
+		if _, hasCode := c["code"]; hasCode {
+			return FromMap(c)
+		}
+		message, _ := c["message"].(string)
+		return errors.New(message), nil
+	}
+	return nil, invalidErrorMap("cause", raw)
+}
+
+// mapInt64 converts the numeric types produced by decoders to int64.
+func mapInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	case float32:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// mapDuration converts a number of nanoseconds or a duration string.
+func mapDuration(v interface{}) (time.Duration, bool) {
+	switch d := v.(type) {
+	case time.Duration:
+		return d, true
+	case string:
+		parsed, err := time.ParseDuration(d)
+		return parsed, err == nil
+	}
+	n, ok := mapInt64(v)
+	return time.Duration(n), ok
+}
+
+// mapTime converts a time.Time, an RFC 3339 string or Unix milliseconds.
+func mapTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		return parsed, err == nil
+	}
+	ms, ok := mapInt64(v)
+	return time.UnixMilli(ms), ok
+}
+
+// invalidErrorMap returns the error reported by FromMap for a key with an unexpected value.
+func invalidErrorMap(key string, value interface{}) *Error {
+	return New(ErrCodeInvalidErrorMap, fmt.Sprintf("invalid %q value in error map: %T", key, value)).
+		WithContext("key", key)
+}
@@ -0,0 +1,158 @@
+// matcher.go: Precise error matching for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MatchMode selects the attributes compared by IsMode and by a Matcher. Modes combine with |.
+type MatchMode uint8
+
+const (
+	// MatchByCode compares the Code, which is what (*Error).Is does.
+	MatchByCode MatchMode = 1 << iota
+	// MatchByField compares the Field.
+	MatchByField
+	// MatchByMessage compares the Message.
+	MatchByMessage
+	// MatchByContext requires every context entry of the target to be present with an equal
+	// value, compared with reflect.DeepEqual; extra entries are allowed.
+	MatchByContext
+)
+
+// Matcher is an errors.Is target that matches an *Error on more than its code, so that
+// two unrelated validation errors sharing a code are told apart. A Matcher is immutable:
+// its With methods return a new one.
+//
+// Example:
+//
+//	if errors.Is(err, errors.MatchCodeAndField(ErrCodeValidation, "email")) {
+//		// the email field is invalid
+//	}
+type Matcher struct {
+	mode    MatchMode
+	code    ErrorCode
+	field   string
+	message string
+	context map[string]interface{}
+}
+
+// MatchCode returns a Matcher for errors with code.
+func MatchCode(code ErrorCode) *Matcher {
+	return &Matcher{mode: MatchByCode, code: code}
+}
+
+// MatchCodeAndField returns a Matcher for errors with code and field.
+func MatchCodeAndField(code ErrorCode, field string) *Matcher {
+	return &Matcher{mode: MatchByCode | MatchByField, code: code, field: field}
+}
+
+// WithMessage returns a copy of the Matcher that also requires message.
+func (m *Matcher) WithMessage(message string) *Matcher {
+	c := m.clone()
+	c.mode |= MatchByMessage
+	c.message = message
+	return c
+}
+
+// WithContext returns a copy of the Matcher that also requires the context entry key=value.
+func (m *Matcher) WithContext(key string, value interface{}) *Matcher {
+	c := m.clone()
+	c.mode |= MatchByContext
+	c.context[key] = value
+	return c
+}
+
+// clone returns a copy of m with its own context map.
+func (m *Matcher) clone() *Matcher {
+	c := *m
+	c.context = make(map[string]interface{}, len(m.context)+1)
+	for k, v := range m.context {
+		c.context[k] = v
+	}
+	return &c
+}
+
+// Match reports whether any error in the chain of err matches, like errors.Is(err, m).
+func (m *Matcher) Match(err error) bool {
+	return errors.Is(err, m)
+}
+
+// Error describes the Matcher, e.g. "match code=VALIDATION_ERROR field=email".
+func (m *Matcher) Error() string {
+	var b strings.Builder
+	b.WriteString("match")
+	if m.mode&MatchByCode != 0 {
+		b.WriteString(" code=" + string(m.code))
+	}
+	if m.mode&MatchByField != 0 {
+		b.WriteString(" field=" + m.field)
+	}
+	if m.mode&MatchByMessage != 0 {
+		b.WriteString(fmt.Sprintf(" message=%q", m.message))
+	}
+	keys := make([]string, 0, len(m.context))
+	for k := range m.context {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(fmt.Sprintf(" %s=%v", k, m.context[k]))
+	}
+	return b.String()
+}
+
+// matches reports whether e has every attribute selected by the mode of m.
+func (m *Matcher) matches(e *Error) bool {
+	if m.mode&MatchByCode != 0 && e.Code != m.code {
+		return false
+	}
+	if m.mode&MatchByField != 0 && e.Field != m.field {
+		return false
+	}
+	if m.mode&MatchByMessage != 0 && e.Message != m.message {
+		return false
+	}
+	if m.mode&MatchByContext != 0 {
+		for k, want := range m.context {
+			got, ok := e.Context[k]
+			if !ok || !reflect.DeepEqual(got, want) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// IsMode reports whether any *Error in the chain of err matches target on the attributes
+// selected by mode. It makes the comparison of errors.Is configurable per call, e.g. in
+// tests asserting on a specific validation failure.
+//
+// Example:
+//
+//	want := errors.NewWithField(ErrCodeValidation, "Email is required", "email", "")
+//	if !errors.IsMode(err, want, errors.MatchByCode|errors.MatchByField|errors.MatchByMessage) {
+//		t.Errorf("unexpected error: %v", err)
+//	}
+func IsMode(err error, target *Error, mode MatchMode) bool {
+	if target == nil {
+		return false
+	}
+	m := &Matcher{
+		mode:    mode,
+		code:    target.Code,
+		field:   target.Field,
+		message: target.Message,
+		context: target.Context,
+	}
+	return m.Match(err)
+}
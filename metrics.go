@@ -0,0 +1,65 @@
+// metrics.go: Error metrics integration for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"sync/atomic"
+)
+
+// MetricsEvent identifies when a MetricsSink is notified.
+type MetricsEvent string
+
+const (
+	// MetricsCreated is recorded for every error built by New, NewWithField, NewWithContext
+	// and the Wrap family.
+	MetricsCreated MetricsEvent = "created"
+	// MetricsReported is recorded by (*Error).Report.
+	MetricsReported MetricsEvent = "reported"
+)
+
+// MetricsSink counts errors by code and severity, typically backed by a Prometheus
+// counter vector. Implementations must be safe for concurrent use and fast, since
+// IncError runs on the error path.
+//
+// Example:
+//
+//	type promSink struct{ c *prometheus.CounterVec }
+//
+//	func (s promSink) IncError(code errors.ErrorCode, severity errors.Severity, event errors.MetricsEvent) {
+//		s.c.WithLabelValues(string(code), string(severity), string(event)).Inc()
+//	}
+type MetricsSink interface {
+	IncError(code ErrorCode, severity Severity, event MetricsEvent)
+}
+
+// metricsSinkHolder lets an interface value be stored in an atomic.Pointer.
+type metricsSinkHolder struct {
+	sink MetricsSink
+}
+
+var metricsSink atomic.Pointer[metricsSinkHolder]
+
+// SetMetricsSink installs sink to be notified when errors are created or reported.
+// Pass nil to remove it. Without a sink, constructors pay a single atomic load.
+//
+// Example:
+//
+//	errors.SetMetricsSink(promSink{c: errorsTotal})
+func SetMetricsSink(sink MetricsSink) {
+	if sink == nil {
+		metricsSink.Store(nil)
+		return
+	}
+	metricsSink.Store(&metricsSinkHolder{sink: sink})
+}
+
+// recordMetric notifies the installed MetricsSink, if any.
+func recordMetric(e *Error, event MetricsEvent) {
+	if h := metricsSink.Load(); h != nil {
+		h.sink.IncError(e.Code, e.Severity, event)
+	}
+}
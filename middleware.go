@@ -0,0 +1,144 @@
+// middleware.go: HTTP middleware and error responses for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// RequestIDHeader is the request header whose value is reported as request_id in the
+// error envelope written by WriteHTTPError.
+var RequestIDHeader = "X-Request-ID"
+
+// HTTPErrorResponse is the JSON envelope written by WriteHTTPError:
+//
+//	{"error":{"code":"USER_NOT_FOUND","message":"User not found","request_id":"abc","status":404}}
+type HTTPErrorResponse struct {
+	Error HTTPErrorBody `json:"error"`
+}
+
+// HTTPErrorBody is the content of the "error" member of HTTPErrorResponse.
+type HTTPErrorBody struct {
+	Code      ErrorCode    `json:"code"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"request_id,omitempty"`
+	Status    int          `json:"status"`
+	Retryable bool         `json:"retryable,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
+}
+
+// HandlerFunc is an http.HandlerFunc that returns an error instead of writing it.
+// Use Handler to turn it into an http.Handler.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// Handler adapts h to an http.Handler. A non-nil error returned by h is enriched with the
+// request metadata of the registered context extractors (see WithRequestContext) and written
+// with WriteHTTPError, and panics are recovered as in Middleware.
+//
+// Example:
+//
+//	mux.Handle("/users/", errors.Handler(func(w http.ResponseWriter, r *http.Request) error {
+//		user, err := store.Find(r.URL.Path)
+//		if err != nil {
+//			return errors.Wrap(err, ErrCodeNotFound, "User lookup failed").
+//				WithUserMessage("User not found").
+//				WithHTTPStatus(http.StatusNotFound)
+//		}
+//		return json.NewEncoder(w).Encode(user)
+//	}))
+func Handler(h HandlerFunc) http.Handler {
+	return Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			WriteHTTPError(w, r, WithRequestContext(r.Context(), err))
+		}
+	}))
+}
+
+// Middleware wraps next so that a panic in a handler is recovered into an *Error with code
+// ErrCodePanic and written with WriteHTTPError instead of closing the connection.
+// http.ErrAbortHandler is re-panicked, since it is the standard way to abort a response.
+//
+// Example:
+//
+//	http.ListenAndServe(":8080", errors.Middleware(mux))
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			WriteHTTPError(w, r, NewFromPanic(recovered, ErrCodePanic))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WriteHTTPError writes err to w as an HTTPErrorResponse with the status returned by
+// HTTPStatus. The code, retryable flag and user message come from the outermost *Error
+// in the chain; the user message is localized for the first language of the request's
+// Accept-Language header. When no user message is set, server errors (5xx) report the standard
+// status text so that technical messages are not leaked to clients, while client errors
+// report the error message. Errors that are not *Error are reported as DefaultErrorCode.
+// For client errors, the FieldErrors of the *Error, such as those of Validation.Err, or
+// the field errors of a *MultiError in the chain are listed in the fields member.
+func WriteHTTPError(w http.ResponseWriter, r *http.Request, err error) {
+	status := HTTPStatus(err)
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	body := HTTPErrorBody{
+		Code:   DefaultErrorCode,
+		Status: status,
+	}
+	if r != nil {
+		body.RequestID = r.Header.Get(RequestIDHeader)
+	}
+
+	var e *Error
+	switch {
+	case errors.As(err, &e) && (e.UserMsg != "" || e.UserMsgKey != ""):
+		body.Code, body.Retryable, body.Message = e.Code, e.Retryable, e.LocalizedUserMessage(requestLanguage(r))
+	case e != nil && status < http.StatusInternalServerError:
+		body.Code, body.Retryable, body.Message = e.Code, e.Retryable, e.Message
+	case e != nil:
+		body.Code, body.Retryable, body.Message = e.Code, e.Retryable, http.StatusText(status)
+	default:
+		body.Message = http.StatusText(status)
+	}
+	var m *MultiError
+	switch {
+	case status >= http.StatusInternalServerError:
+	case e != nil && len(e.FieldErrors) > 0:
+		body.Fields = e.FieldErrors
+	case errors.As(err, &m):
+		body.Fields = m.Fields()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(HTTPErrorResponse{Error: body})
+}
+
+// requestLanguage returns the first language tag of the Accept-Language header of r,
+// or an empty string for the translator's default language.
+func requestLanguage(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	lang := r.Header.Get("Accept-Language")
+	if i := strings.IndexAny(lang, ",;"); i >= 0 {
+		lang = lang[:i]
+	}
+	return strings.TrimSpace(lang)
+}
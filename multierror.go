@@ -0,0 +1,194 @@
+// multierror.go: Error aggregation and multi-field validation errors for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// FieldError describes a single field-level failure inside a MultiError.
+type FieldError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Field   string    `json:"field,omitempty"`
+	Value   string    `json:"value,omitempty"`
+}
+
+// MultiError aggregates structured errors, typically field-level failures produced while
+// validating a form or a request body, so they can all be reported at once.
+// Use NewMultiError, Append or Combine to build one.
+type MultiError struct {
+	errs []*Error
+}
+
+// MultiErrorBuilder collects field errors without knowing the final count up front.
+type MultiErrorBuilder struct {
+	fields []FieldError
+}
+
+// NewMultiError returns an empty builder for a MultiError.
+//
+// Example:
+//
+//	b := errors.NewMultiError()
+//	if req.Email == "" {
+//		b.Add(ErrCodeValidation, "Email is required", "email", req.Email)
+//	}
+//	if len(req.Name) > 64 {
+//		b.Add(ErrCodeValidation, "Name is too long", "name", req.Name)
+//	}
+//	if err := b.Build(); err != nil {
+//		return err
+//	}
+func NewMultiError() *MultiErrorBuilder {
+	return &MultiErrorBuilder{}
+}
+
+// Add records a field error and returns the builder for chaining.
+// If code is empty or whitespace-only, DefaultErrorCode will be used instead.
+func (b *MultiErrorBuilder) Add(code ErrorCode, message, field, value string) *MultiErrorBuilder {
+	b.fields = append(b.fields, FieldError{
+		Code:    checkCode(code),
+		Message: message,
+		Field:   field,
+		Value:   value,
+	})
+	return b
+}
+
+// Build returns the collected field errors as a *MultiError, or nil if none were added.
+func (b *MultiErrorBuilder) Build() error {
+	if len(b.fields) == 0 {
+		return nil
+	}
+	errs := make([]*Error, len(b.fields))
+	for i, f := range b.fields {
+		errs[i] = NewWithField(f.Code, f.Message, f.Field, f.Value)
+	}
+	return &MultiError{errs: errs}
+}
+
+// Append adds errs to err and returns the aggregate. If err is a *MultiError its errors
+// are kept in front; nested *MultiError values are flattened, nil errors are skipped, and
+// errors that are not *Error are wrapped without a stack trace under DefaultErrorCode.
+// It returns nil if no non-nil error remains. err itself is never modified.
+//
+// Example:
+//
+//	var result error
+//	for _, item := range items {
+//		result = errors.Append(result, validate(item))
+//	}
+//	return result
+func Append(err error, errs ...error) error {
+	m := &MultiError{}
+	m.append(err)
+	for _, e := range errs {
+		m.append(e)
+	}
+	if len(m.errs) == 0 {
+		return nil
+	}
+	return m
+}
+
+// Combine aggregates errs into a single *MultiError, following the same rules as Append.
+// It returns nil if every error is nil.
+//
+// Example:
+//
+//	return errors.Combine(validateName(req), validateEmail(req))
+func Combine(errs ...error) error {
+	return Append(nil, errs...)
+}
+
+// append adds err to m, flattening nested aggregates.
+func (m *MultiError) append(err error) {
+	switch e := err.(type) {
+	case nil:
+	case *MultiError:
+		if e != nil {
+			m.errs = append(m.errs, e.errs...)
+		}
+	case *Error:
+		if e != nil {
+			m.errs = append(m.errs, e)
+		}
+	default:
+		m.errs = append(m.errs, WrapNoStack(err, DefaultErrorCode, err.Error()))
+	}
+}
+
+// Error implements the error interface with a one-line summary of every contained error,
+// e.g. "2 errors: email: Email is required; name: Name is too long".
+func (m *MultiError) Error() string {
+	var b strings.Builder
+	b.WriteString(countErrors(len(m.errs)))
+	b.WriteString(": ")
+	for i, f := range m.errs {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		if f.Field != "" {
+			b.WriteString(f.Field)
+			b.WriteString(": ")
+		}
+		b.WriteString(f.Message)
+	}
+	return b.String()
+}
+
+// Fields returns the code, message, field and value of each contained error.
+func (m *MultiError) Fields() []FieldError {
+	fields := make([]FieldError, len(m.errs))
+	for i, e := range m.errs {
+		fields[i] = FieldError{Code: e.Code, Message: e.Message, Field: e.Field, Value: e.Value}
+	}
+	return fields
+}
+
+// Errors returns a copy of the slice of contained errors.
+func (m *MultiError) Errors() []*Error {
+	errs := make([]*Error, len(m.errs))
+	copy(errs, m.errs)
+	return errs
+}
+
+// ErrorCount returns the number of contained errors.
+func (m *MultiError) ErrorCount() int {
+	return len(m.errs)
+}
+
+// Unwrap returns the contained errors, so HasCode, errors.Is and errors.As inspect each
+// of them.
+func (m *MultiError) Unwrap() []error {
+	errs := make([]error, len(m.errs))
+	for i, e := range m.errs {
+		errs[i] = e
+	}
+	return errs
+}
+
+// MarshalJSON serializes the contained errors as {"errors":[...]}, the shape used by most
+// REST validation responses. Each entry is a structured error encoded like
+// (*Error).MarshalJSON, with its severity, context, cause and retry metadata; the envelope
+// set with SetJSONOptions applies to standalone errors and is not repeated per entry.
+func (m *MultiError) MarshalJSON() ([]byte, error) {
+	opts := jsonOptions.Load()
+	entries := make([]json.RawMessage, len(m.errs))
+	for i, e := range m.errs {
+		data, err := opts.marshalEntry(e.jsonView(0))
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = data
+	}
+	return json.Marshal(struct {
+		Errors []json.RawMessage `json:"errors"`
+	}{Errors: entries})
+}
@@ -0,0 +1,102 @@
+// namespace.go: Hierarchical error codes for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"strings"
+)
+
+// CodeSeparator separates the segments of hierarchical codes such as "DB.CONNECTION.TIMEOUT".
+const CodeSeparator = "."
+
+// HasPrefix reports whether the code is prefix or lies below it in the code hierarchy:
+// "DB.CONNECTION.TIMEOUT" has the prefixes "DB" and "DB.CONNECTION", but not "DB.CONN".
+func (c ErrorCode) HasPrefix(prefix ErrorCode) bool {
+	return c == prefix || strings.HasPrefix(string(c), string(prefix)+CodeSeparator)
+}
+
+// HasCodePrefix reports whether any *Error in the chain of err, including the branches of
+// aggregates such as errors.Join, has a code equal to prefix or below it in the code
+// hierarchy (see ErrorCode.HasPrefix).
+//
+// Example:
+//
+//	if errors.HasCodePrefix(err, "DB") {
+//		metrics.DatabaseFailures.Inc()
+//	}
+func HasCodePrefix(err error, prefix ErrorCode) bool {
+	found := false
+	var w chainWalk
+	walkErrors(err, func(cur error) bool {
+		if e, ok := cur.(*Error); ok && e.Code.HasPrefix(prefix) {
+			found = true
+		}
+		return !found
+	}, &w)
+	return found
+}
+
+// CodeNamespace creates errors whose codes are prefixed with its namespace.
+// It is immutable and safe for concurrent use.
+type CodeNamespace struct {
+	prefix string
+}
+
+// Namespace returns a CodeNamespace prepending prefix and CodeSeparator to the codes of
+// the errors it creates.
+//
+// Example:
+//
+//	var dbErrors = errors.Namespace("DB")
+//
+//	err := dbErrors.New("CONNECTION.TIMEOUT", "Connection timed out") // code DB.CONNECTION.TIMEOUT
+//	errors.HasCodePrefix(err, "DB.CONNECTION")                       // true
+func Namespace(prefix string) *CodeNamespace {
+	return &CodeNamespace{prefix: prefix}
+}
+
+// Namespace returns a child namespace, e.g. Namespace("DB").Namespace("CONNECTION")
+// creates codes below "DB.CONNECTION".
+func (n *CodeNamespace) Namespace(name string) *CodeNamespace {
+	return &CodeNamespace{prefix: string(n.Code(name))}
+}
+
+// Prefix returns the namespace itself as a code, for use with HasCodePrefix.
+func (n *CodeNamespace) Prefix() ErrorCode {
+	return ErrorCode(n.prefix)
+}
+
+// Code returns name qualified by the namespace. An empty name returns Prefix.
+func (n *CodeNamespace) Code(name string) ErrorCode {
+	if name == "" {
+		return ErrorCode(n.prefix)
+	}
+	return ErrorCode(n.prefix + CodeSeparator + name)
+}
+
+// Contains reports whether err has a code in the namespace, like HasCodePrefix.
+func (n *CodeNamespace) Contains(err error) bool {
+	return HasCodePrefix(err, n.Prefix())
+}
+
+// New creates an error like New with the code name qualified by the namespace.
+func (n *CodeNamespace) New(name, message string, opts ...Option) *Error {
+	e := &Error{
+		Code:      checkCode(n.Code(name)),
+		Message:   message,
+		Timestamp: now(),
+		Severity:  SeverityError,
+		Context:   make(map[string]interface{}),
+	}
+	e.finish(opts, false, 1)
+	return e
+}
+
+// Wrap wraps err like Wrap with the code name qualified by the namespace.
+func (n *CodeNamespace) Wrap(err error, name, message string, opts ...Option) *Error {
+	return wrapWithOptions(err, WrapOptions{Code: n.Code(name), Message: message}, 1, opts...)
+}
@@ -0,0 +1,157 @@
+// navigation.go: Upward chain navigation for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"hash/maphash"
+	"runtime"
+	"sync"
+	"weak"
+)
+
+const (
+	// wrapLinkShards is the number of independently locked parts of the wrap link table.
+	wrapLinkShards = 64
+	// maxWrapLinks bounds the number of wrapped errors whose wrappers are tracked at once
+	// in each shard, for 65536 errors in total.
+	maxWrapLinks = 1024
+	// maxWrapChildren bounds the number of wrappers remembered per wrapped error.
+	maxWrapChildren = 16
+)
+
+// wrapLink holds the wrappers of a wrapped error.
+type wrapLink struct {
+	parent   weak.Pointer[Error]
+	children []weak.Pointer[Error]
+}
+
+// wrapLinkShard is one part of the wrap link table.
+type wrapLinkShard struct {
+	sync.Mutex
+	m map[weak.Pointer[Error]]*wrapLink
+}
+
+// wrapLinks records the links created by Wrap, keyed by the wrapped error. The links are
+// kept outside of the errors, so that wrapping a shared error, such as a sentinel, from
+// several goroutines does not modify it, and are weak, so that they keep neither the
+// wrapped error nor its wrappers alive. An entry is removed when its error is collected.
+// The table is sharded so that concurrent wraps of different errors rarely contend.
+var (
+	wrapLinks    [wrapLinkShards]wrapLinkShard
+	wrapLinkSeed = maphash.MakeSeed()
+)
+
+// wrapShard returns the shard holding the links of the error behind key.
+func wrapShard(key weak.Pointer[Error]) *wrapLinkShard {
+	return &wrapLinks[maphash.Comparable(wrapLinkSeed, key)%wrapLinkShards]
+}
+
+// recordWrap records wrapper as the most recent wrapper of inner. Beyond maxWrapChildren
+// wrappers, the oldest ones are forgotten. When the shard of inner already tracks
+// maxWrapLinks errors, the entries whose wrappers have all been collected are dropped; if
+// the shard is still full, the link is not recorded and Parent returns nil for inner.
+func recordWrap(inner, wrapper *Error) {
+	key := weak.Make(inner)
+	shard := wrapShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	link := shard.m[key]
+	if link == nil {
+		if len(shard.m) >= maxWrapLinks && !shard.prune() {
+			return
+		}
+		if shard.m == nil {
+			shard.m = make(map[weak.Pointer[Error]]*wrapLink)
+		}
+		link = &wrapLink{}
+		shard.m[key] = link
+		runtime.AddCleanup(inner, forgetWraps, key)
+	}
+	link.parent = weak.Make(wrapper)
+	if len(link.children) == maxWrapChildren {
+		link.children = append(link.children[:0], link.children[1:]...)
+	}
+	link.children = append(link.children, link.parent)
+}
+
+// prune drops the entries whose wrappers have all been collected and reports whether the
+// shard has room for a new entry. The caller holds the shard lock.
+func (s *wrapLinkShard) prune() bool {
+	for key, link := range s.m {
+		alive := false
+		for _, child := range link.children {
+			if child.Value() != nil {
+				alive = true
+				break
+			}
+		}
+		if !alive {
+			delete(s.m, key)
+		}
+	}
+	return len(s.m) < maxWrapLinks
+}
+
+// forgetWraps removes the links of a collected error.
+func forgetWraps(key weak.Pointer[Error]) {
+	shard := wrapShard(key)
+	shard.Lock()
+	delete(shard.m, key)
+	shard.Unlock()
+}
+
+// Parent returns the *Error that most recently wrapped e through Wrap, or nil if e has
+// not been wrapped or that wrapper has been garbage collected. Together with Unwrap it
+// allows walking the chain in both directions. The link is a weak reference kept outside
+// of e: it is not serialized to JSON, not followed by errors.Is or errors.As, and wrapping
+// the same *Error from several goroutines is safe. Links are tracked for up to 65536
+// wrapped errors at a time; beyond that, Parent may return nil for a wrapped error.
+func (e *Error) Parent() *Error {
+	key := weak.Make(e)
+	shard := wrapShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if link := shard.m[key]; link != nil {
+		return link.parent.Value()
+	}
+	return nil
+}
+
+// Children returns the *Error values that wrapped e through Wrap and are still reachable,
+// in wrapping order. Only the most recent wrappers are remembered, up to 16 per error.
+// The returned slice is a copy and can be modified freely.
+func (e *Error) Children() []*Error {
+	key := weak.Make(e)
+	shard := wrapShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	link := shard.m[key]
+	if link == nil {
+		return nil
+	}
+	var children []*Error
+	for _, child := range link.children {
+		if c := child.Value(); c != nil {
+			children = append(children, c)
+		}
+	}
+	return children
+}
+
+// Root follows Parent links upward and returns the outermost wrapper of e.
+// If e has never been wrapped, or its link was not recorded (see Parent), Root returns e
+// itself.
+func (e *Error) Root() *Error {
+	for i := 0; i < GetMaxChainDepth(); i++ {
+		parent := e.Parent()
+		if parent == nil {
+			break
+		}
+		e = parent
+	}
+	return e
+}
@@ -0,0 +1,89 @@
+// netclassify.go: Network and timeout error classification for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// Codes assigned by ClassifyNet.
+const (
+	ErrCodeTimeout           ErrorCode = "TIMEOUT"
+	ErrCodeCanceled          ErrorCode = "CANCELED"
+	ErrCodeDNS               ErrorCode = "DNS_ERROR"
+	ErrCodeConnectionRefused ErrorCode = "CONNECTION_REFUSED"
+	ErrCodeConnectionReset   ErrorCode = "CONNECTION_RESET"
+	ErrCodeNetwork           ErrorCode = "NETWORK_ERROR"
+)
+
+// ClassifyNet wraps a network error into an *Error with a code describing the failure and
+// the retryable flag set accordingly:
+//
+//	context.DeadlineExceeded, net.Error timeouts  TIMEOUT, retryable, 504
+//	context.Canceled                              CANCELED, not retryable
+//	*net.DNSError                                 DNS_ERROR, retryable if temporary or timed out, 502
+//	ECONNREFUSED                                  CONNECTION_REFUSED, retryable, 503
+//	ECONNRESET, EPIPE                             CONNECTION_RESET, retryable, 503
+//	other net.Error values                        NETWORK_ERROR, retryable, 503
+//
+// Other errors are returned unchanged when they are already an *Error, and otherwise
+// wrapped under NETWORK_ERROR without the retryable flag. ClassifyNet returns nil if err
+// is nil.
+//
+// Example:
+//
+//	resp, err := client.Do(req)
+//	if err != nil {
+//		return errors.ClassifyNet(err).WithContext("url", req.URL.String())
+//	}
+func ClassifyNet(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	code, message, retryable, status := netKindOf(err)
+	if code == "" {
+		if e, ok := err.(*Error); ok {
+			return e
+		}
+		code, message, retryable, status = ErrCodeNetwork, "Network error", false, 0
+	}
+	e := wrapWithOptions(err, WrapOptions{Code: code, Message: message}, 1)
+	e.Retryable = retryable
+	e.HTTPStatusCode = status
+	if code == ErrCodeCanceled {
+		e.Severity = SeverityWarning
+	}
+	return e
+}
+
+// netKindOf returns the code, message, retryable flag and HTTP status of a network error,
+// or an empty code if err is not recognized.
+func netKindOf(err error) (ErrorCode, string, bool, int) {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrCodeCanceled, "Operation canceled", false, 0
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrCodeTimeout, "Operation timed out", true, http.StatusGatewayTimeout
+	case errors.As(err, &dnsErr):
+		return ErrCodeDNS, "DNS lookup failed", dnsErr.IsTimeout || dnsErr.IsTemporary, http.StatusBadGateway
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrCodeConnectionRefused, "Connection refused", true, http.StatusServiceUnavailable
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrCodeConnectionReset, "Connection reset", true, http.StatusServiceUnavailable
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrCodeTimeout, "Operation timed out", true, http.StatusGatewayTimeout
+	case errors.As(err, &netErr):
+		return ErrCodeNetwork, "Network error", true, http.StatusServiceUnavailable
+	}
+	return "", "", false, 0
+}
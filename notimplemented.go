@@ -0,0 +1,84 @@
+// notimplemented.go: Not-implemented placeholders for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// ErrCodeNotImplemented is the code of errors created by NewNotImplemented.
+const ErrCodeNotImplemented ErrorCode = "NOT_IMPLEMENTED"
+
+// notImplementedUserMsg is the user message of errors created by NewNotImplemented.
+const notImplementedUserMsg = "This feature is not yet available"
+
+var (
+	notImplementedMu       sync.RWMutex
+	notImplementedFeatures = make(map[string]*Error)
+)
+
+// NewNotImplemented creates a warning-level error with code ErrCodeNotImplemented for a
+// placeholder handler. The feature name is recorded as the operation (see WithOp), the
+// HTTP status is set to 501 and a generic user message is provided.
+//
+// Example:
+//
+//	func (s *Service) ExportReport(ctx context.Context) error {
+//		return errors.NewNotImplemented("ExportReport")
+//	}
+func NewNotImplemented(feature string) *Error {
+	defaults := func(e *Error) {
+		e.HTTPStatusCode = http.StatusNotImplemented
+		e.UserMsg = notImplementedUserMsg
+	}
+	return New(ErrCodeNotImplemented, feature+" is not implemented",
+		WithOp(feature), WithSeverity(SeverityWarning), defaults)
+}
+
+// IsNotImplemented reports whether any error in the chain has code ErrCodeNotImplemented.
+func IsNotImplemented(err error) bool {
+	return HasCode(err, ErrCodeNotImplemented)
+}
+
+// RegisterNotImplemented creates a NewNotImplemented error for each feature, records the
+// features in the package inventory and returns the errors keyed by feature name.
+// It is meant to be called once at service startup for every unimplemented endpoint.
+// The errors are created, and the creation hooks run, before the inventory is locked, and
+// the returned errors are copies of the recorded ones, so callers may modify them.
+func RegisterNotImplemented(features ...string) map[string]*Error {
+	recorded := make(map[string]*Error, len(features))
+	for _, feature := range features {
+		recorded[feature] = NewNotImplemented(feature)
+	}
+
+	notImplementedMu.Lock()
+	for feature, err := range recorded {
+		notImplementedFeatures[feature] = err
+	}
+	notImplementedMu.Unlock()
+
+	created := make(map[string]*Error, len(recorded))
+	for feature, err := range recorded {
+		created[feature] = err.Clone()
+	}
+	return created
+}
+
+// NotImplementedFeatures returns the sorted names of every feature registered through
+// RegisterNotImplemented, for building 501 inventories.
+func NotImplementedFeatures() []string {
+	notImplementedMu.RLock()
+	defer notImplementedMu.RUnlock()
+	features := make([]string, 0, len(notImplementedFeatures))
+	for feature := range notImplementedFeatures {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+	return features
+}
@@ -0,0 +1,191 @@
+// options.go: Functional options for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"sync/atomic"
+)
+
+// Option configures an error at construction time. Options are accepted by New,
+// NewWithField, NewWithContext and Wrap, and are applied in order after the error
+// has been built.
+//
+// Example:
+//
+//	err := errors.New(ErrCodeDB, "Query failed", errors.WithStack(), errors.WithContext("table", "users"))
+type Option func(*Error)
+
+// StackPolicy controls when constructors capture a stack trace by default.
+type StackPolicy int32
+
+const (
+	// OnWrapOnly captures a stack trace in Wrap but not in New (default).
+	OnWrapOnly StackPolicy = iota
+	// AlwaysCapture captures a stack trace in every constructor.
+	AlwaysCapture
+	// NeverCapture never captures a stack trace unless WithStack() is passed.
+	NeverCapture
+	// OnCritical captures a stack trace in Wrap, and in New only for critical errors:
+	// when the WithSeverity option makes the error critical, or when its severity is
+	// later raised to critical with WithSeverity or WithCriticalSeverity.
+	OnCritical
+)
+
+// stackChoice records a per-call WithStack/WithoutStack option. The constructor resolves it
+// against the global StackPolicy, and WithoutStack is honored later by the OnCritical policy.
+type stackChoice int8
+
+const (
+	stackDefault stackChoice = iota
+	stackForce
+	stackSuppress
+)
+
+var (
+	globalStackPolicy atomic.Int32
+	stackDedupOff     atomic.Bool
+)
+
+// SetStackPolicy sets the default stack capture behavior of the constructors.
+// WithStack() and WithoutStack() options always take precedence over the policy.
+//
+// Example:
+//
+//	// High-throughput service: only pay for stacks on critical failures
+//	errors.SetStackPolicy(errors.OnCritical)
+func SetStackPolicy(policy StackPolicy) {
+	globalStackPolicy.Store(int32(policy))
+}
+
+// SetGlobalStackPolicy sets the default stack capture behavior of the constructors.
+//
+// Deprecated: use SetStackPolicy.
+func SetGlobalStackPolicy(policy StackPolicy) {
+	SetStackPolicy(policy)
+}
+
+// SetStackDeduplication controls whether wrapping reuses the stack trace already present in
+// the chain. When enabled (the default), a wrapper whose cause chain contains an *Error with
+// a stack trace does not capture a second one, since the innermost trace already shows the
+// whole call path; WithStack() still forces a capture. Disable it to get a trace on every
+// wrapper.
+func SetStackDeduplication(enabled bool) {
+	stackDedupOff.Store(!enabled)
+}
+
+// GlobalStackPolicy returns the current default stack capture behavior.
+func GlobalStackPolicy() StackPolicy {
+	return StackPolicy(globalStackPolicy.Load())
+}
+
+// WithStack returns an option that captures a stack trace regardless of the StackPolicy.
+func WithStack() Option {
+	return func(e *Error) {
+		e.stackChoice = stackForce
+	}
+}
+
+// WithoutStack returns an option that disables stack capture regardless of the StackPolicy.
+func WithoutStack() Option {
+	return func(e *Error) {
+		e.stackChoice = stackSuppress
+	}
+}
+
+// WithCaller returns an option that records the function, file and line of the caller in
+// Source. It costs a single runtime.Caller lookup and one allocation, much less than a
+// stack trace, for hot paths that only need to know where an error was created.
+func WithCaller() Option {
+	return func(e *Error) {
+		e.withCaller = true
+	}
+}
+
+// WithSeverity returns an option that sets the severity, like (*Error).WithSeverity.
+// Unlike the method, it is applied before the stack policy is resolved, so that
+// New(code, msg, WithSeverity(SeverityCritical)) captures a stack under OnCritical.
+func WithSeverity(severity Severity) Option {
+	return func(e *Error) {
+		e.Severity = severity
+	}
+}
+
+// WithOp returns an option that sets the operation, like (*Error).WithOp.
+func WithOp(op string) Option {
+	return func(e *Error) {
+		e.Op = op
+	}
+}
+
+// WithContext returns an option that adds a context entry, like (*Error).WithContext.
+func WithContext(key string, value interface{}) Option {
+	return func(e *Error) {
+		e.WithContext(key, value)
+	}
+}
+
+// finish applies opts to a freshly built error, captures its stack trace according to
+// the stack options, the global StackPolicy, the stack deduplication setting and the
+// budget of SetStackSampling, records its Source if requested, runs the creation hooks
+// and records the MetricsCreated event.
+// The skip parameter is the number of frames above finish's caller to omit, so that the
+// trace starts at the user's call site.
+func (e *Error) finish(opts []Option, wrapping bool, skip int) {
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	capture := wrapping
+	switch GlobalStackPolicy() {
+	case AlwaysCapture:
+		capture = true
+	case NeverCapture:
+		capture = false
+	case OnCritical:
+		capture = wrapping || e.Severity == SeverityCritical
+	}
+	switch e.stackChoice {
+	case stackForce:
+		capture = true
+	case stackSuppress:
+		capture = false
+	default:
+		if capture && wrapping && !stackDedupOff.Load() && hasStack(e.Cause) {
+			capture = false
+		}
+		if capture && e.Stack == nil && !allowStack(e.Code) {
+			capture = false
+			if e.Context == nil {
+				e.Context = make(map[string]interface{})
+			}
+			e.Context[StackSampledKey] = false
+		}
+	}
+
+	if capture && e.Stack == nil {
+		e.Stack = CaptureStacktrace(skip + 1)
+	}
+	if e.withCaller && e.Source == nil {
+		e.Source = callerFrame(skip + 1)
+	}
+	e.assignErrorID()
+	runHooks(&creationHooks, e)
+	recordMetric(e, MetricsCreated)
+}
+
+// hasStack reports whether an *Error in the chain of err carries a stack trace.
+func hasStack(err error) bool {
+	found := false
+	var w chainWalk
+	walkErrors(err, func(cur error) bool {
+		if e, ok := cur.(*Error); ok && (e.Stack != nil || e.rawStack != "") {
+			found = true
+		}
+		return !found
+	}, &w)
+	return found
+}
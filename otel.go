@@ -0,0 +1,76 @@
+// otel.go: OpenTelemetry-compatible attribute export for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OpenTelemetry semantic convention keys used by Attributes.
+const (
+	AttrErrorType           = "error.type"
+	AttrErrorMessage        = "error.message"
+	AttrExceptionStacktrace = "exception.stacktrace"
+	AttrContextPrefix       = "error.context."
+)
+
+// SpanAttributeSetter is the minimal span interface used by WrapSpan. It keeps the library
+// free of OpenTelemetry dependencies; an OpenTelemetry span is adapted in a few lines:
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) SetAttributes(attrs map[string]string) {
+//		for k, v := range attrs {
+//			s.Span.SetAttributes(attribute.String(k, v))
+//		}
+//	}
+type SpanAttributeSetter interface {
+	SetAttributes(attrs map[string]string)
+}
+
+// Attributes maps the error to OpenTelemetry semantic convention attributes:
+// "error.type" holds the code, "error.message" the message and "exception.stacktrace"
+// the stack trace when one was captured. Every Context entry is added with the
+// "error.context." prefix, formatted with %v.
+func (e *Error) Attributes() map[string]string {
+	context, _, _ := e.redacted()
+	attrs := make(map[string]string, 3+len(context))
+	attrs[AttrErrorType] = string(e.Code)
+	attrs[AttrErrorMessage] = e.Message
+	if stack := e.Stack.String(); stack != "" {
+		attrs[AttrExceptionStacktrace] = stack
+	}
+	for k, v := range context {
+		attrs[AttrContextPrefix+k] = fmt.Sprintf("%v", v)
+	}
+	return attrs
+}
+
+// WrapSpan records err on span as attributes. The first *Error in the chain provides the
+// attributes through Attributes; any other error is reported with its Go type as
+// "error.type" and its message. A nil error or span is ignored.
+//
+// Example:
+//
+//	if err != nil {
+//		errors.WrapSpan(otelSpan{span}, err)
+//	}
+func WrapSpan(span SpanAttributeSetter, err error) {
+	if span == nil || err == nil {
+		return
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		span.SetAttributes(e.Attributes())
+		return
+	}
+	span.SetAttributes(map[string]string{
+		AttrErrorType:    fmt.Sprintf("%T", err),
+		AttrErrorMessage: err.Error(),
+	})
+}
@@ -0,0 +1,158 @@
+// panic.go: Panic recovery for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// ErrCodePanic is the code used by NewFromPanic when no code is provided.
+const ErrCodePanic ErrorCode = "PANIC"
+
+// panicValueKey is the context key holding the original value passed to panic.
+const panicValueKey = "panic_value"
+
+// NewFromPanic converts a value returned by recover() into a structured error.
+// If recovered is an error it becomes the cause, if it is a string it becomes the message,
+// and any other value is formatted with %v. The original value is stored in the context
+// under "panic_value" and the severity is set to critical.
+// If code is empty, ErrCodePanic is used. NewFromPanic returns nil when recovered is nil,
+// so it can be called unconditionally in a deferred function.
+//
+// The stack trace starts at the function that panicked, skipping the deferred
+// recovery function and the runtime panic machinery. Like New, it runs the creation
+// hooks and records MetricsCreated.
+//
+// Example:
+//
+//	defer func() {
+//		if perr := errors.NewFromPanic(recover(), "HANDLER_PANIC"); perr != nil {
+//			err = perr
+//		}
+//	}()
+func NewFromPanic(recovered interface{}, code ErrorCode) *Error {
+	if recovered == nil {
+		return nil
+	}
+	return newFromPanic(recovered, code, "", capturePanicStacktrace())
+}
+
+// newFromPanic builds the error of NewFromPanic around stack, with message instead of the
+// one derived from the panic value if it is not empty.
+func newFromPanic(recovered interface{}, code ErrorCode, message string, stack *Stacktrace) *Error {
+	if !validateErrorCode(code) {
+		code = ErrCodePanic
+	}
+	code = checkCode(code)
+
+	e := &Error{
+		Code:      code,
+		Timestamp: now(),
+		Severity:  SeverityCritical,
+		Context:   map[string]interface{}{panicValueKey: recovered},
+		Stack:     stack,
+	}
+	switch v := recovered.(type) {
+	case error:
+		e.Message = v.Error()
+		e.Cause = v
+	case string:
+		e.Message = v
+	default:
+		e.Message = fmt.Sprintf("%v", v)
+	}
+	if message != "" {
+		e.Message = message
+	}
+	e.finish(nil, false, 1)
+	return e
+}
+
+// Recover converts a panic into an *Error stored in *errp. It must be deferred directly,
+// since recover only stops a panic when called by the deferred function itself. The error
+// is built by NewFromPanic, so the panic value is kept in the context under "panic_value"
+// and the stack trace starts where the panic happened; if message is not empty it
+// replaces the message derived from the panic value. Without a panic, *errp is left
+// unchanged.
+//
+// Example:
+//
+//	func (s *Service) Process(job Job) (err error) {
+//		defer errors.Recover(&err, "JOB_PANIC", "Job processing panicked")
+//		return s.process(job)
+//	}
+func Recover(errp *error, code ErrorCode, message string) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	*errp = newFromPanic(recovered, code, message, capturePanicStacktrace())
+}
+
+// RecoverFunc calls fn and returns its error, or the panic raised by fn converted by
+// NewFromPanic with ErrCodePanic. It is meant for goroutines, where an unrecovered panic
+// would crash the whole process.
+//
+// Example:
+//
+//	go func() {
+//		if err := errors.RecoverFunc(worker.Run); err != nil {
+//			log.Error("worker failed", "error", err)
+//		}
+//	}()
+func RecoverFunc(fn func() error) (err error) {
+	defer Recover(&err, ErrCodePanic, "")
+	return fn()
+}
+
+// IsPanic reports whether any error in the chain was created by NewFromPanic
+// or carries the ErrCodePanic code.
+func IsPanic(err error) bool {
+	for cur := range unwrapChain(err) {
+		if e, ok := cur.(*Error); ok {
+			if e.Code == ErrCodePanic {
+				return true
+			}
+			if _, ok := e.Context[panicValueKey]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PanicValue returns the original value passed to panic, as stored by NewFromPanic,
+// or nil if no error in the chain was created from a panic.
+func PanicValue(err error) interface{} {
+	for cur := range unwrapChain(err) {
+		if e, ok := cur.(*Error); ok {
+			if v, ok := e.Context[panicValueKey]; ok {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// capturePanicStacktrace captures the stack of the caller of NewFromPanic or Recover and
+// trims every frame up to and including runtime.gopanic, so the trace starts where the
+// panic happened. If it is not called during panicking the full stack is kept.
+// DefaultStackFilter is applied after trimming, since it usually hides runtime.gopanic.
+func capturePanicStacktrace() *Stacktrace {
+	st := captureStacktrace(2)
+	for i, pc := range st.Frames {
+		if fn := runtime.FuncForPC(pc - 1); fn != nil && fn.Name() == "runtime.gopanic" {
+			st.Frames = st.Frames[i+1:]
+			break
+		}
+	}
+	stackFilterMu.RLock()
+	st.Frames = filterFrames(st.Frames, DefaultStackFilter)
+	stackFilterMu.RUnlock()
+	return st
+}
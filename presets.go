@@ -0,0 +1,104 @@
+// presets.go: Constructors for common error kinds for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"net/http"
+)
+
+// Codes of the errors created by the constructors below. They are the codes of the
+// matching presets of the codes subpackage, named after the canonical gRPC codes.
+const (
+	ErrCodeNotFound          ErrorCode = "NOT_FOUND"
+	ErrCodeAlreadyExists     ErrorCode = "ALREADY_EXISTS"
+	ErrCodeInvalidArgument   ErrorCode = "INVALID_ARGUMENT"
+	ErrCodeUnauthenticated   ErrorCode = "UNAUTHENTICATED"
+	ErrCodePermissionDenied  ErrorCode = "PERMISSION_DENIED"
+	ErrCodeResourceExhausted ErrorCode = "RESOURCE_EXHAUSTED"
+	ErrCodeInternal          ErrorCode = "INTERNAL"
+	ErrCodeUnavailable       ErrorCode = "UNAVAILABLE"
+)
+
+// NotFound creates a warning-level error with code ErrCodeNotFound and HTTP status 404.
+//
+// Example:
+//
+//	return errors.NotFound("user not found", errors.WithContext("user_id", id))
+func NotFound(message string, opts ...Option) *Error {
+	return newPreset(nil, ErrCodeNotFound, message, http.StatusNotFound, SeverityWarning, false, opts)
+}
+
+// Conflict creates a warning-level error with code ErrCodeAlreadyExists and HTTP status
+// 409, for a resource that already exists or conflicts with the request.
+func Conflict(message string, opts ...Option) *Error {
+	return newPreset(nil, ErrCodeAlreadyExists, message, http.StatusConflict, SeverityWarning, false, opts)
+}
+
+// BadRequest creates a warning-level error with code ErrCodeInvalidArgument and HTTP
+// status 400.
+func BadRequest(message string, opts ...Option) *Error {
+	return newPreset(nil, ErrCodeInvalidArgument, message, http.StatusBadRequest, SeverityWarning, false, opts)
+}
+
+// Unauthorized creates a warning-level error with code ErrCodeUnauthenticated and HTTP
+// status 401, for a request without valid credentials.
+func Unauthorized(message string, opts ...Option) *Error {
+	return newPreset(nil, ErrCodeUnauthenticated, message, http.StatusUnauthorized, SeverityWarning, false, opts)
+}
+
+// Forbidden creates a warning-level error with code ErrCodePermissionDenied and HTTP
+// status 403, for an authenticated caller lacking permission.
+func Forbidden(message string, opts ...Option) *Error {
+	return newPreset(nil, ErrCodePermissionDenied, message, http.StatusForbidden, SeverityWarning, false, opts)
+}
+
+// TooManyRequests creates a retryable warning-level error with code
+// ErrCodeResourceExhausted and HTTP status 429. Add WithRetryAfter to tell the client when
+// to retry.
+func TooManyRequests(message string, opts ...Option) *Error {
+	return newPreset(nil, ErrCodeResourceExhausted, message, http.StatusTooManyRequests, SeverityWarning, true, opts)
+}
+
+// Internal wraps err in an error with code ErrCodeInternal and HTTP status 500. Like
+// Wrap, it captures a stack trace.
+//
+// Example:
+//
+//	if err := tx.Commit(); err != nil {
+//		return errors.Internal(err, "failed to commit order")
+//	}
+func Internal(err error, message string, opts ...Option) *Error {
+	return newPreset(err, ErrCodeInternal, message, http.StatusInternalServerError, SeverityError, false, opts)
+}
+
+// Unavailable wraps err in a retryable error with code ErrCodeUnavailable and HTTP status
+// 503, for a dependency that is temporarily down. Like Wrap, it captures a stack trace.
+func Unavailable(err error, message string, opts ...Option) *Error {
+	return newPreset(err, ErrCodeUnavailable, message, http.StatusServiceUnavailable, SeverityError, true, opts)
+}
+
+// newPreset creates the error of the constructors above, wrapping cause when it is not
+// nil. The options are applied after the defaults, so they can override them.
+func newPreset(cause error, code ErrorCode, message string, status int, severity Severity, retryable bool, opts []Option) *Error {
+	defaults := func(e *Error) {
+		e.HTTPStatusCode = status
+		e.Retryable = retryable
+	}
+	opts = append([]Option{defaults}, opts...)
+	if cause != nil {
+		return wrapWithOptions(cause, WrapOptions{Code: code, Message: message, Severity: severity}, 2, opts...)
+	}
+	e := &Error{
+		Code:      checkCode(code),
+		Message:   message,
+		Timestamp: now(),
+		Severity:  severity,
+		Context:   make(map[string]interface{}),
+	}
+	e.finish(opts, false, 2)
+	return e
+}
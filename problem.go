@@ -0,0 +1,208 @@
+// problem.go: RFC 9457 Problem Details for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType is the media type of Problem Details documents.
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypeBase is the URI prefix of the "type" member produced by ToProblemDetails,
+// followed by the error code, e.g. "https://errors.example.com/" gives
+// "https://errors.example.com/USER_NOT_FOUND". When empty, "about:blank" is used.
+var ProblemTypeBase = ""
+
+// ProblemDetails is an RFC 9457 (formerly RFC 7807) Problem Details document.
+// Extension members are kept in Extensions and encoded at the top level.
+type ProblemDetails struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// problemMembers are the standard members of a Problem Details document.
+var problemMembers = []string{"type", "title", "status", "detail", "instance"}
+
+// MarshalJSON encodes the document with its extension members at the top level.
+// Extensions never override the standard members.
+func (p *ProblemDetails) MarshalJSON() ([]byte, error) {
+	doc := make(map[string]interface{}, 5+len(p.Extensions))
+	for k, v := range p.Extensions {
+		doc[k] = v
+	}
+	for _, k := range problemMembers {
+		delete(doc, k)
+	}
+	doc["type"] = p.Type
+	if doc["type"] == "" {
+		doc["type"] = "about:blank"
+	}
+	if p.Title != "" {
+		doc["title"] = p.Title
+	}
+	if p.Status != 0 {
+		doc["status"] = p.Status
+	}
+	if p.Detail != "" {
+		doc["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		doc["instance"] = p.Instance
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON decodes the standard members and collects every other member in Extensions.
+func (p *ProblemDetails) UnmarshalJSON(data []byte) error {
+	var std struct {
+		Type     string `json:"type"`
+		Title    string `json:"title"`
+		Status   int    `json:"status"`
+		Detail   string `json:"detail"`
+		Instance string `json:"instance"`
+	}
+	if err := json.Unmarshal(data, &std); err != nil {
+		return err
+	}
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, k := range problemMembers {
+		delete(all, k)
+	}
+	*p = ProblemDetails{
+		Type:     std.Type,
+		Title:    std.Title,
+		Status:   std.Status,
+		Detail:   std.Detail,
+		Instance: std.Instance,
+	}
+	if len(all) > 0 {
+		p.Extensions = all
+	}
+	return nil
+}
+
+// ToProblemDetails converts err to a Problem Details document. The status comes from
+// HTTPStatus and the title is its standard text. For an *Error, the type is built from
+// ProblemTypeBase and the code, the detail is the client-safe message of PublicError, and
+// the extensions hold "code", "retryable" when set, "correlation_id" when known, and the
+// Context entries with sensitive values redacted; a string "instance" entry becomes the
+// instance member. Any other error only carries the status and title.
+// It returns nil if err is nil.
+//
+// Example:
+//
+//	w.Header().Set("Content-Type", errors.ProblemContentType)
+//	w.WriteHeader(errors.HTTPStatus(err))
+//	_ = json.NewEncoder(w).Encode(errors.ToProblemDetails(err))
+func ToProblemDetails(err error) *ProblemDetails {
+	if err == nil {
+		return nil
+	}
+	status := HTTPStatus(err)
+	p := &ProblemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		return p
+	}
+
+	if ProblemTypeBase != "" {
+		p.Type = ProblemTypeBase + string(e.Code)
+	}
+	pub := e.PublicError()
+	p.Detail = pub.Message
+
+	context, _, _ := e.redacted()
+	p.Extensions = make(map[string]interface{}, 3+len(context))
+	for k, v := range context {
+		if k == "instance" {
+			if s, ok := v.(string); ok {
+				p.Instance = s
+				continue
+			}
+		}
+		p.Extensions[k] = v
+	}
+	p.Extensions["code"] = string(e.Code)
+	if pub.Retryable {
+		p.Extensions["retryable"] = true
+	}
+	if pub.CorrelationID != "" {
+		p.Extensions["correlation_id"] = pub.CorrelationID
+	}
+	return p
+}
+
+// FromProblemDetails parses a Problem Details document, typically received from another
+// service or an API gateway, into an *Error. The code is read from the "code" extension,
+// or from the last path segment of the type, and defaults to DefaultErrorCode. The detail,
+// or else the title, becomes both the message and the user message, the status becomes
+// HTTPStatusCode, and the remaining extensions and the instance are stored in Context.
+// Like UnmarshalJSON, it does not apply the code convention to the received code.
+//
+// Example:
+//
+//	if resp.Header.Get("Content-Type") == errors.ProblemContentType {
+//		body, _ := io.ReadAll(resp.Body)
+//		if apiErr, err := errors.FromProblemDetails(body); err == nil {
+//			return apiErr
+//		}
+//	}
+func FromProblemDetails(data []byte) (*Error, error) {
+	var p ProblemDetails
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+
+	code := DefaultErrorCode
+	if c, ok := p.Extensions["code"].(string); ok && c != "" {
+		code = ErrorCode(c)
+	} else if p.Type != "" && p.Type != "about:blank" {
+		code = ErrorCode(p.Type[strings.LastIndexByte(p.Type, '/')+1:])
+	}
+	message := p.Detail
+	if message == "" {
+		message = p.Title
+	}
+
+	e := &Error{
+		Code:           code,
+		Message:        message,
+		UserMsg:        message,
+		HTTPStatusCode: p.Status,
+		Timestamp:      now(),
+		Severity:       SeverityError,
+		Context:        make(map[string]interface{}, len(p.Extensions)),
+	}
+	for k, v := range p.Extensions {
+		switch k {
+		case "code":
+		case "retryable":
+			e.Retryable, _ = v.(bool)
+		default:
+			e.Context[k] = v
+		}
+	}
+	if p.Instance != "" {
+		e.Context["instance"] = p.Instance
+	}
+	return e, nil
+}
@@ -0,0 +1,66 @@
+// public.go: Client-safe error representation for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// correlationKeys are the context keys searched, in order, for the correlation ID of
+// PublicError.
+var correlationKeys = []string{"correlation_id", "request_id", "trace_id"}
+
+// PublicError is the client-safe view of an Error, returned by (*Error).PublicError.
+// It never contains the technical message, the context, the cause or the stack trace.
+type PublicError struct {
+	Code          ErrorCode `json:"code"`
+	Message       string    `json:"message"`
+	Retryable     bool      `json:"retryable,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	ErrorID       string    `json:"error_id,omitempty"`
+}
+
+// PublicError returns the view of the error that is safe to send to API clients.
+// The message is the user message (see UserMessage and WithUserMessageKey) or, if none
+// is set, the standard text of the error's HTTP status, never the technical Message.
+// The correlation ID is taken from the "correlation_id", "request_id" or "trace_id"
+// context entry, in that order, and otherwise from TraceID. The ErrorID, if any, is
+// copied and referenced in the message.
+//
+// Example:
+//
+//	w.WriteHeader(errors.HTTPStatus(err))
+//	_ = json.NewEncoder(w).Encode(err.PublicError())
+func (e *Error) PublicError() PublicError {
+	pub := PublicError{
+		Code:      e.Code,
+		Retryable: e.Retryable,
+		ErrorID:   e.ErrorID,
+	}
+	if e.UserMsg != "" || e.UserMsgKey != "" {
+		pub.Message = e.UserMessage()
+	} else {
+		pub.Message = e.withErrorIDReference(http.StatusText(HTTPStatus(e)))
+	}
+	for _, key := range correlationKeys {
+		if id, ok := e.Context[key].(string); ok && id != "" {
+			pub.CorrelationID = id
+			break
+		}
+	}
+	if pub.CorrelationID == "" {
+		pub.CorrelationID = e.TraceID
+	}
+	return pub
+}
+
+// MarshalPublicJSON encodes PublicError as JSON. Use it for API responses, and MarshalJSON
+// for internal logging.
+func (e *Error) MarshalPublicJSON() ([]byte, error) {
+	return json.Marshal(e.PublicError())
+}
@@ -0,0 +1,74 @@
+// ratelimit.go: Rate-limited error construction for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// NewRateLimited returns a constructor that behaves like New but creates at most
+// limitPerSecond errors per second, using a token bucket with a burst equal to the limit
+// (and never smaller than one). When the bucket is empty the constructor returns nil,
+// meaning the error has been rate-limited away and should not be logged or reported.
+// A limit of zero or less makes every call return nil.
+// The returned function is safe for concurrent use.
+//
+// Example:
+//
+//	newCacheMiss := NewRateLimited(10)
+//	if err := newCacheMiss("CACHE_MISS", "Key not found"); err != nil {
+//		log.Warn("cache miss", "error", err)
+//	}
+func NewRateLimited(limitPerSecond float64) func(code ErrorCode, message string) *Error {
+	burst := limitPerSecond
+	if burst < 1 {
+		burst = 1
+	}
+	var (
+		mu     sync.Mutex
+		tokens = burst
+		last   = time.Now()
+	)
+	return func(code ErrorCode, message string) *Error {
+		if limitPerSecond <= 0 {
+			return nil
+		}
+
+		mu.Lock()
+		now := time.Now()
+		tokens += now.Sub(last).Seconds() * limitPerSecond
+		if tokens > burst {
+			tokens = burst
+		}
+		last = now
+		allowed := tokens >= 1
+		if allowed {
+			tokens--
+		}
+		mu.Unlock()
+
+		if !allowed {
+			return nil
+		}
+		err := New(code, message)
+		err.rateLimit = limitPerSecond
+		return err
+	}
+}
+
+// SampledErrorRate returns the per-second limit configured on the NewRateLimited factory
+// that created the first *Error in the chain. It returns 0 if err was not created by a
+// rate-limited constructor.
+func SampledErrorRate(err error) float64 {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.rateLimit
+	}
+	return 0
+}
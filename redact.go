@@ -0,0 +1,198 @@
+// redact.go: Sensitive data redaction for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RedactedValue replaces sensitive values in every output of the package: JSON, slog
+// attributes, %+v, OpenTelemetry attributes and HTTP responses.
+const RedactedValue = "[REDACTED]"
+
+// SensitiveValue holds a context value that must never be printed. It formats, encodes
+// and logs as RedactedValue; use Reveal to read the original value in code.
+type SensitiveValue struct {
+	value interface{}
+}
+
+// Sensitive wraps v so that it is redacted wherever it is printed.
+func Sensitive(v interface{}) SensitiveValue {
+	return SensitiveValue{value: v}
+}
+
+// Reveal returns the wrapped value.
+func (s SensitiveValue) Reveal() interface{} {
+	return s.value
+}
+
+// String implements fmt.Stringer and returns RedactedValue.
+func (s SensitiveValue) String() string {
+	return RedactedValue
+}
+
+// GoString implements fmt.GoStringer and returns RedactedValue, also hiding the value from %#v.
+func (s SensitiveValue) GoString() string {
+	return RedactedValue
+}
+
+// MarshalJSON encodes the value as the RedactedValue string.
+func (s SensitiveValue) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + RedactedValue + `"`), nil
+}
+
+// LogValue implements slog.LogValuer and returns RedactedValue.
+func (s SensitiveValue) LogValue() slog.Value {
+	return slog.StringValue(RedactedValue)
+}
+
+// WithSensitiveContext adds a context entry whose value is redacted in every output,
+// and returns the error for chaining. The value stays available through Reveal.
+//
+// Example:
+//
+//	err := New("AUTH_FAILED", "Invalid credentials").
+//		WithSensitiveContext("email", req.Email)
+//	email := err.Context["email"].(errors.SensitiveValue).Reveal()
+func (e *Error) WithSensitiveContext(key string, value interface{}) *Error {
+	return e.WithContext(key, Sensitive(value))
+}
+
+var (
+	redactMu       sync.RWMutex
+	redactKeys     map[string]struct{}
+	redactPatterns []*regexp.Regexp
+)
+
+// RedactKeys marks context keys as sensitive for every error, compared case-insensitively.
+// Their values, and the Value of errors whose Field is one of them, are printed as
+// RedactedValue.
+//
+// Example:
+//
+//	func init() {
+//		errors.RedactKeys("password", "ssn", "credit_card")
+//	}
+func RedactKeys(keys ...string) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	if redactKeys == nil {
+		redactKeys = make(map[string]struct{}, len(keys))
+	}
+	for _, k := range keys {
+		redactKeys[strings.ToLower(k)] = struct{}{}
+	}
+}
+
+// RedactPattern marks every context key matching re as sensitive, like RedactKeys.
+//
+// Example:
+//
+//	errors.RedactPattern(regexp.MustCompile(`(?i)token|secret`))
+func RedactPattern(re *regexp.Regexp) {
+	if re == nil {
+		return
+	}
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	redactPatterns = append(redactPatterns, re)
+}
+
+// ClearRedaction removes every key and pattern registered with RedactKeys and RedactPattern.
+// Values wrapped with Sensitive stay redacted.
+func ClearRedaction() {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	redactKeys = nil
+	redactPatterns = nil
+}
+
+// redactedView returns e itself, or a shallow copy carrying the redacted Context, Value and
+// FieldErrors when a registered sensitive key applies to e.
+func (e *Error) redactedView() *Error {
+	context, value, changed := e.redacted()
+	fields, fieldsChanged := e.redactedFields()
+	if !changed && !fieldsChanged {
+		return e
+	}
+	c := *e
+	c.Context, c.Value, c.FieldErrors = context, value, fields
+	return &c
+}
+
+// redactedFields returns the FieldErrors of e with the values of sensitive fields replaced
+// by RedactedValue, and whether anything was replaced. The FieldErrors of e are never modified.
+func (e *Error) redactedFields() ([]FieldError, bool) {
+	if len(e.FieldErrors) == 0 {
+		return e.FieldErrors, false
+	}
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	fields := e.FieldErrors
+	copied := false
+	for i, f := range e.FieldErrors {
+		if f.Value == "" || !isSensitiveKey(f.Field) {
+			continue
+		}
+		if !copied {
+			fields = append([]FieldError(nil), e.FieldErrors...)
+			copied = true
+		}
+		fields[i].Value = RedactedValue
+	}
+	return fields, copied
+}
+
+// redacted returns the Context and Value of e with the registered sensitive keys replaced
+// by RedactedValue, and whether anything was replaced. The Context of e is never modified.
+// Without a registered key or pattern it costs a read lock.
+func (e *Error) redacted() (map[string]interface{}, string, bool) {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	if len(redactKeys) == 0 && len(redactPatterns) == 0 {
+		return e.Context, e.Value, false
+	}
+
+	value := e.Value
+	changed := false
+	if e.Field != "" && value != "" && isSensitiveKey(e.Field) {
+		value = RedactedValue
+		changed = true
+	}
+	context := e.Context
+	copied := false
+	for k := range e.Context {
+		if !isSensitiveKey(k) {
+			continue
+		}
+		if !copied {
+			context = make(map[string]interface{}, len(e.Context))
+			for k2, v := range e.Context {
+				context[k2] = v
+			}
+			copied = true
+		}
+		context[k] = RedactedValue
+	}
+	return context, value, changed || copied
+}
+
+// isSensitiveKey reports whether key is registered as sensitive. The caller holds redactMu.
+func isSensitiveKey(key string) bool {
+	if _, ok := redactKeys[strings.ToLower(key)]; ok {
+		return true
+	}
+	for _, re := range redactPatterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+// registry.go: Central error metadata registry for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+)
+
+// ErrCodeAlreadyRegistered is the code returned by Register for a duplicate error code.
+const ErrCodeAlreadyRegistered ErrorCode = "ERROR_CODE_ALREADY_REGISTERED"
+
+// ErrorMeta holds the defaults registered for an error code.
+type ErrorMeta struct {
+	DefaultUserMessage string
+	DefaultSeverity    Severity
+	DefaultRetryable   bool
+	DefaultHTTPStatus  int
+}
+
+// ErrorRegistry centralizes the metadata of the error codes used across a service, so that
+// codes can be enumerated, checked in CI and documented. It is safe for concurrent use.
+type ErrorRegistry struct {
+	mu    sync.RWMutex
+	codes map[ErrorCode]ErrorMeta
+}
+
+// DefaultRegistry is the registry used by the package-level Register and Lookup functions.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *ErrorRegistry {
+	return &ErrorRegistry{codes: make(map[ErrorCode]ErrorMeta)}
+}
+
+// Register records the metadata of code. It returns an error with code
+// ErrCodeAlreadyRegistered if code has already been registered.
+//
+// Example:
+//
+//	var registry = errors.NewRegistry()
+//
+//	func init() {
+//		_ = registry.Register(ErrCodeDatabase, errors.ErrorMeta{
+//			DefaultUserMessage: "The service is temporarily unavailable",
+//			DefaultSeverity:    errors.SeverityCritical,
+//			DefaultRetryable:   true,
+//			DefaultHTTPStatus:  503,
+//		})
+//	}
+func (r *ErrorRegistry) Register(code ErrorCode, meta ErrorMeta) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.codes[code]; exists {
+		return New(ErrCodeAlreadyRegistered, "error code is already registered").
+			WithContext("code", string(code))
+	}
+	r.codes[code] = meta
+	return nil
+}
+
+// Lookup returns the metadata registered for code.
+func (r *ErrorRegistry) Lookup(code ErrorCode) (ErrorMeta, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	meta, ok := r.codes[code]
+	return meta, ok
+}
+
+// All returns a copy of every registered code and its metadata.
+func (r *ErrorRegistry) All() map[ErrorCode]ErrorMeta {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	all := make(map[ErrorCode]ErrorMeta, len(r.codes))
+	for code, meta := range r.codes {
+		all[code] = meta
+	}
+	return all
+}
+
+// New creates an error like New, pre-filled with the registered defaults of code:
+// user message, severity, retryable flag and HTTP status. Unregistered codes produce
+// a plain error. The defaults are applied before the stack policy is evaluated, so a code
+// registered as critical captures a stack trace under OnCritical.
+func (r *ErrorRegistry) New(code ErrorCode, message string) *Error {
+	code = checkCode(code)
+	e := &Error{
+		Code:      code,
+		Message:   message,
+		Timestamp: now(),
+		Severity:  SeverityError,
+		Context:   make(map[string]interface{}),
+	}
+	if meta, ok := r.Lookup(code); ok {
+		e.UserMsg = meta.DefaultUserMessage
+		if meta.DefaultSeverity != "" {
+			e.Severity = meta.DefaultSeverity
+		}
+		e.Retryable = meta.DefaultRetryable
+		e.HTTPStatusCode = meta.DefaultHTTPStatus
+	}
+	e.finish(nil, false, 1)
+	return e
+}
+
+// Validate returns the sorted list of registered codes without a DefaultUserMessage,
+// for use in CI checks.
+func (r *ErrorRegistry) Validate() []ErrorCode {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var missing []ErrorCode
+	for code, meta := range r.codes {
+		if meta.DefaultUserMessage == "" {
+			missing = append(missing, code)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	return missing
+}
+
+// CatalogEntry describes a registered error code in the catalog produced by Catalog.
+type CatalogEntry struct {
+	Code        ErrorCode `json:"code"`
+	UserMessage string    `json:"user_message,omitempty"`
+	Severity    Severity  `json:"severity,omitempty"`
+	Retryable   bool      `json:"retryable"`
+	HTTPStatus  int       `json:"http_status,omitempty"`
+}
+
+// Catalog returns every registered code and its defaults, sorted by code, for generating
+// documentation and client SDKs.
+func (r *ErrorRegistry) Catalog() []CatalogEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entries := make([]CatalogEntry, 0, len(r.codes))
+	for code, meta := range r.codes {
+		entries = append(entries, CatalogEntry{
+			Code:        code,
+			UserMessage: meta.DefaultUserMessage,
+			Severity:    meta.DefaultSeverity,
+			Retryable:   meta.DefaultRetryable,
+			HTTPStatus:  meta.DefaultHTTPStatus,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Code < entries[j].Code })
+	return entries
+}
+
+// WriteCatalog writes the Catalog to w as an indented JSON array. It is meant to be called
+// from a small program run by go:generate or by the build.
+//
+// Example:
+//
+//	// cmd/errcatalog/main.go, run with: //go:generate go run ./cmd/errcatalog
+//	func main() {
+//		f, _ := os.Create("docs/errors.json")
+//		defer f.Close()
+//		if err := myservice.Errors.WriteCatalog(f); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+func (r *ErrorRegistry) WriteCatalog(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.Catalog())
+}
+
+// Register records the metadata of code in DefaultRegistry.
+func Register(code ErrorCode, meta ErrorMeta) error {
+	return DefaultRegistry.Register(code, meta)
+}
+
+// Lookup returns the metadata registered for code in DefaultRegistry.
+func Lookup(code ErrorCode) (ErrorMeta, bool) {
+	return DefaultRegistry.Lookup(code)
+}
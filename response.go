@@ -0,0 +1,169 @@
+// response.go: HTTP response conversion for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const (
+	// ErrCodeHTTPResponse is the code of the errors returned by FromHTTPResponse for a
+	// failed response whose body is not a structured error.
+	ErrCodeHTTPResponse ErrorCode = "HTTP_RESPONSE_ERROR"
+
+	// RemoteStackKey is the context key under which FromHTTPResponse stores the stack trace
+	// serialized by the remote service.
+	RemoteStackKey = "remote_stack"
+
+	// MaxHTTPErrorBody is the number of bytes of a response body read by FromHTTPResponse.
+	MaxHTTPErrorBody = 1 << 20
+
+	// maxHTTPBodyContext bounds the raw body kept in the context of ErrCodeHTTPResponse errors.
+	maxHTTPBodyContext = 512
+)
+
+// WriteHTTP writes err to w in the JSON representation of MarshalJSON, with the status
+// returned by HTTPStatus, so that FromHTTPResponse rebuilds it on the other side. Unlike
+// WriteHTTPError, it sends the technical message, context and stack trace, and is meant for
+// calls between trusted services; use WriteHTTPError or ToProblemDetails for API clients.
+// Errors that are not *Error are written as a {"message":"..."} envelope.
+//
+// Example:
+//
+//	func (s *server) internalHandler(w http.ResponseWriter, r *http.Request) {
+//		if err := s.process(r); err != nil {
+//			errors.WriteHTTP(w, err)
+//		}
+//	}
+func WriteHTTP(w http.ResponseWriter, err error) {
+	status := HTTPStatus(err)
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = EncodeJSON(w, err)
+}
+
+// FromHTTPResponse rebuilds the error reported by a failed HTTP response. It reads up to
+// MaxHTTPErrorBody bytes of the body, which the caller still has to close, and accepts:
+//   - a JSON error written by WriteHTTP or MarshalJSON, whose stack trace, if any, is moved
+//     to the RemoteStackKey context entry since it describes the remote process;
+//   - the envelope written by WriteHTTPError;
+//   - a Problem Details document, see FromProblemDetails.
+//
+// Any other body gives an error with code ErrCodeHTTPResponse whose message is the status
+// text, with the start of the body in the "body" context entry. The status of the response
+// becomes HTTPStatusCode unless the document sets one. Like UnmarshalJSON, it does not apply
+// the code convention to the received code. FromHTTPResponse returns nil for a nil response
+// or a status below 400.
+//
+// Example:
+//
+//	resp, err := client.Do(req)
+//	if err != nil {
+//		return errors.Wrap(err, ErrCodeNetwork, "Billing call failed")
+//	}
+//	defer resp.Body.Close()
+//	if apiErr := errors.FromHTTPResponse(resp); apiErr != nil {
+//		return apiErr
+//	}
+func FromHTTPResponse(resp *http.Response) *Error {
+	if resp == nil || resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	var body []byte
+	if resp.Body != nil {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, MaxHTTPErrorBody))
+	}
+
+	e := parseHTTPErrorBody(resp.Header.Get("Content-Type"), body)
+	if e == nil {
+		e = New(ErrCodeHTTPResponse, http.StatusText(resp.StatusCode))
+		if len(body) > 0 {
+			if len(body) > maxHTTPBodyContext {
+				body = body[:maxHTTPBodyContext]
+			}
+			e.Context["body"] = string(body)
+		}
+	}
+	if e.HTTPStatusCode == 0 {
+		e.HTTPStatusCode = resp.StatusCode
+	}
+	return e
+}
+
+// parseHTTPErrorBody decodes a response body in one of the formats accepted by
+// FromHTTPResponse, or returns nil.
+func parseHTTPErrorBody(contentType string, body []byte) *Error {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == ProblemContentType {
+		e, err := FromProblemDetails(body)
+		if err != nil {
+			return nil
+		}
+		return e
+	}
+
+	body, err := jsonOptions.Load().unmarshalDocument(body)
+	if err != nil {
+		return nil
+	}
+	var probe struct {
+		Code     *string          `json:"code"`
+		Envelope *json.RawMessage `json:"error"`
+		Type     string           `json:"type"`
+		Title    string           `json:"title"`
+	}
+	if json.Unmarshal(body, &probe) != nil {
+		return nil
+	}
+	switch {
+	case probe.Code != nil:
+		e := &Error{}
+		if e.unmarshalJSON(body) != nil {
+			return nil
+		}
+		if e.rawStack != "" {
+			if e.Context == nil {
+				e.Context = make(map[string]interface{})
+			}
+			e.Context[RemoteStackKey] = e.rawStack
+			e.rawStack = ""
+		}
+		return e
+	case probe.Envelope != nil:
+		var envelope HTTPErrorBody
+		if json.Unmarshal(*probe.Envelope, &envelope) != nil || envelope.Code == "" {
+			return nil
+		}
+		e := &Error{
+			Code:           envelope.Code,
+			Message:        envelope.Message,
+			UserMsg:        envelope.Message,
+			HTTPStatusCode: envelope.Status,
+			Retryable:      envelope.Retryable,
+			FieldErrors:    envelope.Fields,
+			Timestamp:      now(),
+			Severity:       SeverityError,
+			Context:        make(map[string]interface{}),
+		}
+		if envelope.RequestID != "" {
+			e.Context["request_id"] = envelope.RequestID
+		}
+		return e
+	case probe.Type != "" || probe.Title != "":
+		e, err := FromProblemDetails(body)
+		if err != nil {
+			return nil
+		}
+		return e
+	}
+	return nil
+}
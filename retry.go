@@ -0,0 +1,239 @@
+// retry.go: Retry guidance for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Context keys recorded by Retry on the error it returns.
+const (
+	RetryAttemptsKey = "retry_attempts" // number of attempts made
+	RetryStoppedKey  = "retry_stopped"  // context error that interrupted the retries, if any
+)
+
+// RetryPolicy describes how an operation failing with an error should be retried.
+// Zero fields mean "no hint": callers apply their own defaults.
+type RetryPolicy struct {
+	MaxRetries     int           `json:"max_retries,omitempty"`     // Maximum number of retries after the first attempt
+	InitialBackoff time.Duration `json:"initial_backoff,omitempty"` // Delay before the first retry
+	MaxBackoff     time.Duration `json:"max_backoff,omitempty"`     // Upper bound for any single delay
+	Multiplier     float64       `json:"multiplier,omitempty"`      // Growth factor between delays; 2 if zero
+	Jitter         bool          `json:"jitter,omitempty"`          // Whether delays should be randomized
+}
+
+// Backoff returns the delay before retry number attempt (starting at 1), growing
+// exponentially from InitialBackoff by Multiplier and capped by MaxBackoff.
+// Jitter is not applied, so the result is deterministic.
+//
+// Example:
+//
+//	p := errors.RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+//	p.Backoff(1) // 100ms
+//	p.Backoff(3) // 400ms
+//	p.Backoff(9) // 1s
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if attempt < 1 || p.InitialBackoff <= 0 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	return time.Duration(delay)
+}
+
+// WithRetryPolicy attaches structured retry guidance to the error, marks it as retryable
+// and returns the error for chaining.
+//
+// Example:
+//
+//	return errors.Wrap(err, "UPSTREAM_UNAVAILABLE", "Inventory service unavailable").
+//		WithRetryPolicy(errors.RetryPolicy{
+//			MaxRetries:     5,
+//			InitialBackoff: 200 * time.Millisecond,
+//			MaxBackoff:     5 * time.Second,
+//			Jitter:         true,
+//		})
+func (e *Error) WithRetryPolicy(p RetryPolicy) *Error {
+	e.RetryPolicy = &p
+	e.Retryable = true
+	return e
+}
+
+// RetryAdvice returns the policy set with WithRetryPolicy.
+// This implements the RetryAdvisor interface.
+func (e *Error) RetryAdvice() (RetryPolicy, bool) {
+	if e.RetryPolicy == nil {
+		return RetryPolicy{}, false
+	}
+	return *e.RetryPolicy, true
+}
+
+// RetryPolicyOf returns the first retry policy found in the error chain, provided by any
+// error implementing RetryAdvisor. The second return value is false if there is none.
+func RetryPolicyOf(err error) (RetryPolicy, bool) {
+	for cur := range unwrapChain(err) {
+		if ra, ok := cur.(RetryAdvisor); ok {
+			if p, ok := ra.RetryAdvice(); ok {
+				return p, true
+			}
+		}
+	}
+	return RetryPolicy{}, false
+}
+
+// RetryOption configures Retry.
+type RetryOption func(*RetryPolicy)
+
+// RetryMaxAttempts sets the total number of attempts, including the first one (default 3).
+func RetryMaxAttempts(n int) RetryOption {
+	return func(p *RetryPolicy) {
+		p.MaxRetries = n - 1
+	}
+}
+
+// RetryBackoff sets the delay before the first retry and the upper bound for any delay
+// (defaults 100ms and 10s).
+func RetryBackoff(initial, maxDelay time.Duration) RetryOption {
+	return func(p *RetryPolicy) {
+		p.InitialBackoff = initial
+		p.MaxBackoff = maxDelay
+	}
+}
+
+// RetryWithoutJitter disables the randomization of the backoff delays.
+func RetryWithoutJitter() RetryOption {
+	return func(p *RetryPolicy) {
+		p.Jitter = false
+	}
+}
+
+// Retry runs fn until it succeeds, returns an error that is not retryable, the attempts
+// are exhausted or ctx is done. An error is retried if any error in its chain implements
+// Retryable and reports true. The delay before each retry is, in order of preference, the
+// RetryDelay of the error (see WithRetryAfter), the backoff of its RetryPolicy, or the
+// exponential backoff configured by opts; jitter spreads delays between half and the full
+// value. A RetryPolicy carried by the error also overrides the configured attempt count.
+//
+// The final error is returned as an *Error with the number of attempts recorded under
+// RetryAttemptsKey, and the context error under RetryStoppedKey if ctx ended the retries.
+// The error of fn is wrapped without modifying it: the wrapper keeps the code of the first
+// *Error in its chain and inherits its metadata, like WrapPreserve.
+//
+// Example:
+//
+//	err := errors.Retry(ctx, func(ctx context.Context) error {
+//		return client.Publish(ctx, msg)
+//	}, errors.RetryMaxAttempts(5), errors.RetryBackoff(50*time.Millisecond, 2*time.Second))
+func Retry(ctx context.Context, fn func(ctx context.Context) error, opts ...RetryOption) error {
+	config := RetryPolicy{
+		MaxRetries:     2,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Jitter:         true,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	attempt := 0
+	for {
+		attempt++
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if !isRetryable(err) {
+			return retryResult(err, attempt, nil)
+		}
+
+		policy := config
+		if p, ok := RetryPolicyOf(err); ok {
+			policy = mergeRetryPolicy(config, p)
+		}
+		if attempt > policy.MaxRetries {
+			return retryResult(err, attempt, nil)
+		}
+
+		delay, ok := RetryDelay(err)
+		if !ok {
+			delay = policy.Backoff(attempt)
+			if policy.Jitter && delay > 0 {
+				delay = delay/2 + rand.N(delay/2+1)
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retryResult(err, attempt, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryable reports whether any error in the chain of err implements Retryable and
+// reports true.
+func isRetryable(err error) bool {
+	found := false
+	var w chainWalk
+	walkErrors(err, func(cur error) bool {
+		if r, ok := cur.(Retryable); ok && r.IsRetryable() {
+			found = true
+		}
+		return !found
+	}, &w)
+	return found
+}
+
+// mergeRetryPolicy returns base with the non-zero fields of hint applied.
+func mergeRetryPolicy(base, hint RetryPolicy) RetryPolicy {
+	if hint.MaxRetries > 0 {
+		base.MaxRetries = hint.MaxRetries
+	}
+	if hint.InitialBackoff > 0 {
+		base.InitialBackoff = hint.InitialBackoff
+	}
+	if hint.MaxBackoff > 0 {
+		base.MaxBackoff = hint.MaxBackoff
+	}
+	if hint.Multiplier > 0 {
+		base.Multiplier = hint.Multiplier
+	}
+	base.Jitter = base.Jitter || hint.Jitter
+	return base
+}
+
+// retryResult returns the final error of Retry annotated with the attempt count and the
+// context error that stopped it, if any. The error of fn is wrapped rather than annotated
+// in place, since it may be shared, e.g. a package-level error returned by every attempt.
+func retryResult(err error, attempts int, stopped error) *Error {
+	message := err.Error()
+	if inner, ok := err.(*Error); ok {
+		message = inner.Message
+	}
+	e := wrapWithOptions(err, WrapOptions{Message: message, PreserveCode: true, SkipStack: true, Inherit: true}, 1)
+	e.WithContext(RetryAttemptsKey, attempts)
+	if stopped != nil {
+		e.WithContext(RetryStoppedKey, stopped.Error())
+	}
+	return e
+}
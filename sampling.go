@@ -0,0 +1,72 @@
+// sampling.go: Probabilistic logging decisions for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"math/rand/v2"
+)
+
+// AlwaysLog returns the sampling rate that logs every occurrence (1.0).
+func AlwaysLog() float64 {
+	return 1.0
+}
+
+// NeverLog returns the sampling rate that suppresses every occurrence (0.0).
+func NeverLog() float64 {
+	return 0.0
+}
+
+// LogOneIn returns the sampling rate that logs on average one occurrence out of n.
+// Values of n lower than 1 are treated as 1.
+func LogOneIn(n int) float64 {
+	if n < 1 {
+		n = 1
+	}
+	return 1.0 / float64(n)
+}
+
+// Sample returns true with probability rate, where rate is in the range 0.0–1.0.
+// Rates outside the range are clamped. The decision uses the runtime-seeded
+// math/rand/v2 generator, so it is fast and safe for concurrent use.
+//
+// Example:
+//
+//	if err.Sample(0.01) {
+//		log.Error("cache failure", "error", err)
+//	}
+func (e *Error) Sample(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if rate >= 1 {
+		return true
+	}
+	return rand.Float64() < rate
+}
+
+// WithSamplingRate stores the sampling rate used by ShouldLog and returns the error for chaining.
+// Use AlwaysLog(), NeverLog() or LogOneIn() for the common rates.
+func (e *Error) WithSamplingRate(rate float64) *Error {
+	e.samplingRate = rate
+	e.hasSamplingRate = true
+	return e
+}
+
+// ShouldLog decides whether err should be logged. It samples with the rate of the outermost
+// *Error in the chain that has one set through WithSamplingRate, and returns true when no
+// rate was set anywhere in the chain. It returns false for a nil error.
+func ShouldLog(err error) bool {
+	if err == nil {
+		return false
+	}
+	for cur := range unwrapChain(err) {
+		if e, ok := cur.(*Error); ok && e.hasSamplingRate {
+			return e.Sample(e.samplingRate)
+		}
+	}
+	return true
+}
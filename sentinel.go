@@ -0,0 +1,47 @@
+// sentinel.go: Sentinel errors for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"reflect"
+)
+
+// NewSentinel creates a package-level sentinel error compared by identity rather than by code.
+// errors.Is(err, sentinel) is true only if the sentinel itself is in the chain of err, even if
+// other errors share its code. HasCode keeps matching by code.
+// Sentinels are shared values: do not modify them, use Wrap or Clone to add context. A clone
+// still matches the sentinel in errors.Is, but not in IsSentinel.
+//
+// Example:
+//
+//	var ErrNotFound = errors.NewSentinel("NOT_FOUND", "Resource not found")
+//
+//	if errors.Is(err, ErrNotFound) {
+//		// Handle the sentinel, not just any NOT_FOUND error
+//	}
+func NewSentinel(code ErrorCode, message string) *Error {
+	e := New(code, message)
+	e.sentinel = true
+	return e
+}
+
+// IsSentinel reports whether the chain of err contains sentinel itself, compared by identity.
+// Unlike errors.Is, it never falls back to code comparison or custom Is methods.
+func IsSentinel(err, sentinel error) bool {
+	if err == nil || sentinel == nil || !reflect.TypeOf(sentinel).Comparable() {
+		return false
+	}
+	found := false
+	var w chainWalk
+	walkErrors(err, func(cur error) bool {
+		if reflect.TypeOf(cur) == reflect.TypeOf(sentinel) && cur == sentinel {
+			found = true
+		}
+		return !found
+	}, &w)
+	return found
+}
@@ -0,0 +1,106 @@
+// severity.go: Typed severity levels for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"strings"
+)
+
+// Severity is the severity level of an error. The predefined levels are ordered
+// SeverityInfo < SeverityWarning < SeverityError < SeverityCritical; custom levels rank
+// below SeverityInfo. Severity marshals to JSON as its plain string form.
+type Severity string
+
+// ParseSeverity returns the predefined Severity named by s, ignoring case and surrounding
+// spaces. It returns false if s is not one of the predefined levels.
+//
+// Example:
+//
+//	level, ok := errors.ParseSeverity(os.Getenv("ALERT_LEVEL"))
+//	if !ok {
+//		level = errors.SeverityCritical
+//	}
+func ParseSeverity(s string) (Severity, bool) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if severity.Rank() == 0 {
+		return "", false
+	}
+	return severity, true
+}
+
+// Rank returns the position of s in the severity ordering, from 1 for SeverityInfo to 4
+// for SeverityCritical, and 0 for custom levels.
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityInfo:
+		return 1
+	case SeverityWarning:
+		return 2
+	case SeverityError:
+		return 3
+	case SeverityCritical:
+		return 4
+	}
+	return 0
+}
+
+// AtLeast reports whether s is as severe as level or more.
+func (s Severity) AtLeast(level Severity) bool {
+	return s.Rank() >= level.Rank()
+}
+
+// String returns the string form of s.
+func (s Severity) String() string {
+	return string(s)
+}
+
+// AtLeast reports whether the severity of the error is as severe as level or more.
+//
+// Example:
+//
+//	if err.AtLeast(errors.SeverityError) {
+//		pager.Notify(err)
+//	}
+func (e *Error) AtLeast(level Severity) bool {
+	return e.Severity.AtLeast(level)
+}
+
+// MaxSeverity returns the highest severity of the *Error values in the chain of err,
+// including the branches of aggregates such as errors.Join. It returns an empty Severity
+// if err is nil or contains no *Error.
+//
+// Example:
+//
+//	if errors.MaxSeverity(err).AtLeast(errors.SeverityCritical) {
+//		pager.Notify(err)
+//	}
+func MaxSeverity(err error) Severity {
+	var highest Severity
+	found := false
+	var w chainWalk
+	walkErrors(err, func(cur error) bool {
+		if e, ok := cur.(*Error); ok && (!found || e.Severity.Rank() > highest.Rank()) {
+			highest, found = e.Severity, true
+		}
+		return true
+	}, &w)
+	return highest
+}
+
+// HasSeverity reports whether any *Error in the chain of err, including the branches of
+// aggregates such as errors.Join, has exactly the given severity.
+func HasSeverity(err error, severity Severity) bool {
+	found := false
+	var w chainWalk
+	walkErrors(err, func(cur error) bool {
+		if e, ok := cur.(*Error); ok && e.Severity == severity {
+			found = true
+		}
+		return !found
+	}, &w)
+	return found
+}
@@ -0,0 +1,86 @@
+// slog.go: log/slog integration for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"log/slog"
+	"sort"
+)
+
+// LogValue implements slog.LogValuer, so that logging an *Error with log/slog emits its
+// structured metadata instead of the flat Error() string.
+// The group mirrors the JSON structure: code, message, severity, and when set field, value,
+// user_msg, retryable, callsite, trace_id and span_id, followed by the Context entries as
+// individual attributes.
+// The stack trace is only included for SeverityError and SeverityCritical, since it is noise
+// for warnings and informational errors. The cause is emitted as a nested group named "cause".
+//
+// Example:
+//
+//	slog.Error("request failed", "err", err)
+//	// {"level":"ERROR","msg":"request failed","err":{"code":"DATABASE_ERROR",...}}
+func (e *Error) LogValue() slog.Value {
+	context, value, _ := e.redacted()
+	attrs := make([]slog.Attr, 0, 8+len(context))
+	attrs = append(attrs,
+		slog.String("code", string(e.Code)),
+		slog.String("message", e.Message),
+		slog.String("severity", string(e.Severity)),
+	)
+	if e.Category != "" {
+		attrs = append(attrs, slog.String("category", e.Category))
+	}
+	if e.Op != "" {
+		attrs = append(attrs, slog.String("op", e.Op))
+	}
+	if e.Field != "" {
+		attrs = append(attrs, slog.String("field", e.Field))
+	}
+	if value != "" {
+		attrs = append(attrs, slog.String("value", value))
+	}
+	if e.UserMsg != "" {
+		attrs = append(attrs, slog.String("user_msg", e.UserMsg))
+	}
+	if e.Retryable {
+		attrs = append(attrs, slog.Bool("retryable", true))
+	}
+	if e.Callsite != "" {
+		attrs = append(attrs, slog.String("callsite", e.Callsite))
+	}
+	if e.TraceID != "" {
+		attrs = append(attrs, slog.String("trace_id", e.TraceID))
+	}
+	if e.SpanID != "" {
+		attrs = append(attrs, slog.String("span_id", e.SpanID))
+	}
+	if e.ErrorID != "" {
+		attrs = append(attrs, slog.String("error_id", e.ErrorID))
+	}
+
+	keys := make([]string, 0, len(context))
+	for k := range context {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, context[k]))
+	}
+
+	if stack := e.stackText(); stack != "" && (e.Severity == SeverityError || e.Severity == SeverityCritical) {
+		attrs = append(attrs, slog.String("stack", stack))
+	}
+
+	if e.Cause != nil {
+		if ce, ok := e.Cause.(*Error); ok {
+			attrs = append(attrs, slog.Attr{Key: "cause", Value: ce.LogValue()})
+		} else {
+			attrs = append(attrs, slog.Group("cause", slog.String("message", e.Cause.Error())))
+		}
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,83 @@
+// sourcecontext.go: Source snippets in stack traces for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SourceContextLines is the number of source lines shown before and after the line of each
+// frame when source context is enabled.
+const SourceContextLines = 2
+
+var (
+	// sourceContext is set by EnableSourceContext.
+	sourceContext atomic.Bool
+	// sourceFiles caches the lines of the source files read for snippets, or nil for files
+	// that cannot be read.
+	sourceFiles sync.Map
+)
+
+// EnableSourceContext controls whether Stacktrace.String includes the source code around
+// the line of each frame, SourceContextLines lines before and after it, with the line of the
+// frame marked by ">". Files are read from the paths recorded in the binary, so snippets only
+// appear where the sources are present, typically in local development; frames whose file
+// cannot be read are rendered as usual. It is disabled by default and meant for debugging:
+// source files are read and cached on first use.
+//
+// Example:
+//
+//	if os.Getenv("APP_ENV") == "dev" {
+//		errors.EnableSourceContext(true)
+//	}
+func EnableSourceContext(enabled bool) {
+	sourceContext.Store(enabled)
+}
+
+// writeSourceContext writes the lines of file around line to b, indented under the frame.
+func writeSourceContext(b *strings.Builder, file string, line int) {
+	lines := sourceLines(file)
+	if line < 1 || line > len(lines) {
+		return
+	}
+	first := max(line-SourceContextLines, 1)
+	last := min(line+SourceContextLines, len(lines))
+	width := len(strconv.Itoa(last))
+	for n := first; n <= last; n++ {
+		b.WriteString("\t")
+		if n == line {
+			b.WriteString("> ")
+		} else {
+			b.WriteString("  ")
+		}
+		num := strconv.Itoa(n)
+		b.WriteString(strings.Repeat(" ", width-len(num)))
+		b.WriteString(num)
+		b.WriteString(" | ")
+		b.WriteString(lines[n-1])
+		b.WriteByte('\n')
+	}
+}
+
+// sourceLines returns the lines of file, reading it on first use.
+func sourceLines(file string) []string {
+	if cached, ok := sourceFiles.Load(file); ok {
+		return cached.([]string)
+	}
+	var lines []string
+	if data, err := os.ReadFile(file); err == nil {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		lines = strings.Split(string(data), "\n")
+	}
+	sourceFiles.Store(file, lines)
+	return lines
+}
@@ -0,0 +1,70 @@
+// stacksampling.go: Stack trace budgets for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StackSampledKey is the context key set to false on errors whose stack trace was skipped
+// because the budget set with SetStackSampling was exhausted.
+const StackSampledKey = "stack_sampled"
+
+// stackSampler holds the per-code budgets of the current SetStackSampling configuration.
+type stackSampler struct {
+	perSecond int64
+	windows   sync.Map // ErrorCode -> *stackWindow
+}
+
+// stackWindow counts the stacks captured for one code during the current second.
+type stackWindow struct {
+	second atomic.Int64
+	count  atomic.Int64
+}
+
+var currentStackSampler atomic.Pointer[stackSampler]
+
+// SetStackSampling limits stack capture to perSecond stack traces per error code and per
+// second. Under error storms, capturing and resolving stacks dominates the cost of
+// creating errors, while a handful of traces per code is enough to diagnose the failure.
+// Errors over the budget are created without a stack and with the context entry
+// StackSampledKey set to false. The budget only applies to captures decided by the stack
+// policy: WithStack() always captures. A value of 0 or less disables sampling (the
+// default). Calling SetStackSampling resets the budgets.
+//
+// Example:
+//
+//	errors.SetStackSampling(10) // at most 10 stacks per code and per second
+func SetStackSampling(perSecond int) {
+	if perSecond <= 0 {
+		currentStackSampler.Store(nil)
+		return
+	}
+	currentStackSampler.Store(&stackSampler{perSecond: int64(perSecond)})
+}
+
+// allowStack reports whether an error with code may capture a stack trace under the
+// current budget. The window is a fixed one-second interval, so a burst straddling two
+// seconds may capture up to twice the budget.
+func allowStack(code ErrorCode) bool {
+	s := currentStackSampler.Load()
+	if s == nil {
+		return true
+	}
+	w, ok := s.windows.Load(code)
+	if !ok {
+		w, _ = s.windows.LoadOrStore(code, &stackWindow{})
+	}
+	window := w.(*stackWindow)
+	second := time.Now().Unix()
+	if window.second.Load() != second && window.second.Swap(second) != second {
+		window.count.Store(0)
+	}
+	return window.count.Add(1) <= s.perSecond
+}
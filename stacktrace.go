@@ -10,18 +10,107 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Stacktrace holds a slice of program counters for error tracing and debugging.
 // It captures the call stack at the time of error creation for detailed debugging information.
+// Symbols are resolved on the first call to String or Resolve and cached, so Frames must
+// not be modified after that.
 type Stacktrace struct {
 	Frames []uintptr
+
+	resolved atomic.Pointer[resolvedStack]
+}
+
+// resolvedStack caches the frames and text of a Stacktrace, resolved with filter and
+// rendered with or without source context.
+type resolvedStack struct {
+	filter []string
+	count  int
+	source bool
+	frames []StackFrame
+	text   string
+}
+
+// DefaultStackFilter lists the function name prefixes removed from every stack trace by
+// CaptureStacktrace and Stacktrace.String. By default it hides the Go runtime and testing
+// frames that wrap every goroutine. Configure it at startup with SetDefaultStackFilter or
+// AddDefaultStackFilter; an empty filter restores the raw stack traces.
+var DefaultStackFilter = []string{"runtime.", "testing."}
+
+// stackFilterMu guards DefaultStackFilter against concurrent configuration and use.
+var stackFilterMu sync.RWMutex
+
+// SetDefaultStackFilter replaces DefaultStackFilter with the given prefixes.
+// Calling it without arguments disables default filtering.
+func SetDefaultStackFilter(prefixes ...string) {
+	stackFilterMu.Lock()
+	DefaultStackFilter = append([]string(nil), prefixes...)
+	stackFilterMu.Unlock()
+}
+
+// AddDefaultStackFilter appends a prefix to DefaultStackFilter, for example the
+// package path of an application's own error helpers.
+func AddDefaultStackFilter(prefix string) {
+	stackFilterMu.Lock()
+	DefaultStackFilter = append(DefaultStackFilter[:len(DefaultStackFilter):len(DefaultStackFilter)], prefix)
+	stackFilterMu.Unlock()
 }
 
 // CaptureStacktrace returns a new Stacktrace from the current call stack.
 // The skip parameter determines how many stack frames to skip from the top.
-// Optimized to reduce allocations by using a smaller initial buffer and growing as needed.
+// Frames matching DefaultStackFilter are removed.
 func CaptureStacktrace(skip int) *Stacktrace {
+	st := captureStacktrace(skip + 1)
+	stackFilterMu.RLock()
+	st.Frames = filterFrames(st.Frames, DefaultStackFilter)
+	stackFilterMu.RUnlock()
+	return st
+}
+
+// Filter returns a new Stacktrace without the frames whose function name starts with any
+// of the given prefixes. With no prefixes it returns an unfiltered copy.
+func (s *Stacktrace) Filter(prefixes ...string) *Stacktrace {
+	if s == nil {
+		return nil
+	}
+	frames := make([]uintptr, len(s.Frames))
+	copy(frames, s.Frames)
+	return &Stacktrace{Frames: filterFrames(frames, prefixes)}
+}
+
+// filterFrames removes in place the program counters whose function matches a prefix.
+func filterFrames(frames []uintptr, prefixes []string) []uintptr {
+	if len(prefixes) == 0 {
+		return frames
+	}
+	kept := frames[:0]
+	for _, pc := range frames {
+		name := ""
+		if fn := runtime.FuncForPC(pc - 1); fn != nil {
+			name = fn.Name()
+		}
+		if !hasAnyPrefix(name, prefixes) {
+			kept = append(kept, pc)
+		}
+	}
+	return kept
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureStacktrace captures the raw call stack without applying DefaultStackFilter.
+// Optimized to reduce allocations by using a smaller initial buffer and growing as needed.
+func captureStacktrace(skip int) *Stacktrace {
 	const (
 		initialDepth = 16 // Start smaller - most stacks are shallow
 		maxDepth     = 64 // Maximum depth we'll capture
@@ -49,30 +138,209 @@ func CaptureStacktrace(skip int) *Stacktrace {
 
 // String returns a human-readable representation of the stack trace.
 // Each frame is displayed with function name, file path, and line number.
-// Optimized for better performance with pre-allocated buffer size estimation.
+// Frames matching DefaultStackFilter are omitted. The text is cached, so logging and
+// serializing the same error resolve its symbols only once. See EnableSourceContext to
+// include the source code around each frame.
 func (s *Stacktrace) String() string {
 	if s == nil || len(s.Frames) == 0 {
 		return ""
 	}
+	return s.resolve().text
+}
 
-	// Pre-allocate buffer with estimated size to reduce allocations
-	// Estimate ~100 chars per frame (function name + file path + line)
-	estimatedSize := len(s.Frames) * 100
-	var b strings.Builder
-	b.Grow(estimatedSize)
+// Resolve returns the frames of the stack trace that are not excluded by
+// DefaultStackFilter, with their function, file, line and package resolved, so that
+// consumers can build their own renderers, filter frames or feed them to Sentry.
+// It returns nil for a nil or empty stack trace. The frames are cached like the text of
+// String; the returned slice is a copy that the caller may modify.
+//
+// Example:
+//
+//	for _, f := range err.Stack.Resolve() {
+//		if f.Package == "main" {
+//			fmt.Printf("%s:%d\n", f.File, f.Line)
+//		}
+//	}
+func (s *Stacktrace) Resolve() []StackFrame {
+	if s == nil || len(s.Frames) == 0 {
+		return nil
+	}
+	return append([]StackFrame(nil), s.resolve().frames...)
+}
+
+// resolve returns the cached resolution of the stack trace, resolving it again when
+// DefaultStackFilter has been replaced, Frames has changed length or source context has
+// been toggled since. Concurrent first calls may each resolve the stack; the results are
+// identical.
+func (s *Stacktrace) resolve() *resolvedStack {
+	stackFilterMu.RLock()
+	filter := DefaultStackFilter
+	stackFilterMu.RUnlock()
+	source := sourceContext.Load()
 
+	if r := s.resolved.Load(); r != nil && r.count == len(s.Frames) && r.source == source && sameFilter(r.filter, filter) {
+		return r
+	}
+	r := &resolvedStack{filter: filter, count: len(s.Frames), source: source, frames: make([]StackFrame, 0, len(s.Frames))}
 	frames := runtime.CallersFrames(s.Frames)
 	for {
 		frame, more := frames.Next()
-		b.WriteString(frame.Function)
-		b.WriteString("\n\t")
-		b.WriteString(frame.File)
-		b.WriteByte(':') // More efficient than WriteString(":")
-		b.WriteString(strconv.Itoa(frame.Line))
-		b.WriteByte('\n') // More efficient than WriteString("\n")
+		if !hasAnyPrefix(frame.Function, filter) {
+			r.frames = append(r.frames, newStackFrame(frame.Function, frame.File, frame.Line))
+		}
 		if !more {
 			break
 		}
 	}
+	r.text = formatFrames(r.frames, source)
+	s.resolved.Store(r)
+	return r
+}
+
+// sameFilter reports whether a and b are the same filter slice. SetDefaultStackFilter and
+// AddDefaultStackFilter always allocate a new slice, so identity detects reconfiguration.
+func sameFilter(a, b []string) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// StackFrame is a single resolved frame of a stack trace.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Package  string `json:"package,omitempty"`
+}
+
+// newStackFrame returns the frame of function at file:line, deriving its package path.
+func newStackFrame(function, file string, line int) StackFrame {
+	return StackFrame{Function: function, File: file, Line: line, Package: functionPackage(function)}
+}
+
+// functionPackage returns the import path of the package of a fully qualified function
+// name, e.g. "github.com/agilira/go-errors" for "github.com/agilira/go-errors.(*Error).Wrap".
+func functionPackage(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	dot := strings.IndexByte(function[slash+1:], '.')
+	if dot < 0 {
+		return ""
+	}
+	return function[:slash+1+dot]
+}
+
+// StackFrames returns the frames of the error's stack trace. For errors decoded by
+// UnmarshalJSON, which carry the stack only as text, the frames are parsed from that text,
+// so a stack received from another service can be inspected like a local one.
+// It returns nil if the error has no stack trace.
+//
+// Example:
+//
+//	for _, f := range err.StackFrames() {
+//		fmt.Printf("%s (%s:%d)\n", f.Function, f.File, f.Line)
+//	}
+func (e *Error) StackFrames() []StackFrame {
+	if e.Stack == nil {
+		return ParseStack(e.rawStack)
+	}
+	return e.Stack.Resolve()
+}
+
+// ParseStack parses the text produced by Stacktrace.String, one function line followed by
+// a tab-indented "file:line" line per frame, back into frames. Lines that do not follow
+// this layout, such as source context, are ignored.
+func ParseStack(text string) []StackFrame {
+	var frames []StackFrame
+	lines := strings.Split(text, "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		if lines[i] == "" || strings.HasPrefix(lines[i], "\t") || !strings.HasPrefix(lines[i+1], "\t") {
+			continue
+		}
+		loc := strings.TrimPrefix(lines[i+1], "\t")
+		sep := strings.LastIndexByte(loc, ':')
+		if sep < 0 {
+			continue
+		}
+		line, err := strconv.Atoi(loc[sep+1:])
+		if err != nil {
+			continue
+		}
+		frames = append(frames, newStackFrame(lines[i], loc[:sep], line))
+		i++
+	}
+	return frames
+}
+
+// formatFrames renders frames in the layout of Stacktrace.String, with the source code
+// around each frame if source is true.
+func formatFrames(frames []StackFrame, source bool) string {
+	var b strings.Builder
+	// Estimate ~100 chars per frame (function name + file path + line)
+	b.Grow(len(frames) * 100)
+	for _, f := range frames {
+		b.WriteString(f.Function)
+		b.WriteString("\n\t")
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+		b.WriteByte('\n')
+		if source {
+			writeSourceContext(&b, f.File, f.Line)
+		}
+	}
 	return b.String()
 }
+
+// stackText returns the rendered stack trace, falling back to the text decoded by
+// UnmarshalJSON when no program counters are available.
+func (e *Error) stackText() string {
+	if e.Stack != nil {
+		return e.Stack.String()
+	}
+	return e.rawStack
+}
+
+// WithCallsite records the file and line of the caller as "file:line" in the Callsite field
+// and returns the error for chaining. It costs a single runtime.Caller lookup and a small
+// string, which makes it cheaper than capturing and resolving a full stack trace when
+// only the origin of the error is needed. The position is resolved immediately, whereas
+// CaptureStacktrace defers symbol resolution until Stacktrace.String is called.
+func (e *Error) WithCallsite() *Error {
+	e.Callsite = callsite(1)
+	return e
+}
+
+// NewAtCallsite creates a new error like New and records the caller's position in Callsite.
+//
+// Example:
+//
+//	err := NewAtCallsite("CONFIG_ERROR", "Missing database URL")
+//	fmt.Println(err.Callsite) // Output: /app/config/load.go:42
+func NewAtCallsite(code ErrorCode, message string) *Error {
+	e := New(code, message)
+	e.Callsite = callsite(1)
+	return e
+}
+
+// callerFrame returns the frame of the caller of the function calling callerFrame, skipping
+// skip additional frames, like callsite. It returns nil if the position is unknown.
+func callerFrame(skip int) *StackFrame {
+	pc, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return nil
+	}
+	function := ""
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		function = fn.Name()
+	}
+	frame := newStackFrame(function, file, line)
+	return &frame
+}
+
+// callsite returns the "file:line" position of the caller of the function calling callsite,
+// skipping skip additional frames. It returns an empty string if the position is unknown.
+func callsite(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+	return file + ":" + strconv.Itoa(line)
+}
@@ -0,0 +1,160 @@
+// template.go: Reusable error templates for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+// TemplateOpts holds the defaults applied by an ErrorTemplate to every error it creates.
+type TemplateOpts struct {
+	UserMsg   string   // Default user-friendly message
+	Severity  Severity // Default severity; SeverityError if empty
+	Retryable bool     // Whether created errors are retryable
+}
+
+// ErrorTemplate is a reusable, pre-configured error factory. Define templates as
+// package-level variables to keep codes, user messages and severities consistent
+// across a codebase.
+//
+// Example:
+//
+//	var ErrDBTimeout = errors.NewTemplate(ErrCodeDatabase, errors.TemplateOpts{
+//		UserMsg:   "The service is busy, please try again",
+//		Severity:  errors.SeverityCritical,
+//		Retryable: true,
+//	})
+//
+//	return ErrDBTimeout.Wrap(err, "Query timed out").WithContext("user_id", userID)
+type ErrorTemplate struct {
+	code ErrorCode
+	opts TemplateOpts
+}
+
+// NewTemplate creates a template for the given code and defaults.
+// If code is empty or whitespace-only, DefaultErrorCode will be used instead.
+func NewTemplate(code ErrorCode, opts TemplateOpts) *ErrorTemplate {
+	if opts.Severity == "" {
+		opts.Severity = SeverityError
+	}
+	return &ErrorTemplate{code: checkCode(code), opts: opts}
+}
+
+// Code returns the error code of the template, for use with HasCode.
+func (t *ErrorTemplate) Code() ErrorCode {
+	return t.code
+}
+
+// New creates a new error with the template's code and defaults.
+// The result can be further customized with the usual chaining methods.
+// The defaults are applied before the stack policy is evaluated, so a critical template
+// captures a stack trace under OnCritical.
+func (t *ErrorTemplate) New(message string) *Error {
+	e := t.apply(&Error{
+		Code:      t.code,
+		Message:   message,
+		Timestamp: now(),
+		Context:   make(map[string]interface{}),
+	})
+	e.finish(nil, false, 1)
+	return e
+}
+
+// Wrap wraps cause with the template's code and defaults, capturing the stack trace
+// of the caller like Wrap.
+func (t *ErrorTemplate) Wrap(cause error, message string) *Error {
+	return wrapWithOptions(cause, WrapOptions{Code: t.code, Message: message}, 1, t.defaults)
+}
+
+// defaults is the Option form of apply.
+func (t *ErrorTemplate) defaults(e *Error) {
+	t.apply(e)
+}
+
+func (t *ErrorTemplate) apply(e *Error) *Error {
+	e.UserMsg = t.opts.UserMsg
+	e.Severity = t.opts.Severity
+	e.Retryable = t.opts.Retryable
+	return e
+}
+
+// Definition is an immutable error definition: a code and a message, plus the defaults of
+// an ErrorTemplate. The With* methods return modified copies, so a definition can be shared
+// as a package-level variable and derived from safely. New and Wrap produce fresh instances.
+//
+// Example:
+//
+//	var ErrUserNotFound = errors.Define("USER_NOT_FOUND", "user not found").
+//		WithUserMessage("We could not find that account").
+//		WithSeverity(errors.SeverityWarning)
+//
+//	return ErrUserNotFound.New(errors.WithContext("user_id", id))
+//	return ErrUserNotFound.Wrap(err)
+type Definition struct {
+	template ErrorTemplate
+	message  string
+}
+
+// Define creates a definition for the given code and message.
+// If code is empty or whitespace-only, DefaultErrorCode will be used instead.
+func Define(code ErrorCode, message string) *Definition {
+	return &Definition{template: *NewTemplate(code, TemplateOpts{}), message: message}
+}
+
+// WithUserMessage returns a copy of the definition with the given user-friendly message.
+func (d *Definition) WithUserMessage(msg string) *Definition {
+	c := *d
+	c.template.opts.UserMsg = msg
+	return &c
+}
+
+// WithSeverity returns a copy of the definition with the given severity.
+func (d *Definition) WithSeverity(severity Severity) *Definition {
+	c := *d
+	c.template.opts.Severity = severity
+	return &c
+}
+
+// AsRetryable returns a copy of the definition whose errors are retryable.
+func (d *Definition) AsRetryable() *Definition {
+	c := *d
+	c.template.opts.Retryable = true
+	return &c
+}
+
+// Code returns the error code of the definition, for use with HasCode.
+func (d *Definition) Code() ErrorCode {
+	return d.template.code
+}
+
+// Message returns the technical message of the definition.
+func (d *Definition) Message() string {
+	return d.message
+}
+
+// New creates a new error from the definition. Options such as WithStack() are applied
+// after the definition's defaults.
+func (d *Definition) New(opts ...Option) *Error {
+	e := d.template.apply(&Error{
+		Code:      d.template.code,
+		Message:   d.message,
+		Timestamp: now(),
+		Context:   make(map[string]interface{}),
+	})
+	e.finish(opts, false, 1)
+	return e
+}
+
+// Wrap wraps cause with the definition's code, message and defaults, capturing the stack
+// trace of the caller like Wrap.
+func (d *Definition) Wrap(cause error, opts ...Option) *Error {
+	return wrapWithOptions(cause, WrapOptions{
+		Code:    d.template.code,
+		Message: d.message,
+	}, 1, append([]Option{d.template.defaults}, opts...)...)
+}
+
+// Is reports whether any error in the chain of err has the definition's code.
+func (d *Definition) Is(err error) bool {
+	return HasCode(err, d.template.code)
+}
@@ -0,0 +1,63 @@
+// timeline.go: Breadcrumb events for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TimelineEvent is a timestamped breadcrumb recorded with WithEvent.
+type TimelineEvent struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// WithEvent appends a breadcrumb to the events of the error, timestamped like new errors
+// (see SetClock), and returns the error for chaining. Handlers record what they attempted while the error
+// propagates, so that the error tells the story of what happened before it surfaced.
+// The message is formatted with fmt.Sprintf when args are given. Events are serialized
+// in order as "events".
+//
+// Example:
+//
+//	for attempt := 1; attempt <= 3; attempt++ {
+//		if err = connect(); err == nil {
+//			return nil
+//		}
+//		apiErr.WithEvent("connection attempt %d failed", attempt)
+//	}
+func (e *Error) WithEvent(message string, args ...interface{}) *Error {
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+	e.Events = append(e.Events, TimelineEvent{Time: now(), Message: message})
+	return e
+}
+
+// Timeline returns the events recorded with WithEvent by every *Error in the chain of err,
+// sorted by time. Events recorded at the same time keep the order of the chain, innermost
+// first, since inner errors are created before their wrappers. It returns nil if there are
+// no events.
+//
+// Example:
+//
+//	for _, ev := range errors.Timeline(err) {
+//		log.Printf("%s %s", ev.Time.Format(time.RFC3339Nano), ev.Message)
+//	}
+func Timeline(err error) []TimelineEvent {
+	chain := Chain(err)
+	var events []TimelineEvent
+	for i := len(chain) - 1; i >= 0; i-- {
+		events = append(events, chain[i].Events...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
@@ -0,0 +1,187 @@
+// translate.go: Error translation between services for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"sync"
+)
+
+// translatedMessage is the technical message of a translated error whose rule has none.
+const translatedMessage = "upstream error"
+
+// TranslationRule describes the error that replaces an upstream error in Translate.
+// Empty fields keep the value of the upstream error, except the messages, which are never
+// taken from it.
+type TranslationRule struct {
+	Code        ErrorCode // Code of the translated error; required
+	Message     string    // Technical message; "upstream error" if empty
+	UserMessage string    // User message; none if empty, so the upstream one does not leak
+	Severity    Severity  // Severity; the upstream severity if empty
+	HTTPStatus  int       // HTTP status; see Translate for the default
+}
+
+// CodeTranslator maps the error codes of dependency services to the codes and user
+// messages of this service, so that internal codes do not leak across service boundaries.
+// Rules are registered for a code or a prefix of hierarchical codes (see
+// ErrorCode.HasPrefix); the longest matching prefix wins. It is safe for concurrent use.
+// Unlike Translator, which localizes user messages, it changes the error itself.
+type CodeTranslator struct {
+	mu       sync.RWMutex
+	rules    map[ErrorCode]TranslationRule
+	fallback *TranslationRule
+}
+
+// DefaultCodeTranslator is the translator used by the package-level RegisterTranslation
+// and Translate functions.
+var DefaultCodeTranslator = NewCodeTranslator()
+
+// NewCodeTranslator creates a translator without rules.
+func NewCodeTranslator() *CodeTranslator {
+	return &CodeTranslator{rules: make(map[ErrorCode]TranslationRule)}
+}
+
+// Register records the rule applied to errors whose code is upstream or lies below it in
+// the code hierarchy. Registering a code again replaces its rule.
+//
+// Example:
+//
+//	t := errors.NewCodeTranslator()
+//	t.Register("PAYMENTS.CARD_DECLINED", errors.TranslationRule{
+//		Code:        "CHECKOUT_PAYMENT_REFUSED",
+//		UserMessage: "Your card was declined.",
+//		HTTPStatus:  http.StatusPaymentRequired,
+//	})
+//	t.Register("PAYMENTS", errors.TranslationRule{Code: "CHECKOUT_PAYMENT_ERROR"})
+func (t *CodeTranslator) Register(upstream ErrorCode, rule TranslationRule) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rules[upstream] = rule
+}
+
+// SetFallback sets the rule applied to errors without a matching rule, including errors
+// that are not *Error, so that nothing crosses the boundary untranslated. A nil rule
+// removes the fallback.
+func (t *CodeTranslator) SetFallback(rule *TranslationRule) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rule == nil {
+		t.fallback = nil
+		return
+	}
+	fallback := *rule
+	t.fallback = &fallback
+}
+
+// Translate applies the rule of the first *Error in the chain of err with a matching
+// code, or the fallback rule, and returns err unchanged if neither applies. The
+// translated error wraps err, so that logs keep the upstream chain while PublicError and
+// the user message only show the translation; the upstream code is kept out of the
+// serialized forms and is available through UpstreamCode, and its retry hints are kept. Without an HTTPStatus in
+// the rule, the status is the one of err as returned by HTTPStatus. Translate returns nil
+// for a nil error.
+func (t *CodeTranslator) Translate(err error) error {
+	if err == nil {
+		return nil
+	}
+	var upstream *Error
+	rule, found := TranslationRule{}, false
+
+	t.mu.RLock()
+	WalkChain(err, func(cur error) bool {
+		if e, ok := cur.(*Error); ok {
+			if upstream == nil {
+				upstream = e
+			}
+			if rule, found = t.lookup(e.Code); found {
+				upstream = e
+			}
+		}
+		return !found
+	})
+	if !found && t.fallback != nil {
+		rule, found = *t.fallback, true
+	}
+	t.mu.RUnlock()
+	if !found {
+		return err
+	}
+
+	message, severity, status := rule.Message, rule.Severity, rule.HTTPStatus
+	if message == "" {
+		message = translatedMessage
+	}
+	if severity == "" && upstream != nil {
+		severity = upstream.Severity
+	}
+	if status == 0 {
+		status = HTTPStatus(err)
+	}
+	translated := wrapWithOptions(err, WrapOptions{
+		Code:      rule.Code,
+		Message:   message,
+		Severity:  severity,
+		SkipStack: true,
+	}, 1)
+	translated.UserMsg = rule.UserMessage
+	translated.HTTPStatusCode = status
+	if upstream != nil {
+		translated.upstreamCode = upstream.Code
+		translated.Retryable = upstream.Retryable
+		translated.RetryAfter = upstream.RetryAfter
+		translated.RetryPolicy = upstream.RetryPolicy
+	}
+	return translated
+}
+
+// UpstreamCode returns the upstream code recorded by Translate on the outermost translated
+// error in the chain of err, or "" if err was not translated. The code is only available in
+// process: it is not part of the JSON, map, Problem Details or public representations.
+func UpstreamCode(err error) ErrorCode {
+	var code ErrorCode
+	WalkChain(err, func(cur error) bool {
+		if e, ok := cur.(*Error); ok && e.upstreamCode != "" {
+			code = e.upstreamCode
+			return false
+		}
+		return true
+	})
+	return code
+}
+
+// lookup returns the rule of the longest registered prefix of code. The caller holds t.mu.
+func (t *CodeTranslator) lookup(code ErrorCode) (TranslationRule, bool) {
+	if rule, ok := t.rules[code]; ok {
+		return rule, true
+	}
+	var best ErrorCode
+	for prefix := range t.rules {
+		if len(prefix) > len(best) && code.HasPrefix(prefix) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return TranslationRule{}, false
+	}
+	return t.rules[best], true
+}
+
+// RegisterTranslation registers a rule in DefaultCodeTranslator.
+func RegisterTranslation(upstream ErrorCode, rule TranslationRule) {
+	DefaultCodeTranslator.Register(upstream, rule)
+}
+
+// Translate translates err with DefaultCodeTranslator.
+//
+// Example:
+//
+//	resp, err := paymentsClient.Charge(ctx, req)
+//	if err != nil {
+//		return errors.Translate(err)
+//	}
+func Translate(err error) error {
+	return DefaultCodeTranslator.Translate(err)
+}
@@ -6,6 +6,8 @@
 
 package errors
 
+import "time"
+
 // WithUserMessage sets a user-friendly message on the error and returns the error for chaining.
 // This message should be safe to display to end users without exposing technical details.
 //
@@ -35,20 +37,81 @@ func (e *Error) AsRetryable() *Error {
 	return e
 }
 
+// WithRetryAfter sets the delay to wait before retrying, marks the error as retryable and
+// returns the error for chaining. Use it to propagate Retry-After values from downstream services.
+//
+// Example:
+//
+//	err := New("RATE_LIMITED", "Too many requests").WithRetryAfter(30 * time.Second)
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	e.RetryAfter = d
+	e.Retryable = true
+	return e
+}
+
+// RetryDelay returns the delay set with WithRetryAfter.
+// This implements the RetryScheduler interface.
+func (e *Error) RetryDelay() time.Duration {
+	return e.RetryAfter
+}
+
+// WithCategory sets the category, or domain, of the error and returns the error for chaining.
+// Categories classify errors along a second axis next to the code, e.g. "billing" for
+// CARD_DECLINED and INVOICE_NOT_FOUND, so that dashboards and alerts can be set per domain.
+//
+// Example:
+//
+//	err := New("CARD_DECLINED", "Card was declined").WithCategory("billing")
+func (e *Error) WithCategory(category string) *Error {
+	e.Category = category
+	return e
+}
+
+// WithOp sets the logical operation that failed, such as "billing.ChargeCard", and
+// returns the error for chaining. Setting an Op on each wrapper records the logical call
+// path, returned by Ops, without the cost of a stack trace.
+//
+// Example:
+//
+//	return errors.Wrap(err, "CHARGE_FAILED", "Charge failed").WithOp("billing.ChargeCard")
+func (e *Error) WithOp(op string) *Error {
+	e.Op = op
+	return e
+}
+
+// WithTraceID sets the trace and span IDs of the operation that failed and returns the
+// error for chaining. Use WrapCtx to read them from a context.Context instead.
+func (e *Error) WithTraceID(traceID, spanID string) *Error {
+	e.TraceID = traceID
+	e.SpanID = spanID
+	return e
+}
+
 // WithSeverity sets the severity level of the error and returns the error for chaining.
 // Common severity levels include "error", "warning", "info", and "critical".
-func (e *Error) WithSeverity(severity string) *Error {
-	e.Severity = severity
+// Under the OnCritical stack policy, raising the severity to critical captures a stack
+// trace at the caller if the error has none.
+func (e *Error) WithSeverity(severity Severity) *Error {
+	e.setSeverity(severity, 1)
 	return e
 }
 
+// setSeverity sets the severity and applies the OnCritical stack policy. The skip parameter
+// is the number of frames above setSeverity's caller to omit from a captured stack trace.
+func (e *Error) setSeverity(severity Severity, skip int) {
+	e.Severity = severity
+	if severity == SeverityCritical && e.Stack == nil && e.rawStack == "" &&
+		e.stackChoice != stackSuppress && GlobalStackPolicy() == OnCritical {
+		e.Stack = CaptureStacktrace(skip + 1)
+	}
+}
+
 // UserMessage returns the user-friendly message if set, otherwise falls back to the technical message.
+// A message key set with WithUserMessageKey is rendered in the translator's default language
+// first, see LocalizedUserMessage.
 // This implements the UserMessager interface.
 func (e *Error) UserMessage() string {
-	if e.UserMsg != "" {
-		return e.UserMsg
-	}
-	return e.Message
+	return e.LocalizedUserMessage("")
 }
 
 // ErrorCode returns the error code.
@@ -66,7 +129,8 @@ func (e *Error) IsRetryable() bool {
 // WithCriticalSeverity sets the error severity to critical and returns the error for chaining.
 // Use this for system failures, data corruption, or security breaches.
 func (e *Error) WithCriticalSeverity() *Error {
-	return e.WithSeverity(SeverityCritical)
+	e.setSeverity(SeverityCritical, 1)
+	return e
 }
 
 // WithWarningSeverity sets the error severity to warning and returns the error for chaining.
@@ -0,0 +1,98 @@
+// validation.go: Field validation helper for the go-errors AGILira library
+//
+// Copyright (c) 2025 AGILira - A. Giordano
+// Series: an AGLIra library
+// SPDX-License-Identifier: MPL-2.0
+
+package errors
+
+import (
+	"net/http"
+)
+
+// ErrCodeValidationFailed is the code of the errors returned by Validation.Err and of
+// the field errors they contain.
+const ErrCodeValidationFailed ErrorCode = "VALIDATION_FAILED"
+
+// Validation accumulates field errors while checking an input, and reports them as a
+// single error. The zero value is ready to use.
+type Validation struct {
+	fields []FieldError
+}
+
+// NewValidation returns an empty Validation.
+//
+// Example:
+//
+//	v := errors.NewValidation()
+//	v.Require(req.Username != "", "username", "required")
+//	v.Require(len(req.Password) >= 8, "password", "must be at least 8 characters")
+//	if err := v.Err(); err != nil {
+//		return err // 422, {"code":"VALIDATION_FAILED",...,"fields":[...]}
+//	}
+func NewValidation() *Validation {
+	return &Validation{}
+}
+
+// Require records a field error with message unless ok is true, and returns the
+// Validation for chaining.
+func (v *Validation) Require(ok bool, field, message string) *Validation {
+	if !ok {
+		v.Add(field, message)
+	}
+	return v
+}
+
+// Add records a field error with message and returns the Validation for chaining.
+func (v *Validation) Add(field, message string) *Validation {
+	v.fields = append(v.fields, FieldError{Code: ErrCodeValidationFailed, Message: message, Field: field})
+	return v
+}
+
+// Valid reports whether no field error has been recorded.
+func (v *Validation) Valid() bool {
+	return len(v.fields) == 0
+}
+
+// Err returns nil if no field error has been recorded. Otherwise it returns an *Error with
+// code ErrCodeValidationFailed and HTTP status 422 holding the field errors in FieldErrors.
+func (v *Validation) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	e := &Error{
+		Code:           ErrCodeValidationFailed,
+		Message:        "Validation failed",
+		Timestamp:      now(),
+		Severity:       SeverityError,
+		Context:        make(map[string]interface{}),
+		FieldErrors:    append([]FieldError(nil), v.fields...),
+		HTTPStatusCode: http.StatusUnprocessableEntity,
+	}
+	e.finish(nil, false, 1)
+	return e
+}
+
+// AddFieldError records a field-level failure in FieldErrors, with the code of the error,
+// and returns the error for chaining. Use it when a single error must report several
+// invalid fields; FieldErrors is serialized as a "fields" array.
+//
+// Example:
+//
+//	err := errors.New(ErrCodeValidation, "Invalid signup form").
+//		AddFieldError("email", req.Email, "invalid format").
+//		AddFieldError("age", strconv.Itoa(req.Age), "must be at least 18")
+func (e *Error) AddFieldError(field, value, message string) *Error {
+	e.FieldErrors = append(e.FieldErrors, FieldError{Code: e.Code, Message: message, Field: field, Value: value})
+	return e
+}
+
+// Fields returns every field-level failure of the error: the Field and Value pair, if
+// Field is set, followed by the FieldErrors. The returned slice is a copy.
+func (e *Error) Fields() []FieldError {
+	fields := make([]FieldError, 0, len(e.FieldErrors)+1)
+	if e.Field != "" {
+		fields = append(fields, FieldError{Code: e.Code, Message: e.Message, Field: e.Field, Value: e.Value})
+	}
+	return append(fields, e.FieldErrors...)
+}